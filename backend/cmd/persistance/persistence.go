@@ -2,8 +2,11 @@ package main
 
 import (
 	"archive/zip"
+	"backend/gtfs"
 	"backend/rpc"
+	"backend/rpc/routing"
 	"backend/scenario"
+	"bytes"
 	"fmt"
 	"github.com/urfave/cli/v2"
 	"io"
@@ -14,6 +17,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var portFlag = &cli.IntFlag{
@@ -23,9 +27,45 @@ var portFlag = &cli.IntFlag{
 	Value:   45734,
 }
 var osrmServerFlag = &cli.StringFlag{
-	Name:  "osrm",
-	Usage: "Base endpoint for the OSRM server",
-	Value: "http://localhost:5000",
+	Name:    "osrm",
+	Aliases: []string{"router-url"},
+	Usage:   "Base endpoint for the routing server",
+	Value:   "http://localhost:5000",
+}
+
+var routerFlag = &cli.StringFlag{
+	Name:  "router",
+	Usage: "Routing engine to use, one of " + strings.Join(routing.Engines, ", ") + ". The haversine engine connects stations by straight lines and needs no server",
+	Value: routing.EngineOSRM,
+}
+
+var roadGraphFlag = &cli.StringFlag{
+	Name:  "road-graph",
+	Usage: "Path of an OSM extract (.osm or .osm.pbf) to route on without a routing server. Takes precedence over --router and --osrm",
+}
+
+var modeRouterFlag = &cli.StringSliceFlag{
+	Name: "mode-router",
+	Usage: "Router of lines of a transport mode in the form MODE=ENGINE[/PROFILE][@URL], e.g. tram=osrm/tram@http://localhost:5001. " +
+		"The URL defaults to --osrm, the profile to the default of the engine. Lines of other modes use the default router. Can be repeated",
+}
+
+var routeCacheFlag = &cli.StringFlag{
+	Name:  "route-cache",
+	Usage: "Directory of the route cache shared by all scenarios. An empty directory disables the cache",
+	Value: defaultRouteCacheDirectory(),
+}
+
+var routeCacheEntriesFlag = &cli.IntFlag{
+	Name:  "route-cache-entries",
+	Usage: "Maximal number of cached routing results, 0 for no limit",
+	Value: 100000,
+}
+
+var routeCacheSizeFlag = &cli.IntFlag{
+	Name:  "route-cache-size",
+	Usage: "Maximal size of the route cache in megabytes, 0 for no limit",
+	Value: 512,
 }
 
 var tileServerFlag = &cli.StringFlag{
@@ -39,18 +79,189 @@ var scenarioFileFlag = &cli.StringFlag{
 	Required: true,
 }
 
+var agencyNameFlag = &cli.StringFlag{
+	Name:  "agency-name",
+	Usage: "Name of the agency in exported GTFS feeds. Defaults to the name of the scenario directory.",
+}
+
+var agencyUrlFlag = &cli.StringFlag{
+	Name:  "agency-url",
+	Usage: "URL of the agency in exported GTFS feeds",
+	Value: "http://localhost",
+}
+
+var timezoneFlag = &cli.StringFlag{
+	Name:  "timezone",
+	Usage: "Timezone of the agency in exported GTFS feeds",
+	Value: "Europe/Berlin",
+}
+
+var startDateFlag = &cli.StringFlag{
+	Name:  "start-date",
+	Usage: "First day (YYYYMMDD) of services without a calendar in exported GTFS feeds. Defaults to today.",
+}
+
+var endDateFlag = &cli.StringFlag{
+	Name:  "end-date",
+	Usage: "Last day (YYYYMMDD) of services without a calendar in exported GTFS feeds. Defaults to one year after the start date.",
+}
+
+var inputFlag = &cli.StringFlag{
+	Name:     "input",
+	Aliases:  []string{"i"},
+	Usage:    "Path of the file to read",
+	Required: true,
+}
+
+var outputFlag = &cli.StringFlag{
+	Name:     "output",
+	Aliases:  []string{"o"},
+	Usage:    "Path of the file to write",
+	Required: true,
+}
+
+var minRatioFlag = &cli.Float64Flag{
+	Name:  "min-ratio",
+	Usage: "Share of the routed travel time a segment must at least be scheduled with",
+	Value: scenario.DefaultRunningTimeOptions().MinRatio,
+}
+
+var maxRatioFlag = &cli.Float64Flag{
+	Name:  "max-ratio",
+	Usage: "Multiple of the routed travel time a segment may at most be scheduled with, 0 disables the check",
+	Value: scenario.DefaultRunningTimeOptions().MaxRatio,
+}
+
+var toleranceFlag = &cli.IntFlag{
+	Name:  "tolerance",
+	Usage: "Deviation from the routed travel time in seconds that is always accepted",
+	Value: scenario.DefaultRunningTimeOptions().ToleranceSeconds,
+}
+
 var manager *scenario.Manager
 var directory string
-var osrmUrl = osrmServerFlag.Value
+var router routing.Router
 var tileServer = tileServerFlag.Value
+var exportOptions gtfs.Options
+
+const gtfsDateFormat = "20060102"
 
 func main() {
 	app := cli.App{
 		Flags: []cli.Flag{
 			portFlag,
 			osrmServerFlag,
+			routerFlag,
+			roadGraphFlag,
+			modeRouterFlag,
+			routeCacheFlag,
+			routeCacheEntriesFlag,
+			routeCacheSizeFlag,
 			scenarioFileFlag,
 			tileServerFlag,
+			agencyNameFlag,
+			agencyUrlFlag,
+			timezoneFlag,
+			startDateFlag,
+			endDateFlag,
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "gtfs-export",
+				Usage: "Exports the scenario as zipped GTFS feed",
+				Flags: []cli.Flag{outputFlag},
+				Action: func(ctx *cli.Context) error {
+					scenarioManager, err := scenario.LoadScenario(ctx.String(scenarioFileFlag.Name))
+					if err != nil {
+						return fmt.Errorf("could not read scenario file: %v", err)
+					}
+					options, err := gtfsOptions(ctx)
+					if err != nil {
+						return err
+					}
+					file, err := os.Create(ctx.String(outputFlag.Name))
+					if err != nil {
+						return fmt.Errorf("could not create output file: %v", err)
+					}
+					err = gtfs.Export(scenarioManager, options, file)
+					closeErr := file.Close()
+					if err != nil {
+						return err
+					}
+					if closeErr != nil {
+						return fmt.Errorf("could not write output file: %v", closeErr)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "gtfs-import",
+				Usage: "Creates a new scenario in the scenario directory from a zipped GTFS feed",
+				Flags: []cli.Flag{inputFlag},
+				Action: func(ctx *cli.Context) error {
+					feed, err := zip.OpenReader(ctx.String(inputFlag.Name))
+					if err != nil {
+						return fmt.Errorf("could not open GTFS feed: %v", err)
+					}
+					defer func() { _ = feed.Close() }()
+					_, err = gtfs.Import(&feed.Reader, ctx.String(scenarioFileFlag.Name))
+					return err
+				},
+			},
+			{
+				Name:  "demand-import",
+				Usage: "Replaces the demand of the scenario by a CSV file with the columns origin station key, destination station key and trips",
+				Flags: []cli.Flag{inputFlag},
+				Action: func(ctx *cli.Context) error {
+					scenarioManager, err := scenario.LoadScenario(ctx.String(scenarioFileFlag.Name))
+					if err != nil {
+						return fmt.Errorf("could not read scenario file: %v", err)
+					}
+					file, err := os.Open(ctx.String(inputFlag.Name))
+					if err != nil {
+						return fmt.Errorf("could not open demand file: %v", err)
+					}
+					defer func() { _ = file.Close() }()
+					demand, err := scenarioManager.ParseDemand(file)
+					if err != nil {
+						return err
+					}
+					scenarioManager.SetDemand(demand)
+					return scenarioManager.Persist()
+				},
+			},
+			{
+				Name:  "route-cache-clear",
+				Usage: "Removes all cached routing results, e.g. after the road data of the routing server has changed",
+				Action: func(ctx *cli.Context) error {
+					cache, err := openRouteCache(ctx)
+					if err != nil || cache == nil {
+						return err
+					}
+					return cache.Clear()
+				},
+			},
+			{
+				Name:  "check-running-times",
+				Usage: "Reports segments of timetables that are scheduled too tight or suspiciously slack compared to the line's path",
+				Flags: []cli.Flag{minRatioFlag, maxRatioFlag, toleranceFlag},
+				Action: func(ctx *cli.Context) error {
+					scenarioManager, err := scenario.LoadScenario(ctx.String(scenarioFileFlag.Name))
+					if err != nil {
+						return fmt.Errorf("could not read scenario file: %v", err)
+					}
+					reports := scenarioManager.CheckRunningTimes(scenario.RunningTimeOptions{
+						MinRatio:         ctx.Float64(minRatioFlag.Name),
+						MaxRatio:         ctx.Float64(maxRatioFlag.Name),
+						ToleranceSeconds: ctx.Int(toleranceFlag.Name),
+					})
+					printRunningTimeReports(ctx.App.Writer, reports)
+					if len(reports) > 0 {
+						return cli.Exit(fmt.Sprintf("found running time issues in %d timetables", len(reports)), 1)
+					}
+					return nil
+				},
+			},
 		},
 		Action: func(ctx *cli.Context) error {
 			var err error
@@ -62,8 +273,15 @@ func main() {
 			if err != nil {
 				return fmt.Errorf("could not read scenario file: %v", err)
 			}
-			osrmUrl = ctx.String(osrmServerFlag.Name)
+			router, err = createRouter(ctx)
+			if err != nil {
+				return err
+			}
 			tileServer = ctx.String(tileServerFlag.Name)
+			exportOptions, err = gtfsOptions(ctx)
+			if err != nil {
+				return err
+			}
 			return http.ListenAndServe("127.0.0.1:"+strconv.Itoa(portFlag.Value), globalHandler())
 		},
 	}
@@ -73,10 +291,147 @@ func main() {
 	}
 }
 
+// createRouter returns the embedded router if a road graph is given, otherwise the router of the engine, and
+// the routers of the transport modes. The results are cached unless the cache is disabled.
+func createRouter(ctx *cli.Context) (routing.Router, error) {
+	cache, err := openRouteCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cached := func(router routing.Router, profile ...string) routing.Router {
+		if cache == nil {
+			return router
+		}
+		return routing.NewCachedRouter(router, cache, strings.TrimSpace(strings.Join(profile, " ")))
+	}
+	var result routing.Router
+	if ctx.IsSet(roadGraphFlag.Name) {
+		graph, err := routing.LoadRoadGraph(ctx.String(roadGraphFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		result = cached(graph, "road-graph", ctx.String(roadGraphFlag.Name))
+	} else {
+		engine, err := routing.New(ctx.String(routerFlag.Name), ctx.String(osrmServerFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		result = cached(engine, ctx.String(routerFlag.Name), ctx.String(osrmServerFlag.Name))
+	}
+	values := ctx.StringSlice(modeRouterFlag.Name)
+	if len(values) == 0 {
+		return result, nil
+	}
+	modes := &routing.Modes{Router: result, Routers: make(map[string]routing.Router)}
+	for _, value := range values {
+		mode, engine, url, profile, err := parseModeRouter(value, ctx.String(osrmServerFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		router, err := routing.NewProfile(engine, url, profile)
+		if err != nil {
+			return nil, fmt.Errorf("the router of mode \"%s\": %v", mode, err)
+		}
+		modes.Routers[mode] = cached(router, engine, url, profile)
+	}
+	return modes, nil
+}
+
+// parseModeRouter parses the router of a transport mode in the form MODE=ENGINE[/PROFILE][@URL].
+func parseModeRouter(value string, defaultUrl string) (mode string, engine string, url string, profile string, err error) {
+	mode, engine, ok := strings.Cut(value, "=")
+	if !ok || engine == "" {
+		return "", "", "", "", fmt.Errorf("the mode router \"%s\" is not of the form MODE=ENGINE[/PROFILE][@URL]", value)
+	}
+	if mode == "" {
+		return "", "", "", "", fmt.Errorf("the mode router \"%s\" has no transport mode", value)
+	}
+	err = scenario.ValidateMode(mode)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	engine, url, ok = strings.Cut(engine, "@")
+	if !ok {
+		url = defaultUrl
+	}
+	engine, profile, _ = strings.Cut(engine, "/")
+	return mode, engine, url, profile, nil
+}
+
+// openRouteCache opens the route cache, or returns nil if it is disabled.
+func openRouteCache(ctx *cli.Context) (*routing.Cache, error) {
+	directory := ctx.String(routeCacheFlag.Name)
+	if directory == "" {
+		return nil, nil
+	}
+	return routing.OpenCache(directory, ctx.Int(routeCacheEntriesFlag.Name), int64(ctx.Int(routeCacheSizeFlag.Name))*1024*1024)
+}
+
+// defaultRouteCacheDirectory returns the route cache directory in the cache directory of the user, or an empty
+// directory if there is none.
+func defaultRouteCacheDirectory() string {
+	directory, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(directory, "ptl-editor", "routes")
+}
+
+func gtfsOptions(ctx *cli.Context) (gtfs.Options, error) {
+	agencyName := ctx.String(agencyNameFlag.Name)
+	if agencyName == "" {
+		agencyName = filepath.Base(ctx.String(scenarioFileFlag.Name))
+	}
+	startDate := time.Now()
+	if ctx.IsSet(startDateFlag.Name) {
+		parsed, err := time.Parse(gtfsDateFormat, ctx.String(startDateFlag.Name))
+		if err != nil {
+			return gtfs.Options{}, fmt.Errorf("could not read start date \"%s\", use the format YYYYMMDD", ctx.String(startDateFlag.Name))
+		}
+		startDate = parsed
+	}
+	endDate := startDate.AddDate(1, 0, -1)
+	if ctx.IsSet(endDateFlag.Name) {
+		parsed, err := time.Parse(gtfsDateFormat, ctx.String(endDateFlag.Name))
+		if err != nil {
+			return gtfs.Options{}, fmt.Errorf("could not read end date \"%s\", use the format YYYYMMDD", ctx.String(endDateFlag.Name))
+		}
+		endDate = parsed
+	}
+	if endDate.Before(startDate) {
+		return gtfs.Options{}, fmt.Errorf("the end date %s is before the start date %s", endDate.Format(gtfsDateFormat), startDate.Format(gtfsDateFormat))
+	}
+	return gtfs.Options{
+		AgencyName:     agencyName,
+		AgencyUrl:      ctx.String(agencyUrlFlag.Name),
+		AgencyTimezone: ctx.String(timezoneFlag.Name),
+		StartDate:      startDate.Format(gtfsDateFormat),
+		EndDate:        endDate.Format(gtfsDateFormat),
+	}, nil
+}
+
+func printRunningTimeReports(writer io.Writer, reports []scenario.RunningTimeReport) {
+	for _, report := range reports {
+		_, _ = fmt.Fprintf(writer, "%s (%s):\n", report.Timetable.Name, report.Timetable.Line().Name)
+		if report.Err != nil {
+			_, _ = fmt.Fprintf(writer, "  could not check running times: %v\n", report.Err)
+		}
+		for _, issue := range report.Issues {
+			kind := "slack"
+			if issue.Tight {
+				kind = "tight"
+			}
+			_, _ = fmt.Fprintf(writer, "  tour %d, %s %s -> %s %s: %s, scheduled %.1f min, routed %.1f min\n",
+				issue.TourIndex+1, issue.Departure, issue.From.Name, issue.Arrival, issue.To.Name, kind,
+				float64(issue.ScheduledSeconds)/60, issue.RoutedSeconds/60)
+		}
+	}
+}
+
 func globalHandler() http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
 		if strings.HasPrefix(req.URL.RequestURI(), "/rpc") {
-			rpc.HandleFunc(manager, osrmUrl).ServeHTTP(resp, req)
+			rpc.HandleFunc(manager, router).ServeHTTP(resp, req)
 			return
 		} else if strings.HasPrefix(req.URL.RequestURI(), "/export") {
 			resp.Header().Set("Content-Type", "application/zip")
@@ -111,6 +466,18 @@ func globalHandler() http.HandlerFunc {
 			}
 			_ = w.Close()
 			return
+		} else if strings.HasPrefix(req.URL.RequestURI(), "/gtfs") {
+			// the feed is built before the headers are sent, so that errors are not reported as truncated download
+			var feed bytes.Buffer
+			err := gtfs.Export(manager, exportOptions, &feed)
+			if err != nil {
+				http.Error(resp, fmt.Sprintf("could not export GTFS feed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			resp.Header().Set("Content-Type", "application/zip")
+			resp.Header().Set("Content-Disposition", "attachment; filename=\"gtfs.zip\"")
+			_, _ = feed.WriteTo(resp)
+			return
 		} else if strings.HasPrefix(req.URL.RequestURI(), "/tile") {
 			tileId := req.URL.RequestURI()[strings.LastIndex(req.URL.String(), "tile")+4:]
 			client := http.Client{Timeout: 0}
@@ -0,0 +1,237 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"backend/scenario"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routeTypes are the basic GTFS route types of the transport modes of lines.
+var routeTypes = map[string]int{
+	scenario.ModeTram:       0,
+	scenario.ModeSubway:     1,
+	scenario.ModeRail:       2,
+	scenario.ModeBus:        3,
+	scenario.ModeFerry:      4,
+	scenario.ModeCableTram:  5,
+	scenario.ModeAerialLift: 6,
+	scenario.ModeFunicular:  7,
+	scenario.ModeTrolleybus: 11,
+	scenario.ModeMonorail:   12,
+}
+
+// dateFormat is the format of dates in GTFS feeds.
+const dateFormat = "20060102"
+
+type Options struct {
+	AgencyName     string
+	AgencyUrl      string
+	AgencyTimezone string
+	// StartDate and EndDate define the validity of the exported services in the format YYYYMMDD.
+	StartDate string
+	EndDate   string
+}
+
+type table struct {
+	name    string
+	header  []string
+	records [][]string
+}
+
+func (t *table) add(record ...string) {
+	t.records = append(t.records, record)
+}
+
+// Export writes the stations, lines, and timetables of the manager as zipped GTFS feed.
+// Waypoint stations are not passenger stops and are therefore omitted from the feed.
+// Calendars become services, timetables without calendar are exported as a service of their own
+// that is operated every day between the start and end date of the options.
+// Tours with an interval are exported as a single trip and a corresponding entry in frequencies.txt.
+func Export(manager *scenario.Manager, options Options, writer io.Writer) error {
+	agency := &table{name: "agency.txt", header: []string{"agency_id", "agency_name", "agency_url", "agency_timezone"}}
+	agency.add("1", options.AgencyName, options.AgencyUrl, options.AgencyTimezone)
+	stops := &table{name: "stops.txt", header: []string{"stop_id", "stop_name", "stop_lat", "stop_lon"}}
+	for _, station := range manager.Stations() {
+		if station.IsWaypoint {
+			continue
+		}
+		stops.add(station.Key, station.Name, formatFloat(station.Lat), formatFloat(station.Lng))
+	}
+	routes := &table{name: "routes.txt", header: []string{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "route_color"}}
+	shapes := &table{name: "shapes.txt", header: []string{"shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence", "shape_dist_traveled"}}
+	for _, line := range manager.Lines() {
+		shortName, longName := splitLineName(line.Name)
+		routes.add(line.Key, "1", shortName, longName, strconv.Itoa(routeTypes[line.TransportMode()]), strings.TrimPrefix(line.Color, "#"))
+		distance := 0.0
+		for index, waypoint := range line.Path {
+			shapes.add(line.Key, formatFloat(waypoint.Lat), formatFloat(waypoint.Lng), strconv.Itoa(index), formatFloat(distance))
+			distance = distance + waypoint.Dist
+		}
+	}
+	trips := &table{name: "trips.txt", header: []string{"route_id", "service_id", "trip_id", "shape_id"}}
+	stopTimes := &table{name: "stop_times.txt", header: []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"}}
+	frequencies := &table{name: "frequencies.txt", header: []string{"trip_id", "start_time", "end_time", "headway_secs", "exact_times"}}
+	calendar := &table{name: "calendar.txt", header: []string{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"}}
+	calendarDates := &table{name: "calendar_dates.txt", header: []string{"service_id", "date", "exception_type"}}
+	exportedServices := make(map[string]bool)
+	for _, timetable := range manager.Timetables() {
+		line := timetable.Line()
+		if line.Key == "" {
+			continue
+		}
+		serviceId := timetable.Key
+		if service, ok := timetable.Calendar(); ok {
+			serviceId = service.Key
+			if !exportedServices[serviceId] {
+				exportCalendar(calendar, calendarDates, service, options)
+				exportedServices[serviceId] = true
+			}
+		} else {
+			calendar.add(serviceId, "1", "1", "1", "1", "1", "1", "1", options.StartDate, options.EndDate)
+		}
+		stations := timetable.Stations()
+		for tourIndex, tour := range timetable.Tours {
+			tripId := fmt.Sprintf("%s_%d", timetable.Key, tourIndex)
+			start := exportStopTimes(stopTimes, tripId, stations, tour)
+			if start < 0 {
+				continue
+			}
+			shapeId := ""
+			if len(line.Path) > 0 {
+				shapeId = line.Key
+			}
+			trips.add(line.Key, serviceId, tripId, shapeId)
+			if tour.IntervalMinutes <= 0 || !tour.LastTour.IsSet() {
+				continue
+			}
+			lastTour := tour.LastTour.Seconds()
+			for lastTour < start {
+				lastTour = lastTour + 24*3600
+			}
+			frequencies.add(tripId, formatTime(start), formatTime(lastTour+60), strconv.Itoa(tour.IntervalMinutes*60), "1")
+		}
+	}
+	archive := zip.NewWriter(writer)
+	for _, t := range []*table{agency, stops, routes, trips, stopTimes, calendar, calendarDates, shapes, frequencies} {
+		err := writeTable(archive, t)
+		if err != nil {
+			return fmt.Errorf("could not write \"%s\": %v", t.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+// exportCalendar adds the calendar as service to calendar.txt and its exceptions to calendar_dates.txt.
+// Unbounded validity dates are replaced by the dates of the options.
+func exportCalendar(calendar *table, calendarDates *table, service scenario.Calendar, options Options) {
+	record := []string{service.Key}
+	for _, weekday := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if service.Weekdays[weekday] {
+			record = append(record, "1")
+		} else {
+			record = append(record, "0")
+		}
+	}
+	startDate := options.StartDate
+	if !service.StartDate.IsZero() {
+		startDate = service.StartDate.Format(dateFormat)
+	}
+	endDate := options.EndDate
+	if !service.EndDate.IsZero() {
+		endDate = service.EndDate.Format(dateFormat)
+	}
+	calendar.add(append(record, startDate, endDate)...)
+	for _, date := range service.AddedDates {
+		calendarDates.add(service.Key, date.Format(dateFormat), "1")
+	}
+	for _, date := range service.RemovedDates {
+		calendarDates.add(service.Key, date.Format(dateFormat), "2")
+	}
+}
+
+// exportStopTimes adds the stop times of the tour to the table and returns the
+// departure time of the tour in seconds after midnight, or -1 if the tour has no events.
+// Times earlier than their predecessor are considered to be after midnight.
+func exportStopTimes(stopTimes *table, tripId string, stations []scenario.Station, tour scenario.Tour) int {
+	start := -1
+	previous := 0
+	sequence := 0
+	for index, event := range tour.Events {
+		if index >= len(stations) || stations[index].IsWaypoint {
+			continue
+		}
+		arrival, departure := eventSeconds(event)
+		if arrival < 0 {
+			continue
+		}
+		for arrival < previous {
+			arrival = arrival + 24*3600
+		}
+		for departure < arrival {
+			departure = departure + 24*3600
+		}
+		if start < 0 {
+			start = departure
+		}
+		previous = departure
+		stopTimes.add(tripId, formatTime(arrival), formatTime(departure), stations[index].Key, strconv.Itoa(sequence))
+		sequence = sequence + 1
+	}
+	return start
+}
+
+// eventSeconds returns the arrival and departure of the event in seconds after midnight.
+// If only one of them is set, it is used for both. If none is set, -1 is returned for both.
+func eventSeconds(event scenario.ArrivalDeparture) (int, int) {
+	if !event.Arrival.IsSet() && !event.Departure.IsSet() {
+		return -1, -1
+	}
+	arrival := event.Arrival
+	if !arrival.IsSet() {
+		arrival = event.Departure
+	}
+	departure := event.Departure
+	if !departure.IsSet() {
+		departure = event.Arrival
+	}
+	return arrival.Seconds(), departure.Seconds()
+}
+
+func writeTable(archive *zip.Writer, t *table) error {
+	file, err := archive.Create(t.name)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	err = writer.Write(t.header)
+	if err != nil {
+		return err
+	}
+	err = writer.WriteAll(t.records)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func splitLineName(name string) (string, string) {
+	parts := strings.SplitN(name, ":", 2)
+	if len(parts) < 2 {
+		return "", name
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatTime formats seconds after midnight as hh:mm:ss, as required by GTFS.
+func formatTime(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
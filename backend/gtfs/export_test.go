@@ -0,0 +1,158 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"backend/scenario"
+	"bytes"
+	"encoding/csv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFeed(t *testing.T, data []byte) map[string][][]string {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	result := make(map[string][][]string)
+	for _, file := range reader.File {
+		content, err := file.Open()
+		require.NoError(t, err)
+		records, err := csv.NewReader(content).ReadAll()
+		require.NoError(t, err)
+		result[file.Name] = records
+		_ = content.Close()
+	}
+	return result
+}
+
+var testOptions = Options{
+	AgencyName:     "Test Agency",
+	AgencyUrl:      "https://example.com",
+	AgencyTimezone: "Europe/Berlin",
+	StartDate:      "20260101",
+	EndDate:        "20261231",
+}
+
+func TestExport(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "a", Name: "Main Station", Lat: 49.5, Lng: 9.5})
+	manager.SaveStation(scenario.Station{Key: "w", Name: "Waypoint", Lat: 49.6, Lng: 9.6, IsWaypoint: true})
+	manager.SaveStation(scenario.Station{Key: "b", Name: "Court Street", Lat: 49.7, Lng: 9.7})
+	manager.SaveLine(scenario.Line{
+		Key:   "line1",
+		Name:  "Line 1: Main Station → Court Street",
+		Color: "#ff0000",
+		Stops: []string{"a", "w", "b"},
+		Path: []scenario.Waypoint{
+			{Lat: 49.5, Lng: 9.5, Dist: 100, Dur: 10, Stop: true},
+			{Lat: 49.6, Lng: 9.6, Dist: 150.5, Dur: 15, Stop: true},
+			{Lat: 49.7, Lng: 9.7, Stop: true},
+		},
+	})
+	manager.SaveTimetable(scenario.Timetable{
+		Key:         "tt1",
+		LineKey:     "line1",
+		Name:        "Working Day",
+		StationKeys: []string{"a", "w", "b"},
+		Tours: []scenario.Tour{
+			{
+				Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("23:58")}, {}, {Arrival: scenario.MustParseTime("0:05")}},
+			},
+			{
+				IntervalMinutes: 30,
+				LastTour:        scenario.MustParseTime("9:00"),
+				Events:          []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("7:00")}, {Departure: scenario.MustParseTime("7:02")}, {Arrival: scenario.MustParseTime("7:05"), Departure: scenario.MustParseTime("7:06")}},
+			},
+		},
+	})
+	manager.SaveTimetable(scenario.Timetable{Key: "orphan", LineKey: "not existing"})
+
+	var buffer bytes.Buffer
+	err := Export(manager, testOptions, &buffer)
+	require.NoError(t, err)
+	feed := readFeed(t, buffer.Bytes())
+
+	assert.Equal(t, [][]string{
+		{"agency_id", "agency_name", "agency_url", "agency_timezone"},
+		{"1", "Test Agency", "https://example.com", "Europe/Berlin"},
+	}, feed["agency.txt"])
+	assert.Equal(t, [][]string{
+		{"stop_id", "stop_name", "stop_lat", "stop_lon"},
+		{"b", "Court Street", "49.7", "9.7"},
+		{"a", "Main Station", "49.5", "9.5"},
+	}, feed["stops.txt"])
+	assert.Equal(t, [][]string{
+		{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "route_color"},
+		{"line1", "1", "Line 1", "Main Station → Court Street", "3", "ff0000"},
+	}, feed["routes.txt"])
+	assert.Equal(t, [][]string{
+		{"shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence", "shape_dist_traveled"},
+		{"line1", "49.5", "9.5", "0", "0"},
+		{"line1", "49.6", "9.6", "1", "100"},
+		{"line1", "49.7", "9.7", "2", "250.5"},
+	}, feed["shapes.txt"])
+	assert.Equal(t, [][]string{
+		{"route_id", "service_id", "trip_id", "shape_id"},
+		{"line1", "tt1", "tt1_0", "line1"},
+		{"line1", "tt1", "tt1_1", "line1"},
+	}, feed["trips.txt"])
+	assert.Equal(t, [][]string{
+		{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"},
+		{"tt1_0", "23:58:00", "23:58:00", "a", "0"},
+		{"tt1_0", "24:05:00", "24:05:00", "b", "1"},
+		{"tt1_1", "07:00:00", "07:00:00", "a", "0"},
+		{"tt1_1", "07:05:00", "07:06:00", "b", "1"},
+	}, feed["stop_times.txt"])
+	assert.Equal(t, [][]string{
+		{"trip_id", "start_time", "end_time", "headway_secs", "exact_times"},
+		{"tt1_1", "07:00:00", "09:01:00", "1800", "1"},
+	}, feed["frequencies.txt"])
+	assert.Equal(t, [][]string{
+		{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"},
+		{"tt1", "1", "1", "1", "1", "1", "1", "1", "20260101", "20261231"},
+	}, feed["calendar.txt"])
+}
+
+func TestExport_Calendar(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "a", Name: "Main Station"})
+	manager.SaveStation(scenario.Station{Key: "b", Name: "Court Street"})
+	manager.SaveLine(scenario.Line{Key: "line1", Name: "Line 1", Stops: []string{"a", "b"}})
+	removed, _ := scenario.ParseDate("2026-12-24")
+	saturday := scenario.Calendar{Key: "sat", StartDate: removed.AddDate(0, -1, 0), RemovedDates: []time.Time{removed}}
+	_ = saturday.SetWeekdayNames([]string{"saturday"})
+	manager.SaveCalendar(saturday)
+	tour := scenario.Tour{Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("7:00")}, {Arrival: scenario.MustParseTime("7:05")}}}
+	manager.SaveTimetable(scenario.Timetable{Key: "tt1", LineKey: "line1", Name: "A", CalendarKey: "sat", StationKeys: []string{"a", "b"}, Tours: []scenario.Tour{tour}})
+	manager.SaveTimetable(scenario.Timetable{Key: "tt2", LineKey: "line1", Name: "B", CalendarKey: "sat", StationKeys: []string{"a", "b"}, Tours: []scenario.Tour{tour}})
+
+	var buffer bytes.Buffer
+	require.NoError(t, Export(manager, testOptions, &buffer))
+	feed := readFeed(t, buffer.Bytes())
+	assert.Equal(t, [][]string{
+		{"route_id", "service_id", "trip_id", "shape_id"},
+		{"line1", "sat", "tt1_0", ""},
+		{"line1", "sat", "tt2_0", ""},
+	}, feed["trips.txt"])
+	assert.Equal(t, [][]string{
+		{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"},
+		{"sat", "0", "0", "0", "0", "0", "1", "0", "20261124", "20261231"},
+	}, feed["calendar.txt"])
+	assert.Equal(t, [][]string{
+		{"service_id", "date", "exception_type"},
+		{"sat", "20261224", "2"},
+	}, feed["calendar_dates.txt"])
+}
+
+func TestExport_Scenario(t *testing.T) {
+	manager, err := scenario.LoadScenario(filepath.Join("..", "testdata", "wuerzburg"))
+	require.NoError(t, err)
+	var buffer bytes.Buffer
+	err = Export(manager, testOptions, &buffer)
+	require.NoError(t, err)
+	feed := readFeed(t, buffer.Bytes())
+	assert.Equal(t, 41, len(feed["routes.txt"]))
+	assert.Equal(t, 22, len(feed["calendar.txt"]))
+}
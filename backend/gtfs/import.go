@@ -0,0 +1,616 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"backend/scenario"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStopShapeDistance is the maximum distance in meters between a stop and the nearest point
+// of a shape. If a stop is farther away, the shape is ignored and the stops are connected by straight segments.
+const maxStopShapeDistance = 200.0
+
+type pattern struct {
+	routeId string
+	stopIds []string
+	trips   []map[string]string
+}
+
+type stopTime struct {
+	stopId    string
+	sequence  int
+	arrival   int
+	departure int
+}
+
+// Import reads the GTFS feed and creates a new scenario in the given directory.
+// Each distinct stop sequence of a route becomes a line, and the trips of a line are grouped
+// into one timetable per service. Services become calendars of the timetables. The directory must not exist yet.
+func Import(feed *zip.Reader, directory string) (*scenario.Manager, error) {
+	if _, err := os.Stat(directory); err == nil {
+		return nil, fmt.Errorf("the directory \"%s\" already exists", directory)
+	}
+	manager, err := scenario.LoadScenario(directory)
+	if err != nil {
+		return nil, err
+	}
+	stations, err := importStops(feed, manager)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := readIndexedTable(feed, "routes.txt", "route_id", true)
+	if err != nil {
+		return nil, err
+	}
+	services, err := importCalendars(feed, manager)
+	if err != nil {
+		return nil, err
+	}
+	stopTimes, err := readStopTimes(feed)
+	if err != nil {
+		return nil, err
+	}
+	shapes, err := readShapes(feed)
+	if err != nil {
+		return nil, err
+	}
+	frequencies, err := readTable(feed, "frequencies.txt", false)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := readTable(feed, "trips.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]*pattern, 0, 0)
+	patternIndex := make(map[string]*pattern)
+	for _, trip := range trips {
+		stopIds := make([]string, 0, len(stopTimes[trip["trip_id"]]))
+		for _, st := range stopTimes[trip["trip_id"]] {
+			if _, ok := stations[st.stopId]; !ok {
+				return nil, fmt.Errorf("the trip \"%s\" references the unknown stop \"%s\"", trip["trip_id"], st.stopId)
+			}
+			stopIds = append(stopIds, st.stopId)
+		}
+		if len(stopIds) < 2 {
+			continue
+		}
+		key := trip["route_id"] + "|" + strings.Join(stopIds, "|")
+		p, ok := patternIndex[key]
+		if !ok {
+			p = &pattern{routeId: trip["route_id"], stopIds: stopIds}
+			patternIndex[key] = p
+			patterns = append(patterns, p)
+		}
+		p.trips = append(p.trips, trip)
+	}
+	tripFrequencies := make(map[string][]map[string]string)
+	for _, frequency := range frequencies {
+		tripFrequencies[frequency["trip_id"]] = append(tripFrequencies[frequency["trip_id"]], frequency)
+	}
+	for _, p := range patterns {
+		route, ok := routes[p.routeId]
+		if !ok {
+			return nil, fmt.Errorf("the route \"%s\" does not exist", p.routeId)
+		}
+		representative := p.trips[0]
+		for _, trip := range p.trips {
+			if len(shapes[trip["shape_id"]]) > 0 {
+				representative = trip
+				break
+			}
+		}
+		lineStations := make([]scenario.Station, 0, len(p.stopIds))
+		stops := make([]string, 0, len(p.stopIds))
+		for _, stopId := range p.stopIds {
+			lineStations = append(lineStations, stations[stopId])
+			stops = append(stops, stations[stopId].Key)
+		}
+		line := manager.SaveLine(scenario.Line{
+			Stops: stops,
+			Path:  buildPath(lineStations, shapes[representative["shape_id"]], segmentDurations(stopTimes[representative["trip_id"]])),
+			Name:  lineName(route, lineStations),
+			Color: lineColor(route),
+			Mode:  lineMode(route),
+		})
+		err = importTimetables(manager, line, p.trips, stopTimes, tripFrequencies, services)
+		if err != nil {
+			return nil, err
+		}
+	}
+	manager.Center = center(manager.Stations())
+	err = manager.Persist()
+	if err != nil {
+		return nil, fmt.Errorf("could not persist imported scenario: %v", err)
+	}
+	return manager, nil
+}
+
+func importStops(feed *zip.Reader, manager *scenario.Manager) (map[string]scenario.Station, error) {
+	stops, err := readTable(feed, "stops.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]scenario.Station)
+	for _, stop := range stops {
+		if stop["location_type"] != "" && stop["location_type"] != "0" {
+			continue
+		}
+		lat, err := strconv.ParseFloat(stop["stop_lat"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not read latitude of stop \"%s\": %v", stop["stop_id"], err)
+		}
+		lng, err := strconv.ParseFloat(stop["stop_lon"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not read longitude of stop \"%s\": %v", stop["stop_id"], err)
+		}
+		result[stop["stop_id"]] = manager.SaveStation(scenario.Station{
+			Name: stop["stop_name"],
+			Lat:  lat,
+			Lng:  lng,
+		})
+	}
+	return result, nil
+}
+
+func importTimetables(manager *scenario.Manager, line scenario.Line, trips []map[string]string, stopTimes map[string][]stopTime,
+	frequencies map[string][]map[string]string, calendars map[string]scenario.Calendar) error {
+	type startedTour struct {
+		start int
+		tour  scenario.Tour
+	}
+	services := make([]string, 0, 0)
+	seenServices := make(map[string]bool)
+	tours := make(map[string][]startedTour)
+	for _, trip := range trips {
+		serviceId := trip["service_id"]
+		if !seenServices[serviceId] {
+			seenServices[serviceId] = true
+			services = append(services, serviceId)
+		}
+		times := stopTimes[trip["trip_id"]]
+		tripFrequencies := frequencies[trip["trip_id"]]
+		if len(tripFrequencies) == 0 {
+			tours[serviceId] = append(tours[serviceId], startedTour{start: times[0].departure, tour: createTour(times, 0, 0, 0)})
+			continue
+		}
+		for _, frequency := range tripFrequencies {
+			start, err := parseGtfsTime(frequency["start_time"])
+			if err != nil {
+				return fmt.Errorf("could not read start time of frequency of trip \"%s\": %v", trip["trip_id"], err)
+			}
+			end, err := parseGtfsTime(frequency["end_time"])
+			if err != nil {
+				return fmt.Errorf("could not read end time of frequency of trip \"%s\": %v", trip["trip_id"], err)
+			}
+			headway, err := strconv.Atoi(frequency["headway_secs"])
+			if err != nil || headway <= 0 {
+				return fmt.Errorf("could not read headway of frequency of trip \"%s\": %q is not a positive number", trip["trip_id"], frequency["headway_secs"])
+			}
+			if end <= start {
+				// the end time is exclusive, so the frequency contains no departure
+				continue
+			}
+			last := start + (end-start-1)/headway*headway
+			if headway%60 == 0 {
+				tours[serviceId] = append(tours[serviceId], startedTour{start: start, tour: createTour(times, start-times[0].departure, headway, last)})
+				continue
+			}
+			// interval tours repeat every full minute, so other headways are imported as explicit trips
+			for departure := start; departure <= last; departure = departure + headway {
+				tours[serviceId] = append(tours[serviceId], startedTour{start: departure, tour: createTour(times, departure-times[0].departure, 0, 0)})
+			}
+		}
+	}
+	for _, serviceId := range services {
+		started := tours[serviceId]
+		if len(started) == 0 {
+			continue
+		}
+		sort.SliceStable(started, func(i, j int) bool {
+			return started[i].start < started[j].start
+		})
+		serviceTours := make([]scenario.Tour, 0, len(started))
+		for _, t := range started {
+			serviceTours = append(serviceTours, t.tour)
+		}
+		calendar := calendars[serviceId]
+		manager.SaveTimetable(scenario.Timetable{
+			LineKey:     line.Key,
+			Name:        timetableName(serviceId, calendar),
+			CalendarKey: calendar.Key,
+			Tours:       serviceTours,
+			StationKeys: line.Stops,
+		})
+	}
+	return nil
+}
+
+// createTour converts the stop times into a tour. All times are shifted by the offset in seconds.
+// If the headway is positive, the tour is repeated every headway seconds until the last start, which must be
+// a multiple of a minute.
+func createTour(times []stopTime, offset int, headway int, lastStart int) scenario.Tour {
+	events := make([]scenario.ArrivalDeparture, 0, len(times))
+	for index, st := range times {
+		event := scenario.ArrivalDeparture{}
+		if index == len(times)-1 {
+			event.Arrival = scenario.NewTime(0, 0, st.arrival+offset)
+		} else if index == 0 || st.arrival == st.departure {
+			event.Departure = scenario.NewTime(0, 0, st.departure+offset)
+		} else {
+			event.Arrival = scenario.NewTime(0, 0, st.arrival+offset)
+			event.Departure = scenario.NewTime(0, 0, st.departure+offset)
+		}
+		events = append(events, event)
+	}
+	tour := scenario.Tour{Events: events}
+	if headway > 0 && lastStart > times[0].departure+offset {
+		tour.IntervalMinutes = headway / 60
+		tour.LastTour = scenario.NewTime(0, 0, lastStart)
+	}
+	return tour
+}
+
+// buildPath creates the path of a line from the shape. If the shape does not fit to the stations,
+// the stations are connected by straight segments.
+func buildPath(stations []scenario.Station, shape [][2]float64, durations []float64) []scenario.Waypoint {
+	indices, ok := matchStopsToShape(stations, shape)
+	if !ok {
+		shape = make([][2]float64, 0, len(stations))
+		indices = make([]int, 0, len(stations))
+		for index, station := range stations {
+			shape = append(shape, [2]float64{station.Lat, station.Lng})
+			indices = append(indices, index)
+		}
+	}
+	path := make([]scenario.Waypoint, 0, indices[len(indices)-1]-indices[0]+1)
+	for segment := 0; segment < len(indices)-1; segment++ {
+		points := shape[indices[segment] : indices[segment+1]+1]
+		length := 0.0
+		for index := 0; index < len(points)-1; index++ {
+			length = length + scenario.HaversineDistance(points[index][0], points[index][1], points[index+1][0], points[index+1][1])
+		}
+		for index := 0; index < len(points)-1; index++ {
+			dist := scenario.HaversineDistance(points[index][0], points[index][1], points[index+1][0], points[index+1][1])
+			dur := 0.0
+			if length > 0 {
+				dur = durations[segment] * dist / length
+			}
+			path = append(path, scenario.Waypoint{
+				Lat:  points[index][0],
+				Lng:  points[index][1],
+				Dist: dist,
+				Dur:  dur,
+				Stop: index == 0,
+			})
+		}
+	}
+	last := shape[indices[len(indices)-1]]
+	path = append(path, scenario.Waypoint{Lat: last[0], Lng: last[1], Stop: true})
+	return path
+}
+
+func matchStopsToShape(stations []scenario.Station, shape [][2]float64) ([]int, bool) {
+	if len(shape) < len(stations) {
+		return nil, false
+	}
+	indices := make([]int, 0, len(stations))
+	start := 0
+	for stationIndex, station := range stations {
+		best := -1
+		bestDistance := maxStopShapeDistance
+		end := len(shape) - (len(stations) - stationIndex - 1)
+		for index := start; index < end; index++ {
+			distance := scenario.HaversineDistance(station.Lat, station.Lng, shape[index][0], shape[index][1])
+			if distance < bestDistance {
+				best = index
+				bestDistance = distance
+			} else if best >= 0 && distance > maxStopShapeDistance {
+				// the shape left the stop, later passes belong to later stops of loop lines
+				break
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+		indices = append(indices, best)
+		start = best + 1
+	}
+	return indices, true
+}
+
+func segmentDurations(times []stopTime) []float64 {
+	result := make([]float64, 0, len(times))
+	for index := 0; index < len(times)-1; index++ {
+		result = append(result, float64(times[index+1].arrival-times[index].departure))
+	}
+	return result
+}
+
+func lineName(route map[string]string, stations []scenario.Station) string {
+	prefix := route["route_short_name"]
+	if prefix == "" {
+		prefix = route["route_long_name"]
+	}
+	return fmt.Sprintf("%s: %s → %s", prefix, stations[0].Name, stations[len(stations)-1].Name)
+}
+
+func lineColor(route map[string]string) string {
+	if route["route_color"] == "" {
+		return "#000000"
+	}
+	return "#" + route["route_color"]
+}
+
+// lineMode returns the transport mode of the basic or extended GTFS route type of the route, bus if it is unknown.
+func lineMode(route map[string]string) string {
+	routeType, err := strconv.Atoi(route["route_type"])
+	if err != nil {
+		return scenario.ModeBus
+	}
+	for mode, basic := range routeTypes {
+		if routeType == basic {
+			return mode
+		}
+	}
+	switch {
+	case routeType >= 100 && routeType < 200:
+		return scenario.ModeRail
+	case routeType == 405:
+		return scenario.ModeMonorail
+	case routeType >= 400 && routeType < 500:
+		return scenario.ModeSubway
+	case routeType == 800:
+		return scenario.ModeTrolleybus
+	case routeType >= 900 && routeType < 1000:
+		return scenario.ModeTram
+	case routeType >= 1000 && routeType < 1300:
+		return scenario.ModeFerry
+	case routeType >= 1300 && routeType < 1400:
+		return scenario.ModeAerialLift
+	case routeType >= 1400 && routeType < 1500:
+		return scenario.ModeFunicular
+	}
+	return scenario.ModeBus
+}
+
+func center(stations []scenario.Station) scenario.Center {
+	if len(stations) == 0 {
+		return scenario.Center{}
+	}
+	lat := 0.0
+	lng := 0.0
+	for _, station := range stations {
+		lat = lat + station.Lat
+		lng = lng + station.Lng
+	}
+	return scenario.Center{
+		Lat:  lat / float64(len(stations)),
+		Lng:  lng / float64(len(stations)),
+		Zoom: 13,
+	}
+}
+
+// importCalendars creates a calendar for each service of calendar.txt and calendar_dates.txt
+// and returns them by their service id.
+func importCalendars(feed *zip.Reader, manager *scenario.Manager) (map[string]scenario.Calendar, error) {
+	calendar, err := readTable(feed, "calendar.txt", false)
+	if err != nil {
+		return nil, err
+	}
+	calendarDates, err := readTable(feed, "calendar_dates.txt", false)
+	if err != nil {
+		return nil, err
+	}
+	serviceIds := make([]string, 0, len(calendar))
+	result := make(map[string]scenario.Calendar)
+	for _, service := range calendar {
+		serviceId := service["service_id"]
+		converted := scenario.Calendar{Name: serviceId}
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			converted.Weekdays[weekday] = service[strings.ToLower(weekday.String())] == "1"
+		}
+		converted.StartDate, err = parseOptionalDate(service["start_date"])
+		if err != nil {
+			return nil, fmt.Errorf("could not read start date of service \"%s\": %v", serviceId, err)
+		}
+		converted.EndDate, err = parseOptionalDate(service["end_date"])
+		if err != nil {
+			return nil, fmt.Errorf("could not read end date of service \"%s\": %v", serviceId, err)
+		}
+		if _, ok := result[serviceId]; !ok {
+			serviceIds = append(serviceIds, serviceId)
+		}
+		result[serviceId] = converted
+	}
+	for _, exception := range calendarDates {
+		serviceId := exception["service_id"]
+		converted, ok := result[serviceId]
+		if !ok {
+			converted = scenario.Calendar{Name: serviceId}
+			serviceIds = append(serviceIds, serviceId)
+		}
+		date, err := time.Parse(dateFormat, exception["date"])
+		if err != nil {
+			return nil, fmt.Errorf("could not read exception date of service \"%s\": %v", serviceId, err)
+		}
+		switch exception["exception_type"] {
+		case "1":
+			converted.AddedDates = append(converted.AddedDates, date)
+		case "2":
+			converted.RemovedDates = append(converted.RemovedDates, date)
+		default:
+			return nil, fmt.Errorf("the exception type \"%s\" of service \"%s\" is unknown", exception["exception_type"], serviceId)
+		}
+		result[serviceId] = converted
+	}
+	for _, serviceId := range serviceIds {
+		result[serviceId] = manager.SaveCalendar(result[serviceId])
+	}
+	return result, nil
+}
+
+func parseOptionalDate(text string) (time.Time, error) {
+	if text == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateFormat, text)
+}
+
+// timetableName names the timetable after the service and its weekdays, e.g. "WD (Mon, Tue)".
+func timetableName(serviceId string, calendar scenario.Calendar) string {
+	active := make([]string, 0, 7)
+	for _, weekday := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if calendar.Weekdays[weekday] {
+			active = append(active, weekday.String()[:3])
+		}
+	}
+	if len(active) == 0 {
+		return serviceId
+	}
+	return fmt.Sprintf("%s (%s)", serviceId, strings.Join(active, ", "))
+}
+
+func readStopTimes(feed *zip.Reader) (map[string][]stopTime, error) {
+	rows, err := readTable(feed, "stop_times.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]stopTime)
+	for _, row := range rows {
+		sequence, err := strconv.Atoi(row["stop_sequence"])
+		if err != nil {
+			return nil, fmt.Errorf("could not read stop sequence of trip \"%s\": %v", row["trip_id"], err)
+		}
+		arrivalText := row["arrival_time"]
+		departureText := row["departure_time"]
+		if arrivalText == "" {
+			arrivalText = departureText
+		}
+		if departureText == "" {
+			departureText = arrivalText
+		}
+		arrival, err := parseGtfsTime(arrivalText)
+		if err != nil {
+			return nil, fmt.Errorf("could not read arrival of trip \"%s\" at stop \"%s\": %v", row["trip_id"], row["stop_id"], err)
+		}
+		departure, err := parseGtfsTime(departureText)
+		if err != nil {
+			return nil, fmt.Errorf("could not read departure of trip \"%s\" at stop \"%s\": %v", row["trip_id"], row["stop_id"], err)
+		}
+		result[row["trip_id"]] = append(result[row["trip_id"]], stopTime{
+			stopId:    row["stop_id"],
+			sequence:  sequence,
+			arrival:   arrival,
+			departure: departure,
+		})
+	}
+	for _, times := range result {
+		sort.Slice(times, func(i, j int) bool {
+			return times[i].sequence < times[j].sequence
+		})
+	}
+	return result, nil
+}
+
+func readShapes(feed *zip.Reader) (map[string][][2]float64, error) {
+	rows, err := readTable(feed, "shapes.txt", false)
+	if err != nil {
+		return nil, err
+	}
+	type point struct {
+		sequence int
+		latLng   [2]float64
+	}
+	points := make(map[string][]point)
+	for _, row := range rows {
+		sequence, err := strconv.Atoi(row["shape_pt_sequence"])
+		if err != nil {
+			return nil, fmt.Errorf("could not read sequence of shape \"%s\": %v", row["shape_id"], err)
+		}
+		lat, err := strconv.ParseFloat(row["shape_pt_lat"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not read latitude of shape \"%s\": %v", row["shape_id"], err)
+		}
+		lng, err := strconv.ParseFloat(row["shape_pt_lon"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not read longitude of shape \"%s\": %v", row["shape_id"], err)
+		}
+		points[row["shape_id"]] = append(points[row["shape_id"]], point{sequence: sequence, latLng: [2]float64{lat, lng}})
+	}
+	result := make(map[string][][2]float64)
+	for shapeId, shapePoints := range points {
+		sort.Slice(shapePoints, func(i, j int) bool {
+			return shapePoints[i].sequence < shapePoints[j].sequence
+		})
+		coords := make([][2]float64, 0, len(shapePoints))
+		for _, p := range shapePoints {
+			coords = append(coords, p.latLng)
+		}
+		result[shapeId] = coords
+	}
+	return result, nil
+}
+
+func readIndexedTable(feed *zip.Reader, name string, keyColumn string, required bool) (map[string]map[string]string, error) {
+	rows, err := readTable(feed, name, required)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]string)
+	for _, row := range rows {
+		result[row[keyColumn]] = row
+	}
+	return result, nil
+}
+
+// readTable reads the CSV file with the given name from the feed and returns its rows as
+// maps from column name to value. If the file is optional and does not exist, no rows are returned.
+func readTable(feed *zip.Reader, name string, required bool) ([]map[string]string, error) {
+	file, err := feed.Open(name)
+	if err != nil {
+		if required {
+			return nil, fmt.Errorf("could not open \"%s\" of feed: %v", name, err)
+		}
+		return []map[string]string{}, nil
+	}
+	defer func() { _ = file.Close() }()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read \"%s\" of feed: %v", name, err)
+	}
+	if len(records) == 0 {
+		return []map[string]string{}, nil
+	}
+	header := records[0]
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	result := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for index, column := range header {
+			if index < len(record) {
+				row[strings.TrimSpace(column)] = strings.TrimSpace(record[index])
+			}
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+func parseGtfsTime(text string) (int, error) {
+	result, err := scenario.ParseTime(text)
+	if err != nil {
+		return 0, err
+	}
+	return result.Seconds(), nil
+}
@@ -0,0 +1,256 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"backend/scenario"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func createFeed(t *testing.T, files map[string]string) *zip.Reader {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = file.Write([]byte(strings.TrimSpace(content) + "\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	reader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+	return reader
+}
+
+var testFeed = map[string]string{
+	"stops.txt": `
+stop_id,stop_name,stop_lat,stop_lon,location_type
+A,Main Station,49.8,9.9,0
+B,Court Street,49.801,9.9,
+C,Harbour,49.802,9.9,0
+P,Parent Station,49.8,9.9,1`,
+	"routes.txt": `
+route_id,agency_id,route_short_name,route_long_name,route_type,route_color
+R1,1,5,,3,00ff00
+R2,1,,Night Express,900,`,
+	"trips.txt": `
+route_id,service_id,trip_id,shape_id
+R1,WD,t1,S1
+R1,WD,t2,
+R1,SA,t3,
+R2,WD,t4,`,
+	"stop_times.txt": `
+trip_id,arrival_time,departure_time,stop_id,stop_sequence
+t1,08:00:00,08:00:00,A,1
+t1,08:04:00,08:05:00,B,2
+t1,08:10:00,08:10:00,C,3
+t2,07:00:00,07:00:00,A,1
+t2,07:04:00,07:04:00,B,2
+t2,07:09:00,07:09:00,C,3
+t3,10:00:00,10:00:00,A,1
+t3,10:04:00,10:04:00,B,2
+t3,10:09:00,10:09:00,C,3
+t4,23:58:00,23:58:00,C,1
+t4,24:05:00,24:05:00,A,2`,
+	"frequencies.txt": `
+trip_id,start_time,end_time,headway_secs
+t1,08:00:00,09:00:00,1200`,
+	"shapes.txt": `
+shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence
+S1,49.8,9.9,1
+S1,49.8005,9.9005,2
+S1,49.801,9.9,3
+S1,49.802,9.9,4`,
+	"calendar.txt": `
+service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date
+WD,1,1,1,1,1,0,0,20260101,20261231
+SA,0,0,0,0,0,1,0,20260101,20261231`,
+	"calendar_dates.txt": `
+service_id,date,exception_type
+WD,20261224,2
+SA,20261224,1`,
+}
+
+func TestImport(t *testing.T) {
+	dir, _ := os.MkdirTemp(os.TempDir(), "*")
+	defer func() { _ = os.RemoveAll(dir) }()
+	directory := filepath.Join(dir, "imported")
+
+	manager, err := Import(createFeed(t, testFeed), directory)
+	require.NoError(t, err)
+
+	stations := manager.Stations()
+	require.Equal(t, 3, len(stations))
+	names := make(map[string]string)
+	for _, station := range stations {
+		names[station.Key] = station.Name
+	}
+
+	lines := manager.Lines()
+	require.Equal(t, 2, len(lines))
+	line5 := lines[0]
+	assert.Equal(t, "5: Main Station → Harbour", line5.Name)
+	assert.Equal(t, "#00ff00", line5.Color)
+	assert.Equal(t, scenario.ModeBus, line5.Mode)
+	assert.Equal(t, []string{"Main Station", "Court Street", "Harbour"}, []string{names[line5.Stops[0]], names[line5.Stops[1]], names[line5.Stops[2]]})
+	require.Equal(t, 4, len(line5.Path))
+	assert.Equal(t, []bool{true, false, true, true}, []bool{line5.Path[0].Stop, line5.Path[1].Stop, line5.Path[2].Stop, line5.Path[3].Stop})
+	assert.InDelta(t, 240, line5.Path[0].Dur+line5.Path[1].Dur, 0.001)
+	assert.InDelta(t, 111.2, line5.Path[2].Dist, 0.1)
+
+	night := lines[1]
+	assert.Equal(t, "Night Express: Harbour → Main Station", night.Name)
+	assert.Equal(t, "#000000", night.Color)
+	assert.Equal(t, scenario.ModeTram, night.Mode)
+	assert.Equal(t, 2, len(night.Path))
+	assert.True(t, night.Path[0].Stop)
+	assert.Equal(t, 420.0, night.Path[0].Dur)
+
+	timetables := manager.Timetables()
+	require.Equal(t, 3, len(timetables))
+	assert.Equal(t, "SA (Sat)", timetables[0].Name)
+	workingDay := timetables[1]
+	assert.Equal(t, "WD (Mon, Tue, Wed, Thu, Fri)", workingDay.Name)
+	assert.Equal(t, line5.Key, workingDay.LineKey)
+	assert.Equal(t, line5.Stops, workingDay.StationKeys)
+	assert.Equal(t, []scenario.Tour{
+		{
+			Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("7:00")}, {Departure: scenario.MustParseTime("7:04")}, {Arrival: scenario.MustParseTime("7:09")}},
+		},
+		{
+			IntervalMinutes: 20,
+			LastTour:        scenario.MustParseTime("8:40"),
+			Events:          []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("8:00")}, {Arrival: scenario.MustParseTime("8:04"), Departure: scenario.MustParseTime("8:05")}, {Arrival: scenario.MustParseTime("8:10")}},
+		},
+	}, workingDay.Tours)
+	assert.Equal(t, []scenario.Tour{
+		{Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("23:58")}, {Arrival: scenario.MustParseTime("24:05")}}},
+	}, timetables[2].Tours)
+
+	require.Equal(t, 2, len(manager.Calendars()))
+	calendar, ok := workingDay.Calendar()
+	require.True(t, ok)
+	assert.Equal(t, "WD", calendar.Name)
+	assert.Equal(t, []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, calendar.WeekdayNames())
+	assert.Equal(t, "2026-12-31", scenario.FormatOptionalDate(calendar.EndDate))
+	assert.Equal(t, []string{"2026-12-24"}, scenario.FormatDates(calendar.RemovedDates))
+	christmasEve, _ := scenario.ParseDate("2026-12-24")
+	active := manager.ActiveTimetables(christmasEve)
+	assert.Equal(t, 1, len(active))
+	assert.Equal(t, "SA (Sat)", active[0].Name)
+
+	assert.FileExists(t, filepath.Join(directory, "stations.json"))
+	reloaded, err := scenario.LoadScenario(directory)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(reloaded.Lines()))
+	assert.Equal(t, 3, len(reloaded.Timetables()))
+	assert.Equal(t, 2, len(reloaded.Calendars()))
+	assert.InDelta(t, 49.801, reloaded.Center.Lat, 0.0001)
+}
+
+func TestImport_headwayInSeconds(t *testing.T) {
+	feed := make(map[string]string)
+	for name, content := range testFeed {
+		feed[name] = content
+	}
+	feed["frequencies.txt"] = `
+trip_id,start_time,end_time,headway_secs
+t1,08:00:00,08:05:00,90`
+	manager, err := Import(createFeed(t, feed), filepath.Join(t.TempDir(), "imported"))
+	require.NoError(t, err)
+	workingDay := manager.Timetables()[1]
+	departures := make([]string, 0)
+	for _, tour := range workingDay.Tours {
+		assert.Equal(t, 0, tour.IntervalMinutes)
+		departures = append(departures, tour.Events[0].Departure.String())
+	}
+	assert.Equal(t, []string{"7:00", "8:00", "8:01:30", "8:03", "8:04:30"}, departures)
+}
+
+func TestImport_emptyFrequency(t *testing.T) {
+	feed := make(map[string]string)
+	for name, content := range testFeed {
+		feed[name] = content
+	}
+	feed["frequencies.txt"] = `
+trip_id,start_time,end_time,headway_secs
+t1,08:00:00,08:00:00,90
+t1,09:00:00,08:00:00,600`
+	manager, err := Import(createFeed(t, feed), filepath.Join(t.TempDir(), "imported"))
+	require.NoError(t, err)
+	timetables := manager.Timetables()
+	require.Equal(t, 3, len(timetables))
+	workingDay := timetables[1]
+	assert.Equal(t, "WD (Mon, Tue, Wed, Thu, Fri)", workingDay.Name)
+	require.Equal(t, 1, len(workingDay.Tours))
+	assert.Equal(t, "7:00", workingDay.Tours[0].Events[0].Departure.String())
+}
+
+func TestMatchStopsToShape(t *testing.T) {
+	stations := []scenario.Station{{Lat: 49.8, Lng: 9.9}, {Lat: 49.81, Lng: 9.9}, {Lat: 49.8001, Lng: 9.9}}
+	// the line returns to the first stop and ends next to it
+	shape := [][2]float64{{49.8, 9.9}, {49.805, 9.9}, {49.81, 9.9}, {49.805, 9.905}, {49.8, 9.9}, {49.8, 9.9005}, {49.8001, 9.9}}
+	indices, ok := matchStopsToShape(stations, shape)
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 2, 6}, indices)
+
+	_, ok = matchStopsToShape(stations, shape[:3])
+	assert.False(t, ok)
+}
+
+func TestImport_Errors(t *testing.T) {
+	dir, _ := os.MkdirTemp(os.TempDir(), "*")
+	defer func() { _ = os.RemoveAll(dir) }()
+	t.Run("directory exists", func(t *testing.T) {
+		_, err := Import(createFeed(t, testFeed), dir)
+		assert.EqualError(t, err, "the directory \""+dir+"\" already exists")
+	})
+	t.Run("missing stops", func(t *testing.T) {
+		_, err := Import(createFeed(t, map[string]string{}), filepath.Join(dir, "missing"))
+		assert.EqualError(t, err, "could not open \"stops.txt\" of feed: open stops.txt: file does not exist")
+	})
+	t.Run("unknown stop", func(t *testing.T) {
+		files := make(map[string]string)
+		for name, content := range testFeed {
+			files[name] = content
+		}
+		files["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\nt1,08:00:00,08:00:00,X,1"
+		_, err := Import(createFeed(t, files), filepath.Join(dir, "unknown"))
+		assert.EqualError(t, err, "the trip \"t1\" references the unknown stop \"X\"")
+	})
+}
+
+func TestImport_RoundTrip(t *testing.T) {
+	original, err := scenario.LoadScenario(filepath.Join("..", "testdata", "wuerzburg"))
+	require.NoError(t, err)
+	var buffer bytes.Buffer
+	require.NoError(t, Export(original, testOptions, &buffer))
+	feed, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+
+	dir, _ := os.MkdirTemp(os.TempDir(), "*")
+	defer func() { _ = os.RemoveAll(dir) }()
+	manager, err := Import(feed, filepath.Join(dir, "wuerzburg"))
+	require.NoError(t, err)
+	assert.Equal(t, 20, len(manager.Timetables()))
+	for _, timetable := range manager.Timetables() {
+		assert.Equal(t, len(timetable.Line().Stops), len(timetable.StationKeys))
+		assert.NotEmpty(t, timetable.Tours)
+	}
+}
+
+func TestLineMode(t *testing.T) {
+	for _, mode := range scenario.Modes {
+		assert.Equal(t, mode, lineMode(map[string]string{"route_type": strconv.Itoa(routeTypes[mode])}))
+	}
+	for routeType, mode := range map[string]string{"109": scenario.ModeRail, "405": scenario.ModeMonorail, "401": scenario.ModeSubway,
+		"700": scenario.ModeBus, "800": scenario.ModeTrolleybus, "1200": scenario.ModeFerry, "": scenario.ModeBus, "1700": scenario.ModeBus} {
+		assert.Equal(t, mode, lineMode(map[string]string{"route_type": routeType}), routeType)
+	}
+}
@@ -21,12 +21,19 @@ type Station struct {
 	IsWaypoint bool   `json:"isWaypoint"`
 }
 
+type Demand struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Trips       float64 `json:"trips"`
+}
+
 type Line struct {
 	Stops []string `json:"stops,omitempty"`
 	Path  Path     `json:"path"`
 	Name  string   `json:"name"`
 	Color string   `json:"color"`
 	Key   string   `json:"key"`
+	Mode  string   `json:"mode,omitempty"`
 }
 
 type Path struct {
@@ -38,12 +45,15 @@ type MetaCoord struct {
 	Dist float64 `json:"dist,omitempty"`
 	Dur  float64 `json:"dur,omitempty"`
 	Stop bool    `json:"stop,omitempty"`
+	// Manual is set if the segment to the next coordinate was drawn by hand instead of being routed.
+	Manual bool `json:"manual,omitempty"`
 }
 
 type Timetable struct {
 	Key      string   `json:"key"`
 	Line     string   `json:"line,omitempty"`
 	Name     string   `json:"name"`
+	Calendar string   `json:"calendar,omitempty"`
 	Tours    []Tour   `json:"tours,omitempty"`
 	Stations []string `json:"stations"`
 }
@@ -59,6 +69,16 @@ type ArrivalDeparture struct {
 	Departure string `json:"departure,omitempty"`
 }
 
+type Calendar struct {
+	Key          string   `json:"key"`
+	Name         string   `json:"name"`
+	Weekdays     []string `json:"weekdays"`
+	StartDate    string   `json:"startDate,omitempty"`
+	EndDate      string   `json:"endDate,omitempty"`
+	AddedDates   []string `json:"addedDates,omitempty"`
+	RemovedDates []string `json:"removedDates,omitempty"`
+}
+
 type Vehicle struct {
 	Name     string `json:"name"`
 	Key      string `json:"key"`
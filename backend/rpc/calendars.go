@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"backend/rpc/mapper"
+	"backend/rpc/types"
+	"backend/scenario"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type calendarHandler struct {
+	manager *scenario.Manager
+}
+
+func newCalendarHandler(manager *scenario.Manager) *calendarHandler {
+	return &calendarHandler{
+		manager: manager,
+	}
+}
+
+func (c *calendarHandler) Methods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"getCalendars": {
+			description: "Returns a list of all calendars sorted by name.",
+			input:       reflect.TypeOf(nil),
+			output:      reflect.TypeOf([]types.Calendar{}),
+			method:      c.getCalendars,
+		},
+		"getCalendar": {
+			description: "Returns the calendar identified by the given key.",
+			input:       reflect.TypeOf(types.Calendar{}),
+			output:      reflect.TypeOf(types.Calendar{}),
+			method:      c.getCalendar,
+		},
+		"saveCalendar": {
+			description: "Saves the given calendar or creates it if the key doesn't exist yet. Weekdays are given by their english name, " +
+				"e.g. \"monday\", dates in the format yyyy-mm-dd. Start and end date are optional and inclusive. " +
+				"Added dates are operated regardless of weekday and validity, removed dates are never operated.",
+			input:          reflect.TypeOf(types.Calendar{}),
+			output:         reflect.TypeOf(types.Calendar{}),
+			method:         c.saveCalendar,
+			persistChanged: true,
+		},
+		"deleteCalendar": {
+			description:    "Deletes the calendar identified by the given key and removes it from all timetables.",
+			input:          reflect.TypeOf(types.Calendar{}),
+			method:         c.deleteCalendar,
+			persistChanged: true,
+		},
+	}
+}
+
+func (c *calendarHandler) getCalendars(params json.RawMessage) (json.RawMessage, error) {
+	calendars := c.manager.Calendars()
+	result := make([]types.Calendar, 0, len(calendars))
+	for _, calendar := range calendars {
+		result = append(result, mapper.ToDtoCalendar(calendar))
+	}
+	return mustMarshal(result), nil
+}
+
+func (c *calendarHandler) getCalendar(params json.RawMessage) (json.RawMessage, error) {
+	var calendar types.Calendar
+	_ = json.Unmarshal(params, &calendar)
+	result, ok := c.manager.Calendar(calendar.Key)
+	if !ok {
+		return nil, fmt.Errorf("could not find calendar with key \"%s\"", calendar.Key)
+	}
+	return mustMarshal(mapper.ToDtoCalendar(result)), nil
+}
+
+func (c *calendarHandler) saveCalendar(params json.RawMessage) (json.RawMessage, error) {
+	var calendar types.Calendar
+	_ = json.Unmarshal(params, &calendar)
+	vo, err := mapper.ToVoCalendar(calendar)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	result := c.manager.SaveCalendar(vo)
+	return mustMarshal(mapper.ToDtoCalendar(result)), nil
+}
+
+func (c *calendarHandler) deleteCalendar(params json.RawMessage) (json.RawMessage, error) {
+	var calendar types.Calendar
+	_ = json.Unmarshal(params, &calendar)
+	c.manager.DeleteCalendar(calendar.Key)
+	return nil, nil
+}
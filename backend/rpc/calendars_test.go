@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCalendarHandler_SaveCalendar(t *testing.T) {
+	manager := scenario.Empty()
+	handler := calendarHandler{manager: manager}
+	t.Run("success", func(t *testing.T) {
+		calendar := types.Calendar{
+			Name:         "Working Day",
+			Weekdays:     []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+			StartDate:    "2026-01-01",
+			EndDate:      "2026-12-31",
+			RemovedDates: []string{"2026-12-24"},
+		}
+		result, err := handler.saveCalendar(mustMarshal(calendar))
+		require.NoError(t, err)
+		var got types.Calendar
+		_ = json.Unmarshal(result, &got)
+		assert.NotEmpty(t, got.Key)
+		calendar.Key = got.Key
+		assert.Equal(t, calendar, got)
+
+		result, err = handler.getCalendar(mustMarshal(types.Calendar{Key: got.Key}))
+		require.NoError(t, err)
+		var loaded types.Calendar
+		_ = json.Unmarshal(result, &loaded)
+		assert.Equal(t, calendar, loaded)
+	})
+	t.Run("invalid weekday", func(t *testing.T) {
+		_, err := handler.saveCalendar(mustMarshal(types.Calendar{Weekdays: []string{"mo"}}))
+		assert.EqualError(t, err, "there is no weekday \"mo\", use one of \"monday\" to \"sunday\"")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+	t.Run("reversed validity", func(t *testing.T) {
+		_, err := handler.saveCalendar(mustMarshal(types.Calendar{StartDate: "2026-12-31", EndDate: "2026-01-01"}))
+		assert.EqualError(t, err, "the end date 2026-01-01 must not be before the start date 2026-12-31")
+	})
+}
+
+func TestCalendarHandler_GetCalendars(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveCalendar(scenario.Calendar{Key: "c2", Name: "Sunday"})
+	manager.SaveCalendar(scenario.Calendar{Key: "c1", Name: "Saturday"})
+	handler := calendarHandler{manager: manager}
+	result, err := handler.getCalendars(nil)
+	require.NoError(t, err)
+	var got []types.Calendar
+	_ = json.Unmarshal(result, &got)
+	require.Equal(t, 2, len(got))
+	assert.Equal(t, "c1", got[0].Key)
+	assert.Equal(t, "c2", got[1].Key)
+}
+
+func TestCalendarHandler_DeleteCalendar(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveCalendar(scenario.Calendar{Key: "c1"})
+	handler := calendarHandler{manager: manager}
+	_, err := handler.deleteCalendar(mustMarshal(types.Calendar{Key: "c1"}))
+	require.NoError(t, err)
+	_, err = handler.getCalendar(mustMarshal(types.Calendar{Key: "c1"}))
+	assert.EqualError(t, err, "could not find calendar with key \"c1\"")
+}
@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"backend/rpc/mapper"
+	"backend/rpc/types"
+	"backend/scenario"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+type demandHandler struct {
+	manager *scenario.Manager
+}
+
+func newDemandHandler(manager *scenario.Manager) *demandHandler {
+	return &demandHandler{
+		manager: manager,
+	}
+}
+
+func (d *demandHandler) Methods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"getDemand": {
+			description: "Returns the origin–destination demand of the scenario sorted by origin and destination.",
+			input:       reflect.TypeOf(nil),
+			output:      reflect.TypeOf([]types.Demand{}),
+			method:      d.getDemand,
+		},
+		"importDemand": {
+			description: "Replaces the demand of the scenario by the given CSV with the columns origin station key, destination " +
+				"station key and number of trips. A header line is skipped, the trips of duplicate pairs are summed up.",
+			input:          reflect.TypeOf(types.DemandImportRequest{}),
+			output:         reflect.TypeOf([]types.Demand{}),
+			method:         d.importDemand,
+			persistChanged: true,
+		},
+	}
+}
+
+func (d *demandHandler) getDemand(params json.RawMessage) (json.RawMessage, error) {
+	return mustMarshal(mapper.ToDtoDemand(d.manager.Demand())), nil
+}
+
+func (d *demandHandler) importDemand(params json.RawMessage) (json.RawMessage, error) {
+	var request types.DemandImportRequest
+	_ = json.Unmarshal(params, &request)
+	demand, err := d.manager.ParseDemand(strings.NewReader(request.Csv))
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	d.manager.SetDemand(demand)
+	return mustMarshal(mapper.ToDtoDemand(d.manager.Demand())), nil
+}
@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDemandHandler_ImportDemand(t *testing.T) {
+	manager := scenario.Empty()
+	first := manager.SaveStation(scenario.Station{Name: "Hauptbahnhof"})
+	second := manager.SaveStation(scenario.Station{Name: "Sanderring"})
+	handler := newDemandHandler(manager)
+	t.Run("success", func(t *testing.T) {
+		csv := "origin,destination,trips\n" + first.Key + "," + second.Key + ",120\n" + second.Key + "," + first.Key + ",80\n"
+		result, err := handler.importDemand(mustMarshal(types.DemandImportRequest{Csv: csv}))
+		require.NoError(t, err)
+		var imported []types.Demand
+		_ = json.Unmarshal(result, &imported)
+		assert.Equal(t, 2, len(imported))
+		assert.Equal(t, 120.0, manager.Trips(first.Key, second.Key))
+
+		result, err = handler.getDemand(nil)
+		require.NoError(t, err)
+		var loaded []types.Demand
+		_ = json.Unmarshal(result, &loaded)
+		assert.Equal(t, imported, loaded)
+	})
+	t.Run("invalid csv", func(t *testing.T) {
+		_, err := handler.importDemand(mustMarshal(types.DemandImportRequest{Csv: first.Key + ",unknown,1"}))
+		assert.EqualError(t, err, "line 1: could not find station with key \"unknown\"")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+		assert.Equal(t, 120.0, manager.Trips(first.Key, second.Key))
+	})
+}
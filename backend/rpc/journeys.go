@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"backend/rpc/mapper"
+	"backend/rpc/types"
+	"backend/scenario"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const (
+	defaultMaxWalkMeters = 400
+	walkSpeed            = 1.25
+	defaultJourneyCount  = 3
+	maxJourneyCount      = 10
+)
+
+type journeyHandler struct {
+	manager *scenario.Manager
+}
+
+func newJourneyHandler(manager *scenario.Manager) *journeyHandler {
+	return &journeyHandler{
+		manager: manager,
+	}
+}
+
+func (j *journeyHandler) Methods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"plan": {
+			description: fmt.Sprintf("Plans the journeys with the earliest arrival from the origin to the destination station departing "+
+				"at or after the given time (h:mm or h:mm:ss). Legs are rides with a single trip or walks between stations within the maximal "+
+				"walking distance (default %d m, negative to disable walking) at %.2f m/s. The minimal transfer time applies to changes at "+
+				"the same station. Returns up to count (default %d, at most %d) journeys, each departing later than the one before. If a "+
+				"date (yyyy-mm-dd) is given, only timetables operated on that date are considered.",
+				defaultMaxWalkMeters, walkSpeed, defaultJourneyCount, maxJourneyCount),
+			input:  reflect.TypeOf(types.JourneyRequest{}),
+			output: reflect.TypeOf([]types.Journey{}),
+			method: j.plan,
+		},
+	}
+}
+
+func (j *journeyHandler) plan(params json.RawMessage) (json.RawMessage, error) {
+	var request types.JourneyRequest
+	_ = json.Unmarshal(params, &request)
+	for _, key := range []string{request.OriginKey, request.DestinationKey} {
+		if _, ok := j.manager.Station(key); !ok {
+			return nil, invalidParamsError{err: fmt.Errorf("could not find station with key \"%s\"", key)}
+		}
+	}
+	departure, err := scenario.ParseTime(request.Departure)
+	if err != nil {
+		return nil, invalidParamsError{err: fmt.Errorf("departure: %v", err)}
+	}
+	date, err := scenario.ParseOptionalDate(request.Date)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	if request.MinTransferMinutes < 0 {
+		return nil, invalidParamsError{err: fmt.Errorf("the minimal transfer time must not be negative")}
+	}
+	if request.MaxWalkMeters == 0 {
+		request.MaxWalkMeters = defaultMaxWalkMeters
+	}
+	if request.Count <= 0 {
+		request.Count = defaultJourneyCount
+	}
+	if request.Count > maxJourneyCount {
+		request.Count = maxJourneyCount
+	}
+	journeys := j.manager.PlanJourneys(request.OriginKey, request.DestinationKey, scenario.JourneyOptions{
+		Departure:          departure,
+		Date:               date,
+		MinTransferSeconds: request.MinTransferMinutes * 60,
+		MaxWalkMeters:      request.MaxWalkMeters,
+		WalkSpeed:          walkSpeed,
+		Count:              request.Count,
+	})
+	result := make([]types.Journey, 0, len(journeys))
+	for _, journey := range journeys {
+		result = append(result, mapper.ToDtoJourney(journey))
+	}
+	return mustMarshal(result), nil
+}
@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestJourneyHandler_Plan(t *testing.T) {
+	manager, err := scenario.LoadScenario(filepath.Join("..", "testdata", "wuerzburg"))
+	require.NoError(t, err)
+	timetable, ok := manager.Timetable("ihDOhYX9PK")
+	require.True(t, ok)
+	origin := timetable.StationKeys[0]
+	destination := timetable.StationKeys[len(timetable.StationKeys)-1]
+	handler := newJourneyHandler(manager)
+	t.Run("success", func(t *testing.T) {
+		rawResult, err := handler.plan(mustMarshal(types.JourneyRequest{OriginKey: origin, DestinationKey: destination, Departure: "6:00", Count: 2}))
+		require.NoError(t, err)
+		var result []types.Journey
+		_ = json.Unmarshal(rawResult, &result)
+		require.Equal(t, 2, len(result))
+		for _, journey := range result {
+			require.NotEmpty(t, journey.Legs)
+			assert.Equal(t, origin, journey.Legs[0].FromKey)
+			assert.Equal(t, destination, journey.Legs[len(journey.Legs)-1].ToKey)
+			assert.Greater(t, journey.DurationMinutes, 0.0)
+		}
+		departures := []scenario.Time{scenario.MustParseTime(result[0].Departure), scenario.MustParseTime(result[1].Departure)}
+		assert.True(t, departures[0].Before(departures[1]))
+	})
+	t.Run("unknown station", func(t *testing.T) {
+		_, err := handler.plan(mustMarshal(types.JourneyRequest{OriginKey: origin, DestinationKey: "unknown", Departure: "6:00"}))
+		assert.EqualError(t, err, "could not find station with key \"unknown\"")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+	t.Run("invalid departure", func(t *testing.T) {
+		_, err := handler.plan(mustMarshal(types.JourneyRequest{OriginKey: origin, DestinationKey: destination, Departure: "six"}))
+		assert.EqualError(t, err, "departure: the time \"six\" is not in the format h:mm or h:mm:ss")
+	})
+}
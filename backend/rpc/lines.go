@@ -2,22 +2,30 @@ package rpc
 
 import (
 	"backend/rpc/mapper"
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+)
+
+const (
+	reportTimetablePolicy  = "report"
+	migrateTimetablePolicy = "migrate"
 )
 
 type lineHandler struct {
 	manager *scenario.Manager
-	osrmUrl string
+	router  routing.Router
 }
 
-func newLineHandler(manager *scenario.Manager, osrmUrl string) *lineHandler {
+func newLineHandler(manager *scenario.Manager, router routing.Router) *lineHandler {
 	return &lineHandler{
 		manager: manager,
-		osrmUrl: osrmUrl,
+		router:  router,
 	}
 }
 
@@ -38,10 +46,17 @@ func (h *lineHandler) Methods() map[string]rpcMethod {
 			description: "Saves a line. " +
 				"If the key already exists, the line will be overwritten. " +
 				"If the key is empty, a new line will be created. " +
-				"Ignores the stations field of the line. The stops referenced in the stops list must exist.",
+				"Ignores the stations field of the line. The stops referenced in the stops list must exist. " +
+				"If the stops of an existing line change, its timetables are handled according to the timetable policy: " +
+				"\"report\" (default) leaves them untouched, \"migrate\" drops the events of removed stations and interpolates " +
+				"the events of added stations from the path durations. In both cases the affected timetables are returned. " +
+				"The transport mode must be one of " + strings.Join(scenario.Modes, ", ") + " or empty for buses. If the mode " +
+				"has a router of its own or the mode of an existing line changes, the path is rerouted with the router of the mode. " +
+				"A segment between two stops whose waypoints are all marked as manual, except the one of the next stop, was drawn by hand: its distances are " +
+				"computed as the crow flies and its durations from the average speed of the mode, and it is kept when the line is rerouted. If another segment cannot be routed then, the line is not saved.",
 			input:          reflect.TypeOf(types.Line{}),
 			output:         reflect.TypeOf(types.Line{}),
-			method:         h.saveLine,
+			contextMethod:  h.saveLine,
 			persistChanged: true,
 		},
 		"deleteLine": {
@@ -69,16 +84,136 @@ func (h *lineHandler) queryLine(params json.RawMessage) (json.RawMessage, error)
 	return mustMarshal(mapper.ToDtoLine(line)), nil
 }
 
-func (h *lineHandler) saveLine(params json.RawMessage) (json.RawMessage, error) {
+func (h *lineHandler) saveLine(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
 	var line types.Line
 	_ = json.Unmarshal(params, &line)
+	coordinates := make([]types.LatLng, 0, len(line.Stops))
 	for _, stop := range line.Stops {
-		if _, ok := h.manager.Station(stop); !ok {
+		station, ok := h.manager.Station(stop)
+		if !ok {
 			return nil, fmt.Errorf("a station with key \"%s\" does not exist", stop)
 		}
+		coordinates = append(coordinates, types.LatLng{Lat: station.Lat, Lng: station.Lng})
+	}
+	if line.TimetablePolicy != "" && line.TimetablePolicy != reportTimetablePolicy && line.TimetablePolicy != migrateTimetablePolicy {
+		return nil, invalidParamsError{err: fmt.Errorf("the timetable policy \"%s\" is unknown, use \"%s\" or \"%s\"",
+			line.TimetablePolicy, reportTimetablePolicy, migrateTimetablePolicy)}
+	}
+	err := scenario.ValidateMode(line.Mode)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	previous, existed := h.manager.Line(line.Key)
+	converted := mapper.ToVoLine(line)
+	err = converted.ValidateManualSegments()
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	mode := converted.TransportMode()
+	if len(coordinates) > 1 && (routing.HasModeRouter(h.router, mode) || existed && previous.TransportMode() != mode) {
+		path, err := routeLine(ctx, h.router, converted, coordinates)
+		if err != nil {
+			return nil, fmt.Errorf("could not route the line with transport mode \"%s\": %v", mode, err)
+		}
+		converted.Path = path
+	}
+	result := h.manager.SaveLine(converted)
+	dto := mapper.ToDtoLine(result)
+	if existed && !equalStops(previous.Stops, result.Stops) {
+		dto.AffectedTimetables = h.updateTimetables(result, line.TimetablePolicy == migrateTimetablePolicy)
+	}
+	return mustMarshal(dto), nil
+}
+
+// routeLine routes the line through the coordinates of its stops with the router of its transport mode. Manually
+// drawn segments between two stops are kept and only moved to the coordinates of the stops, the other segments
+// are rerouted. Without manual segments, the path is empty if the router cannot find a route, otherwise an
+// error is returned.
+func routeLine(ctx context.Context, router routing.Router, line scenario.Line, coordinates []types.LatLng) ([]scenario.Waypoint, error) {
+	router = routing.ForMode(router, line.TransportMode())
+	segments, ok := line.StopSegments()
+	if !ok || len(segments) != len(coordinates)-1 || !line.HasManualSegments() {
+		waypoints, err := router.Route(ctx, coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return mapper.ToVoWaypoints(waypoints), nil
+	}
+	result := make([]scenario.Waypoint, 0, len(line.Path))
+	for start := 0; start < len(segments); {
+		var part []scenario.Waypoint
+		end := start + 1
+		if segments[start][0].Manual {
+			part = make([]scenario.Waypoint, len(segments[start]))
+			copy(part, segments[start])
+			part[0].Lat, part[0].Lng = coordinates[start].Lat, coordinates[start].Lng
+			part[len(part)-1].Lat, part[len(part)-1].Lng = coordinates[end].Lat, coordinates[end].Lng
+		} else {
+			// consecutive routed segments are routed together
+			for end < len(segments) && !segments[end][0].Manual {
+				end++
+			}
+			waypoints, err := router.Route(ctx, coordinates[start:end+1])
+			if err != nil {
+				return nil, err
+			}
+			// an empty path would discard the manual segments as well
+			if len(waypoints) == 0 {
+				return nil, fmt.Errorf("there is no route from stop %d to stop %d", start, end)
+			}
+			part = mapper.ToVoWaypoints(waypoints)
+		}
+		// the last waypoint of the previous part is the first waypoint of this part
+		if len(result) > 0 {
+			result = result[:len(result)-1]
+		}
+		result = append(result, part...)
+		start = end
+	}
+	return result, nil
+}
+
+// updateTimetables finds the timetables of the line whose stations differ from the stops and migrates them if requested.
+func (h *lineHandler) updateTimetables(line scenario.Line, migrate bool) []types.AffectedTimetable {
+	result := make([]types.AffectedTimetable, 0)
+	for _, timetable := range h.manager.Timetables() {
+		if timetable.LineKey != line.Key {
+			continue
+		}
+		changes := timetable.StationChanges(line)
+		if changes.IsEmpty() {
+			continue
+		}
+		affected := types.AffectedTimetable{
+			Key:             timetable.Key,
+			Name:            timetable.Name,
+			AddedStations:   changes.Added,
+			RemovedStations: changes.Removed,
+		}
+		if migrate {
+			migrated, _, err := timetable.Migrate(line)
+			if err != nil {
+				affected.Error = err.Error()
+			} else {
+				h.manager.SaveTimetable(migrated)
+				affected.Migrated = true
+			}
+		}
+		result = append(result, affected)
+	}
+	return result
+}
+
+func equalStops(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
 	}
-	result := h.manager.SaveLine(mapper.ToVoLine(line))
-	return mustMarshal(mapper.ToDtoLine(result)), nil
+	return true
 }
 
 func (h *lineHandler) deleteLine(params json.RawMessage) (json.RawMessage, error) {
@@ -1,8 +1,10 @@
 package rpc
 
 import (
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,7 +15,7 @@ import (
 func TestLineHandler_SaveLine(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
 	count := len(manager.Lines())
-	handler := newLineHandler(manager, "")
+	handler := newLineHandler(manager, routing.NewOSRM(""))
 	t.Run("success", func(t *testing.T) {
 		line29, _ := manager.Line("7BNJI4rUT6")
 		require.Equal(t, "Linie 29: Busbahnhof → Hubland Nord", line29.Name)
@@ -38,7 +40,7 @@ func TestLineHandler_SaveLine(t *testing.T) {
 			Color: "yellow",
 			Key:   "7BNJI4rUT6",
 		}
-		_, err := handler.saveLine(mustMarshal(changedLine))
+		_, err := handler.saveLine(context.Background(), mustMarshal(changedLine))
 		require.NoError(t, err)
 		assert.Equal(t, count, len(manager.Lines()))
 		createdLine, _ := manager.Line("7BNJI4rUT6")
@@ -63,7 +65,7 @@ func TestLineHandler_SaveLine(t *testing.T) {
 		}, createdLine.Path[1])
 	})
 	t.Run("create line", func(t *testing.T) {
-		lineObj, err := handler.saveLine(nil)
+		lineObj, err := handler.saveLine(context.Background(), nil)
 		require.NoError(t, err)
 		var line types.Line
 		err = json.Unmarshal(lineObj, &line)
@@ -82,15 +84,162 @@ func TestLineHandler_SaveLine(t *testing.T) {
 			Key:   "7BNJI4rUT6",
 			Stops: []string{"ORxFvp_ICt", "does not exist", "zmdfh1U3G6"},
 		}
-		result, err := handler.saveLine(mustMarshal(changedLine))
+		result, err := handler.saveLine(context.Background(), mustMarshal(changedLine))
 		assert.Nil(t, result)
 		assert.EqualError(t, err, "a station with key \"does not exist\" does not exist")
 	})
 }
 
+func TestLineHandler_SaveLine_Timetables(t *testing.T) {
+	createManager := func() *scenario.Manager {
+		manager := scenario.Empty()
+		for _, key := range []string{"a", "b", "c"} {
+			manager.SaveStation(scenario.Station{Key: key})
+		}
+		manager.SaveLine(scenario.Line{Key: "line1", Stops: []string{"a", "c"}})
+		manager.SaveTimetable(scenario.Timetable{
+			Key:         "timetable1",
+			LineKey:     "line1",
+			Name:        "Working Day",
+			StationKeys: []string{"a", "c"},
+			Tours:       []scenario.Tour{{Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("8:00")}, {Arrival: scenario.MustParseTime("8:10")}}}},
+		})
+		return manager
+	}
+	durations := []float64{120, 480, 0}
+	line := types.Line{
+		Key:   "line1",
+		Stops: []string{"a", "b", "c"},
+		Path: []types.Waypoint{
+			{Dur: &durations[0], Stop: true},
+			{Dur: &durations[1], Stop: true},
+			{Dur: &durations[2], Stop: true},
+		},
+	}
+	t.Run("report", func(t *testing.T) {
+		manager := createManager()
+		handler := newLineHandler(manager, routing.NewOSRM(""))
+		rawResult, err := handler.saveLine(context.Background(), mustMarshal(line))
+		require.NoError(t, err)
+		var result types.Line
+		_ = json.Unmarshal(rawResult, &result)
+		assert.Equal(t, []types.AffectedTimetable{
+			{Key: "timetable1", Name: "Working Day", AddedStations: []string{"b"}, RemovedStations: []string{}},
+		}, result.AffectedTimetables)
+		timetable, _ := manager.Timetable("timetable1")
+		assert.Equal(t, []string{"a", "c"}, timetable.StationKeys)
+	})
+	t.Run("migrate", func(t *testing.T) {
+		manager := createManager()
+		handler := newLineHandler(manager, routing.NewOSRM(""))
+		migrating := line
+		migrating.TimetablePolicy = "migrate"
+		rawResult, err := handler.saveLine(context.Background(), mustMarshal(migrating))
+		require.NoError(t, err)
+		var result types.Line
+		_ = json.Unmarshal(rawResult, &result)
+		require.Equal(t, 1, len(result.AffectedTimetables))
+		assert.True(t, result.AffectedTimetables[0].Migrated)
+		timetable, _ := manager.Timetable("timetable1")
+		assert.Equal(t, []string{"a", "b", "c"}, timetable.StationKeys)
+		assert.Equal(t, scenario.ArrivalDeparture{Departure: scenario.MustParseTime("8:02")}, timetable.Tours[0].Events[1])
+	})
+	t.Run("unknown policy", func(t *testing.T) {
+		handler := newLineHandler(createManager(), routing.NewOSRM(""))
+		unknown := line
+		unknown.TimetablePolicy = "ignore"
+		_, err := handler.saveLine(context.Background(), mustMarshal(unknown))
+		assert.EqualError(t, err, "the timetable policy \"ignore\" is unknown, use \"report\" or \"migrate\"")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+}
+
+func TestLineHandler_SaveLine_mode(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "a", Lat: 49.79745, Lng: 9.93503})
+	manager.SaveStation(scenario.Station{Key: "b", Lat: 49.80182, Lng: 9.92265})
+	bus := routing.NewHaversine()
+	bus.Speed = 5
+	modes := &routing.Modes{Router: bus, Routers: map[string]routing.Router{scenario.ModeTram: routing.NewHaversine()}}
+	handler := newLineHandler(manager, modes)
+	drawn := []types.Waypoint{{Lat: 49.79745, Lng: 9.93503, Stop: true}, {Lat: 49.8, Lng: 9.93}, {Lat: 49.80182, Lng: 9.92265, Stop: true}}
+
+	t.Run("keep the path of modes without router", func(t *testing.T) {
+		raw, err := handler.saveLine(context.Background(), mustMarshal(types.Line{Key: "line", Stops: []string{"a", "b"}, Path: drawn}))
+		require.NoError(t, err)
+		var result types.Line
+		_ = json.Unmarshal(raw, &result)
+		assert.Equal(t, 3, len(result.Path))
+		assert.Equal(t, "", result.Mode)
+	})
+	t.Run("reroute with the router of the mode", func(t *testing.T) {
+		raw, err := handler.saveLine(context.Background(), mustMarshal(types.Line{Key: "line", Stops: []string{"a", "b"}, Path: drawn, Mode: scenario.ModeTram}))
+		require.NoError(t, err)
+		var result types.Line
+		_ = json.Unmarshal(raw, &result)
+		assert.Equal(t, scenario.ModeTram, result.Mode)
+		require.Equal(t, 2, len(result.Path))
+		assert.InDelta(t, 1013/routing.DefaultHaversineSpeed, *result.Path[0].Dur, 1)
+		saved, _ := manager.Line("line")
+		assert.Equal(t, scenario.ModeTram, saved.Mode)
+	})
+	t.Run("reroute if the mode changes", func(t *testing.T) {
+		raw, err := handler.saveLine(context.Background(), mustMarshal(types.Line{Key: "line", Stops: []string{"a", "b"}, Path: drawn, Mode: scenario.ModeBus}))
+		require.NoError(t, err)
+		var result types.Line
+		_ = json.Unmarshal(raw, &result)
+		require.Equal(t, 2, len(result.Path))
+		assert.InDelta(t, 1013/5.0, *result.Path[0].Dur, 1)
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := handler.saveLine(context.Background(), mustMarshal(types.Line{Key: "line", Stops: []string{"a", "b"}, Mode: "hovercraft"}))
+		assert.ErrorAs(t, err, &invalidParamsError{})
+		saved, _ := manager.Line("line")
+		assert.Equal(t, scenario.ModeBus, saved.Mode)
+	})
+}
+
+func TestLineHandler_SaveLine_manual(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "a", Lat: 49.79745, Lng: 9.93503})
+	manager.SaveStation(scenario.Station{Key: "b", Lat: 49.80182, Lng: 9.92265})
+	manager.SaveStation(scenario.Station{Key: "c", Lat: 49.80500, Lng: 9.91000})
+	modes := &routing.Modes{Router: routing.NewHaversine(), Routers: map[string]routing.Router{scenario.ModeFerry: &emptyRouter{Router: routing.NewHaversine()}}}
+	handler := newLineHandler(manager, modes)
+	path := func(manual ...bool) []types.Waypoint {
+		return []types.Waypoint{
+			{Lat: 49.79745, Lng: 9.93503, Stop: true},
+			{Lat: 49.80182, Lng: 9.92265, Stop: true, Manual: manual[0]},
+			{Lat: 49.80400, Lng: 9.92000, Manual: manual[1]},
+			{Lat: 49.80500, Lng: 9.91000, Stop: true},
+		}
+	}
+
+	t.Run("partially marked segment", func(t *testing.T) {
+		_, err := handler.saveLine(context.Background(), mustMarshal(types.Line{Key: "line", Stops: []string{"a", "b", "c"}, Path: path(false, true)}))
+		assert.EqualError(t, err, "the segment from stop 1 to stop 2 must be marked as manual at all of its waypoints or at none")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+	t.Run("keep manual segments if the route of another segment is not found", func(t *testing.T) {
+		_, err := handler.saveLine(context.Background(), mustMarshal(types.Line{Key: "line", Stops: []string{"a", "b", "c"}, Path: path(true, true), Mode: scenario.ModeFerry}))
+		assert.EqualError(t, err, "could not route the line with transport mode \"ferry\": there is no route from stop 0 to stop 1")
+		_, ok := manager.Line("line")
+		assert.False(t, ok)
+	})
+}
+
+// emptyRouter does not find any routes.
+type emptyRouter struct {
+	routing.Router
+}
+
+func (r *emptyRouter) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	return []types.Waypoint{}, nil
+}
+
 func TestLineHandler_QueryLine(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
-	handler := newLineHandler(manager, "")
+	handler := newLineHandler(manager, routing.NewOSRM(""))
 	t.Run("success", func(t *testing.T) {
 		request := types.LineIdentifier{Key: "7BNJI4rUT6"}
 		result, err := handler.queryLine(mustMarshal(request))
@@ -128,7 +277,7 @@ func TestLineHandler_QueryLine(t *testing.T) {
 
 func TestLineHandler_GetLinePaths(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
-	handler := newLineHandler(manager, "")
+	handler := newLineHandler(manager, routing.NewOSRM(""))
 
 	response, err := handler.getLinePaths(nil)
 	assert.Nil(t, err)
@@ -152,7 +301,7 @@ func TestLineHandler_GetLinePaths(t *testing.T) {
 
 func TestLineHandler_DeleteLine(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
-	handler := newLineHandler(manager, "")
+	handler := newLineHandler(manager, routing.NewOSRM(""))
 	count := len(manager.Lines())
 	t.Run("success", func(t *testing.T) {
 		request := types.LineIdentifier{Key: "7BNJI4rUT6"}
@@ -19,6 +19,7 @@ func ToDtoLine(line scenario.Line) types.Line {
 		Name:     line.Name,
 		Color:    line.Color,
 		Key:      line.Key,
+		Mode:     line.Mode,
 	}
 }
 
@@ -52,6 +53,7 @@ func ToVoLine(line types.Line) scenario.Line {
 		Name:  line.Name,
 		Color: line.Color,
 		Key:   line.Key,
+		Mode:  line.Mode,
 	}
 }
 
@@ -63,11 +65,12 @@ func ToDtoWaypoints(waypoints []scenario.Waypoint) []types.Waypoint {
 	for _, waypoint := range waypoints {
 		wp := waypoint
 		path = append(path, types.Waypoint{
-			Lat:  waypoint.Lat,
-			Lng:  waypoint.Lng,
-			Dist: &wp.Dist,
-			Dur:  &wp.Dur,
-			Stop: waypoint.Stop,
+			Lat:    waypoint.Lat,
+			Lng:    waypoint.Lng,
+			Dist:   &wp.Dist,
+			Dur:    &wp.Dur,
+			Stop:   waypoint.Stop,
+			Manual: waypoint.Manual,
 		})
 	}
 	return path
@@ -88,63 +91,94 @@ func ToVoWaypoints(waypoints []types.Waypoint) []scenario.Waypoint {
 			dur = *wp.Dur
 		}
 		result = append(result, scenario.Waypoint{
-			Lat:  wp.Lat,
-			Lng:  wp.Lng,
-			Dist: dist,
-			Dur:  dur,
-			Stop: wp.Stop,
+			Lat:    wp.Lat,
+			Lng:    wp.Lng,
+			Dist:   dist,
+			Dur:    dur,
+			Stop:   wp.Stop,
+			Manual: wp.Manual,
 		})
 	}
 	return result
 }
 
 func ToDtoTimetable(timetable scenario.Timetable) types.Timetable {
-	tours := make([]types.Tour, 0, len(timetable.Tours))
 	stations := make([]types.Station, 0, len(timetable.StationKeys))
 	for _, station := range timetable.Stations() {
 		stations = append(stations, ToDtoStation(station, false))
 	}
-	for _, tour := range timetable.Tours {
+	result := types.Timetable{
+		Key:         timetable.Key,
+		Name:        timetable.Name,
+		CalendarKey: timetable.CalendarKey,
+		Tours:       ToDtoTours(timetable.Tours),
+		Stations:    stations,
+	}
+	result.LineKey = timetable.Line().Key
+	result.LineName = timetable.Line().Name
+	return result
+}
+
+func ToDtoTours(tours []scenario.Tour) []types.Tour {
+	result := make([]types.Tour, 0, len(tours))
+	for _, tour := range tours {
 		events := make([]types.ArrivalDeparture, 0, len(tour.Events))
 		for _, event := range tour.Events {
 			arrDep := types.ArrivalDeparture{
-				Arrival:   event.Arrival,
-				Departure: event.Departure,
+				Arrival:   event.Arrival.String(),
+				Departure: event.Departure.String(),
 			}
 			events = append(events, arrDep)
 		}
-		tours = append(tours, types.Tour{
+		result = append(result, types.Tour{
 			IntervalMinutes: tour.IntervalMinutes,
-			LastTour:        tour.LastTour,
+			LastTour:        tour.LastTour.String(),
 			Events:          events,
 		})
 	}
-	result := types.Timetable{
-		Key:      timetable.Key,
-		Name:     timetable.Name,
-		Tours:    tours,
-		Stations: stations,
-	}
-	result.LineKey = timetable.Line().Key
-	result.LineName = timetable.Line().Name
 	return result
 }
 
-func ToVoTimetable(timetable types.Timetable) scenario.Timetable {
+func ToVoEvents(events []types.ArrivalDeparture) ([]scenario.ArrivalDeparture, error) {
+	result := make([]scenario.ArrivalDeparture, 0, len(events))
+	for eventIndex, event := range events {
+		arrival, err := scenario.ParseOptionalTime(event.Arrival)
+		if err != nil {
+			return nil, fmt.Errorf("arrival of event %d: %v", eventIndex, err)
+		}
+		departure, err := scenario.ParseOptionalTime(event.Departure)
+		if err != nil {
+			return nil, fmt.Errorf("departure of event %d: %v", eventIndex, err)
+		}
+		result = append(result, scenario.ArrivalDeparture{
+			Arrival:   arrival,
+			Departure: departure,
+		})
+	}
+	return result, nil
+}
+
+func ToVoTimetable(timetable types.Timetable) (scenario.Timetable, error) {
 	tours := make([]scenario.Tour, 0, len(timetable.Tours))
-	for _, tour := range timetable.Tours {
-		events := make([]scenario.ArrivalDeparture, 0, len(tour.Events))
-		for _, event := range tour.Events {
-			events = append(events, scenario.ArrivalDeparture{
-				Arrival:   event.Arrival,
-				Departure: event.Departure,
-			})
+	for tourIndex, tour := range timetable.Tours {
+		events, err := ToVoEvents(tour.Events)
+		if err != nil {
+			return scenario.Timetable{}, fmt.Errorf("tour %d, %v", tourIndex, err)
+		}
+		lastTour, err := scenario.ParseOptionalTime(tour.LastTour)
+		if err != nil {
+			return scenario.Timetable{}, fmt.Errorf("tour %d, last tour: %v", tourIndex, err)
 		}
-		tours = append(tours, scenario.Tour{
+		converted := scenario.Tour{
 			IntervalMinutes: tour.IntervalMinutes,
-			LastTour:        tour.LastTour,
+			LastTour:        lastTour,
 			Events:          events,
-		})
+		}
+		err = converted.Validate()
+		if err != nil {
+			return scenario.Timetable{}, fmt.Errorf("tour %d: %v", tourIndex, err)
+		}
+		tours = append(tours, converted)
 	}
 	stations := make([]string, 0, len(timetable.Stations))
 	for _, station := range timetable.Stations {
@@ -154,8 +188,195 @@ func ToVoTimetable(timetable types.Timetable) scenario.Timetable {
 		Key:         timetable.Key,
 		LineKey:     timetable.LineKey,
 		Name:        timetable.Name,
+		CalendarKey: timetable.CalendarKey,
 		Tours:       tours,
 		StationKeys: stations,
+	}, nil
+}
+
+func ToDtoCalendar(calendar scenario.Calendar) types.Calendar {
+	return types.Calendar{
+		Key:          calendar.Key,
+		Name:         calendar.Name,
+		Weekdays:     calendar.WeekdayNames(),
+		StartDate:    scenario.FormatOptionalDate(calendar.StartDate),
+		EndDate:      scenario.FormatOptionalDate(calendar.EndDate),
+		AddedDates:   scenario.FormatDates(calendar.AddedDates),
+		RemovedDates: scenario.FormatDates(calendar.RemovedDates),
+	}
+}
+
+func ToVoCalendar(calendar types.Calendar) (scenario.Calendar, error) {
+	result := scenario.Calendar{Key: calendar.Key, Name: calendar.Name}
+	err := result.SetWeekdayNames(calendar.Weekdays)
+	if err != nil {
+		return scenario.Calendar{}, err
+	}
+	result.StartDate, err = scenario.ParseOptionalDate(calendar.StartDate)
+	if err != nil {
+		return scenario.Calendar{}, fmt.Errorf("start date: %v", err)
+	}
+	result.EndDate, err = scenario.ParseOptionalDate(calendar.EndDate)
+	if err != nil {
+		return scenario.Calendar{}, fmt.Errorf("end date: %v", err)
+	}
+	result.AddedDates, err = scenario.ParseDates(calendar.AddedDates)
+	if err != nil {
+		return scenario.Calendar{}, fmt.Errorf("added dates: %v", err)
+	}
+	result.RemovedDates, err = scenario.ParseDates(calendar.RemovedDates)
+	if err != nil {
+		return scenario.Calendar{}, fmt.Errorf("removed dates: %v", err)
+	}
+	return result, result.Validate()
+}
+
+func ToDtoTrips(trips []scenario.Trip) []types.Trip {
+	result := make([]types.Trip, 0, len(trips))
+	for _, trip := range trips {
+		events := make([]types.ArrivalDeparture, 0, len(trip.Events))
+		for _, event := range trip.Events {
+			events = append(events, types.ArrivalDeparture{
+				Arrival:   event.Arrival.String(),
+				Departure: event.Departure.String(),
+			})
+		}
+		result = append(result, types.Trip{
+			TourIndex: trip.TourIndex,
+			Start:     trip.Start.String(),
+			Events:    events,
+		})
+	}
+	return result
+}
+
+func ToDtoRunningTimeReport(report scenario.RunningTimeReport) types.RunningTimeReport {
+	line := report.Timetable.Line()
+	result := types.RunningTimeReport{
+		TimetableKey:  report.Timetable.Key,
+		TimetableName: report.Timetable.Name,
+		LineKey:       line.Key,
+		LineName:      line.Name,
+		Issues:        make([]types.RunningTimeIssue, 0, len(report.Issues)),
+	}
+	if report.Err != nil {
+		result.Error = report.Err.Error()
+	}
+	for _, issue := range report.Issues {
+		kind := "slack"
+		if issue.Tight {
+			kind = "tight"
+		}
+		result.Issues = append(result.Issues, types.RunningTimeIssue{
+			TourIndex:        issue.TourIndex,
+			FromKey:          issue.From.Key,
+			FromName:         issue.From.Name,
+			ToKey:            issue.To.Key,
+			ToName:           issue.To.Name,
+			Departure:        issue.Departure.String(),
+			Arrival:          issue.Arrival.String(),
+			ScheduledMinutes: float64(issue.ScheduledSeconds) / 60,
+			RoutedMinutes:    issue.RoutedSeconds / 60,
+			Kind:             kind,
+		})
+	}
+	return result
+}
+
+func ToDtoDemand(demand []scenario.Demand) []types.Demand {
+	result := make([]types.Demand, 0, len(demand))
+	for _, entry := range demand {
+		result = append(result, types.Demand{Origin: entry.Origin, Destination: entry.Destination, Trips: entry.Trips})
+	}
+	return result
+}
+
+func ToDtoDepartures(departures []scenario.Departure) []types.Departure {
+	result := make([]types.Departure, 0, len(departures))
+	for _, departure := range departures {
+		line := departure.Timetable.Line()
+		result = append(result, types.Departure{
+			Time:            departure.Time.String(),
+			LineKey:         line.Key,
+			LineName:        line.Name,
+			LineColor:       line.Color,
+			TimetableKey:    departure.Timetable.Key,
+			TimetableName:   departure.Timetable.Name,
+			TourIndex:       departure.TourIndex,
+			DestinationKey:  departure.Destination.Key,
+			DestinationName: departure.Destination.Name,
+		})
+	}
+	return result
+}
+
+func ToDtoStationTransfers(transfers scenario.StationTransfers) types.StationTransfers {
+	result := types.StationTransfers{
+		StationKey:  transfers.Station.Key,
+		StationName: transfers.Station.Name,
+		Score:       transfers.Score,
+		Pairs:       make([]types.LineTransfers, 0, len(transfers.Pairs)),
+	}
+	totalWait := 0
+	for _, pair := range transfers.Pairs {
+		missed := make([]types.MissedConnection, 0, len(pair.Missed))
+		for _, connection := range pair.Missed {
+			missed = append(missed, types.MissedConnection{
+				Arrival:   connection.Arrival.String(),
+				Departure: connection.Departure.String(),
+			})
+		}
+		result.Pairs = append(result.Pairs, types.LineTransfers{
+			FromLineKey:        pair.From.Key,
+			FromLineName:       pair.From.Name,
+			ToLineKey:          pair.To.Key,
+			ToLineName:         pair.To.Name,
+			Arrivals:           pair.Arrivals,
+			Connections:        pair.Connections,
+			AverageWaitMinutes: pair.AverageWaitSeconds() / 60,
+			MaxWaitMinutes:     float64(pair.MaxWaitSeconds) / 60,
+			MissedConnections:  missed,
+		})
+		result.Arrivals = result.Arrivals + pair.Arrivals
+		result.Connections = result.Connections + pair.Connections
+		result.MissedConnections = result.MissedConnections + len(pair.Missed)
+		totalWait = totalWait + pair.TotalWaitSeconds
+	}
+	if result.Connections > 0 {
+		result.AverageWaitMinutes = float64(totalWait) / float64(result.Connections) / 60
+	}
+	return result
+}
+
+func ToDtoJourney(journey scenario.Journey) types.Journey {
+	legs := make([]types.Leg, 0, len(journey.Legs))
+	for _, leg := range journey.Legs {
+		converted := types.Leg{
+			Type:      "walk",
+			FromKey:   leg.From.Key,
+			FromName:  leg.From.Name,
+			ToKey:     leg.To.Key,
+			ToName:    leg.To.Name,
+			Departure: leg.Departure.String(),
+			Arrival:   leg.Arrival.String(),
+		}
+		if !leg.IsWalk() {
+			line := leg.Timetable.Line()
+			converted.Type = "ride"
+			converted.LineKey = line.Key
+			converted.LineName = line.Name
+			converted.LineColor = line.Color
+			converted.TimetableKey = leg.Timetable.Key
+			converted.TourIndex = leg.TourIndex
+		}
+		legs = append(legs, converted)
+	}
+	return types.Journey{
+		Departure:       journey.Departure().String(),
+		Arrival:         journey.Arrival().String(),
+		DurationMinutes: float64(journey.Arrival().Sub(journey.Departure())) / 60,
+		Transfers:       journey.Transfers(),
+		Legs:            legs,
 	}
 }
 
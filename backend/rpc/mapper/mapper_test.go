@@ -170,6 +170,7 @@ func TestMapper_ToVoLine(t *testing.T) {
 		Key:      "line-xyz",
 		Name:     "Line 88",
 		Color:    "red",
+		Mode:     "tram",
 	}
 	got := ToVoLine(line)
 	assert.Equal(t, scenario.Line{
@@ -192,6 +193,7 @@ func TestMapper_ToVoLine(t *testing.T) {
 		Name:  "Line 88",
 		Color: "red",
 		Key:   "line-xyz",
+		Mode:  "tram",
 	}, got)
 }
 
@@ -206,11 +208,12 @@ func TestMapper_ToVoWaypoints(t *testing.T) {
 			Dur:  &dur,
 			Stop: true,
 		}, {
-			Lat:  77,
-			Lng:  68,
-			Dist: nil,
-			Dur:  nil,
-			Stop: false,
+			Lat:    77,
+			Lng:    68,
+			Dist:   nil,
+			Dur:    nil,
+			Stop:   false,
+			Manual: true,
 		},
 	}
 	got := ToVoWaypoints(waypoints)
@@ -222,11 +225,12 @@ func TestMapper_ToVoWaypoints(t *testing.T) {
 			Dur:  10,
 			Stop: true,
 		}, {
-			Lat:  77,
-			Lng:  68,
-			Dist: 0,
-			Dur:  0,
-			Stop: false,
+			Lat:    77,
+			Lng:    68,
+			Dist:   0,
+			Dur:    0,
+			Stop:   false,
+			Manual: true,
 		},
 	}, got)
 	t.Run("nil check", func(t *testing.T) {
@@ -261,18 +265,18 @@ func TestMapper_ToDtoTimetable(t *testing.T) {
 		Tours: []scenario.Tour{
 			{
 				IntervalMinutes: 30,
-				LastTour:        "16:30",
+				LastTour:        scenario.MustParseTime("16:30"),
 				Events: []scenario.ArrivalDeparture{
-					{Departure: "8:30"},
-					{Departure: "8:35"},
-					{Arrival: "8:40"},
+					{Departure: scenario.MustParseTime("8:30")},
+					{Departure: scenario.MustParseTime("8:35")},
+					{Arrival: scenario.MustParseTime("8:40")},
 				},
 			},
 			{
 				Events: []scenario.ArrivalDeparture{
-					{Departure: "17:30"},
-					{Departure: "17:35"},
-					{Arrival: "17:40"},
+					{Departure: scenario.MustParseTime("17:30")},
+					{Departure: scenario.MustParseTime("17:35")},
+					{Arrival: scenario.MustParseTime("17:40")},
 				},
 			},
 		},
@@ -356,7 +360,8 @@ func TestMapper_ToVoTimetable(t *testing.T) {
 		},
 		Stations: []types.Station{{Key: "a"}, {Key: "b"}, {Key: "c"}},
 	}
-	got := ToVoTimetable(timetable)
+	got, err := ToVoTimetable(timetable)
+	require.NoError(t, err)
 	assert.Equal(t, scenario.Timetable{
 		Key:     "tt-abc",
 		Name:    "Working Day",
@@ -364,18 +369,18 @@ func TestMapper_ToVoTimetable(t *testing.T) {
 		Tours: []scenario.Tour{
 			{
 				IntervalMinutes: 30,
-				LastTour:        "16:30",
+				LastTour:        scenario.MustParseTime("16:30"),
 				Events: []scenario.ArrivalDeparture{
-					{Departure: "8:30"},
-					{Departure: "8:35"},
-					{Arrival: "8:40"},
+					{Departure: scenario.MustParseTime("8:30")},
+					{Departure: scenario.MustParseTime("8:35")},
+					{Arrival: scenario.MustParseTime("8:40")},
 				},
 			},
 			{
 				Events: []scenario.ArrivalDeparture{
-					{Departure: "17:30"},
-					{Departure: "17:35"},
-					{Arrival: "17:40"},
+					{Departure: scenario.MustParseTime("17:30")},
+					{Departure: scenario.MustParseTime("17:35")},
+					{Arrival: scenario.MustParseTime("17:40")},
 				},
 			},
 		},
@@ -383,6 +388,41 @@ func TestMapper_ToVoTimetable(t *testing.T) {
 	}, got)
 }
 
+func TestMapper_ToVoTimetable_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		tour types.Tour
+		want string
+	}{
+		{
+			name: "invalid departure",
+			tour: types.Tour{Events: []types.ArrivalDeparture{{Departure: "8:00"}, {Departure: "4:7x"}}},
+			want: "tour 0, departure of event 1: the time \"4:7x\" is not in the format h:mm or h:mm:ss",
+		},
+		{
+			name: "invalid arrival",
+			tour: types.Tour{Events: []types.ArrivalDeparture{{Arrival: "48:00"}}},
+			want: "tour 0, arrival of event 0: the hours of time \"48:00\" must be smaller than 48",
+		},
+		{
+			name: "invalid last tour",
+			tour: types.Tour{IntervalMinutes: 10, LastTour: "9:60", Events: []types.ArrivalDeparture{{Departure: "8:00"}}},
+			want: "tour 0, last tour: the minutes and seconds of time \"9:60\" must be smaller than 60",
+		},
+		{
+			name: "events not in order",
+			tour: types.Tour{Events: []types.ArrivalDeparture{{Departure: "8:10"}, {Arrival: "8:12", Departure: "8:11"}}},
+			want: "tour 0: the time 8:11 of event 1 is earlier than the time 8:12 before",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToVoTimetable(types.Timetable{Tours: []types.Tour{tt.tour}})
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
 func TestToDtoVehicle(t *testing.T) {
 	dist := 11.0
 	dur := 12.0
@@ -1,9 +1,12 @@
 package rpc
 
 import (
+	"backend/rpc/mapper"
 	"backend/rpc/osrmutils"
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -11,14 +14,30 @@ import (
 	"sort"
 )
 
+const (
+	detourMetricDistance = "distance"
+	detourMetricDuration = "duration"
+)
+
+// defaultHistogramBinWidth and minHistogramBinWidth are the default and the smallest width of the histogram bins
+// of computeDetourMatrix, maxHistogramBins limits the number of bins a histogram may have.
+const (
+	defaultHistogramBinWidth = 0.1
+	minHistogramBinWidth     = 0.001
+	maxHistogramBins         = 10000
+)
+
+// detourPercentileRanks are the percentiles of the relative detours returned by computeDetourMatrix.
+var detourPercentileRanks = []int{5, 10, 25, 50, 75, 90, 95}
+
 type osrmHandler struct {
-	url     string
+	router  routing.Router
 	manager *scenario.Manager
 }
 
-func newOsrmHandler(manager *scenario.Manager, url string) *osrmHandler {
+func newOsrmHandler(manager *scenario.Manager, router routing.Router) *osrmHandler {
 	return &osrmHandler{
-		url:     url,
+		router:  router,
 		manager: manager,
 	}
 }
@@ -26,30 +45,77 @@ func newOsrmHandler(manager *scenario.Manager, url string) *osrmHandler {
 func (o *osrmHandler) Methods() map[string]rpcMethod {
 	return map[string]rpcMethod{
 		"queryRoute": {
-			description: "Queries the route connecting the given lat/lng pairs.",
-			input:       reflect.TypeOf([]types.LatLng{}),
-			output:      reflect.TypeOf([]types.Waypoint{}),
-			method:      o.queryRoute,
+			description:   "Queries the route connecting the given lat/lng pairs.",
+			input:         reflect.TypeOf([]types.LatLng{}),
+			output:        reflect.TypeOf([]types.Waypoint{}),
+			contextMethod: o.queryRoute,
 		},
 		"queryAddress": {
-			description: "Queries the name of the nearest street of the given lat/lng pair.",
-			input:       reflect.TypeOf(types.LatLng{}),
-			output:      reflect.TypeOf(types.AddressResponse{}),
-			method:      o.queryAddress,
+			description:   "Queries the name of the nearest street of the given lat/lng pair.",
+			input:         reflect.TypeOf(types.LatLng{}),
+			output:        reflect.TypeOf(types.AddressResponse{}),
+			contextMethod: o.queryAddress,
+		},
+		"getRouteCacheStats": {
+			description: "Returns the number of routing queries answered from the route cache (hits) and passed on to the router " +
+				"(misses) since the backend was started, and the number and total size in bytes of the cached results. Zero " +
+				"limits mean that there is no limit. The cache is disabled if the backend was started without a cache directory.",
+			input:  reflect.TypeOf(nil),
+			output: reflect.TypeOf(types.RouteCacheStats{}),
+			method: o.getRouteCacheStats,
 		},
 		"computeDetours": {
-			description: "Computes the detours for a line identified by a key.",
-			input:       reflect.TypeOf(types.DetourRequest{}),
-			output:      reflect.TypeOf(types.DetourResponse{}),
-			method:      o.computeDetour,
+			description: "Computes the detours for a line identified by a key. The direct distances and durations between the stops are " +
+				"queried from the table service of the router. The detours are ranked by the relative detour of the given metric, \"distance\" " +
+				"(default) or \"duration\". The demand detour weights the detours between all stations with the imported demand. " +
+				"The direct routes are queried with the router of the transport mode, bus by default.",
+			input:         reflect.TypeOf(types.DetourRequest{}),
+			output:        reflect.TypeOf(types.DetourResponse{}),
+			contextMethod: o.computeDetour,
+		},
+		"computeDetourSegments": {
+			description: "Attributes the detours of the station pairs selected by the cap like computeDetours to the segments of the " +
+				"path between consecutive stations. The absolute detour of the given metric of each pair is split among the segments " +
+				"it passes in proportion to their length. The score of a segment is the attributed detour per pair and unit of " +
+				"length, 0 if the segment is only part of direct connections and close to 1 if it is part of long detours.",
+			input:         reflect.TypeOf(types.DetourRequest{}),
+			output:        reflect.TypeOf(types.DetourSegmentsResponse{}),
+			contextMethod: o.computeDetourSegments,
+		},
+		"optimizeStopOrder": {
+			description: "Searches for stop orders of the line identified by the key that reduce its average relative detour of the " +
+				"given metric. Stations can be moved, parts of the order reversed and waypoint stations dropped, unless the terminals " +
+				"are fixed or the waypoint station is required. The figures are predicted from the routes between consecutive " +
+				"stations, also for the current order. Returns up to the given number of better candidates, 5 by default. The line " +
+				"is not modified.",
+			input:         reflect.TypeOf(types.StopOrderRequest{}),
+			output:        reflect.TypeOf(types.StopOrderResponse{}),
+			contextMethod: o.optimizeStopOrder,
+		},
+		"computeDemandDetours": {
+			description: "Weights the detours between all stations of each line with the imported demand and returns the trips, the " +
+				"weighted average relative detours and the passenger minutes lost to detours per line, ordered by the minutes lost. " +
+				"The trips in both directions between two stations are counted, waypoints are skipped. The direct routes are queried with the router of " +
+				"the transport mode of each line.",
+			input:         reflect.TypeOf(nil),
+			output:        reflect.TypeOf([]types.LineDemandDetour{}),
+			contextMethod: o.computeDemandDetours,
+		},
+		"computeDetourMatrix": {
+			description: "Computes the detours of all station pairs of a line selected by the cap like computeDetours and returns them " +
+				"ordered by source and target, together with the 5th, 10th, 25th, 50th, 75th, 90th and 95th percentile and a histogram " +
+				"of the relative detours of the given metric. The histogram bins have the given width, 0.1 by default and at least 0.001, and a histogram may have at most 10000 bins.",
+			input:         reflect.TypeOf(types.DetourMatrixRequest{}),
+			output:        reflect.TypeOf(types.DetourMatrixResponse{}),
+			contextMethod: o.computeDetourMatrix,
 		},
 	}
 }
 
-func (o *osrmHandler) queryRoute(params json.RawMessage) (json.RawMessage, error) {
+func (o *osrmHandler) queryRoute(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
 	var request []types.LatLng
 	_ = json.Unmarshal(params, &request)
-	waypoints, err := osrmutils.QueryRoute(o.url, request)
+	waypoints, err := o.router.Route(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -57,10 +123,10 @@ func (o *osrmHandler) queryRoute(params json.RawMessage) (json.RawMessage, error
 	return result, nil
 }
 
-func (o *osrmHandler) queryAddress(params json.RawMessage) (json.RawMessage, error) {
+func (o *osrmHandler) queryAddress(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
 	var request types.LatLng
 	_ = json.Unmarshal(params, &request)
-	name, err := osrmutils.QueryAddress(o.url, request)
+	name, err := o.router.Nearest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -71,54 +137,395 @@ func (o *osrmHandler) queryAddress(params json.RawMessage) (json.RawMessage, err
 	return result, nil
 }
 
-func (o *osrmHandler) computeDetour(params json.RawMessage) (json.RawMessage, error) {
-	var request types.DetourRequest
-	_ = json.Unmarshal(params, &request)
+func (o *osrmHandler) getRouteCacheStats(params json.RawMessage) (json.RawMessage, error) {
+	cache := routing.CacheOf(o.router)
+	if cache == nil {
+		return mustMarshal(types.RouteCacheStats{}), nil
+	}
+	stats := cache.Stats()
+	return mustMarshal(types.RouteCacheStats{
+		Enabled:    true,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Entries:    stats.Entries,
+		Size:       stats.Size,
+		MaxEntries: stats.MaxEntries,
+		MaxSize:    stats.MaxSize,
+	}), nil
+}
+
+// detourTable knows the direct distances and durations between all stops of a line and the distances and
+// durations along the line, so the detour between any two stations can be computed without further queries.
+type detourTable struct {
+	table routing.Table
+	// only stops are part of the table, tableIndex maps the index of a station to its index in the table
+	tableIndex []int
+	distances  []float64
+	durations  []float64
+}
+
+// queryDetourTable queries the direct routes between the stations with the router of the transport mode.
+func (o *osrmHandler) queryDetourTable(ctx context.Context, mode string, stations []types.Station, path []types.Waypoint) (*detourTable, error) {
+	tableIndex := make([]int, len(stations))
+	coordinates := make([]types.LatLng, 0, len(stations))
+	for index, station := range stations {
+		tableIndex[index] = len(coordinates)
+		if !station.IsWaypoint {
+			coordinates = append(coordinates, types.LatLng{Lat: station.Lat, Lng: station.Lng})
+		}
+	}
+	table, err := routing.ForMode(o.router, transportMode(mode)).Table(ctx, coordinates)
+	if err != nil {
+		return nil, fmt.Errorf("could not query detour: %v", err)
+	}
+	return &detourTable{
+		table:      table,
+		tableIndex: tableIndex,
+		distances:  osrmutils.DistanceBetweenStations(path),
+		durations:  osrmutils.DurationBetweenStations(path),
+	}, nil
+}
+
+// detour returns the detour from the source to the target station, false if it is undefined.
+func (d *detourTable) detour(source int, target int) (types.Detour, bool) {
+	routeLength := d.table.Distances[d.tableIndex[source]][d.tableIndex[target]]
+	routeDuration := d.table.Durations[d.tableIndex[source]][d.tableIndex[target]]
+	// the detour of stations without route or at the same location is undefined
+	if routeLength == nil || *routeLength == 0 || routeDuration == nil || *routeDuration == 0 {
+		return types.Detour{}, false
+	}
+	lineLength := d.distances[target] - d.distances[source]
+	lineDuration := d.durations[target] - d.durations[source]
+	return types.Detour{
+		Absolute:         lineLength - *routeLength,
+		Relative:         lineLength / *routeLength,
+		AbsoluteDuration: lineDuration - *routeDuration,
+		RelativeDuration: lineDuration / *routeDuration,
+		Source:           source,
+		Target:           target,
+	}, true
+}
+
+// detours computes the detours of the station pairs selected by the cap of the request. Pairs without route are omitted.
+func (o *osrmHandler) detours(ctx context.Context, request types.DetourRequest) ([]types.Detour, error) {
 	if len(request.Stations) < 2 {
-		return mustMarshal(types.DetourResponse{EmptyResult: true}), nil
+		return []types.Detour{}, nil
+	}
+	table, err := o.queryDetourTable(ctx, request.Mode, request.Stations, request.Path)
+	if err != nil {
+		return nil, err
 	}
-	stations := request.Stations
-	pairs := osrmutils.CreateQueryPairs(stations, request.Cap)
-	distances := osrmutils.DistanceBetweenStations(request.Path)
+	return table.detours(osrmutils.CreateQueryPairs(request.Stations, request.Cap)), nil
+}
+
+// detours returns the detours of the given pairs of station indices. Pairs without route are omitted.
+func (d *detourTable) detours(pairs [][2]int) []types.Detour {
 	detours := make([]types.Detour, 0, len(pairs))
-	detourSum := 0.0
 	for _, pair := range pairs {
-		source := stations[pair[0]]
-		target := stations[pair[1]]
-		route, err := osrmutils.QueryRoute(o.url, []types.LatLng{
-			{
-				Lat: source.Lat,
-				Lng: source.Lng,
-			},
-			{Lat: target.Lat, Lng: target.Lng},
+		detour, ok := d.detour(pair[0], pair[1])
+		if ok {
+			detours = append(detours, detour)
+		}
+	}
+	return detours
+}
+
+// segments attributes the absolute detours of the given metric to the segments between consecutive stations
+// the pairs pass, in proportion to the length of the segments. stopWaypoints contains the index of the
+// waypoint of each station in the path.
+func (d *detourTable) segments(detours []types.Detour, metric string, stopWaypoints []int) []types.DetourSegment {
+	accumulated := d.distances
+	if metric == detourMetricDuration {
+		accumulated = d.durations
+	}
+	result := make([]types.DetourSegment, 0, len(stopWaypoints))
+	for index := 0; index+1 < len(stopWaypoints); index++ {
+		result = append(result, types.DetourSegment{
+			Source:       index,
+			Target:       index + 1,
+			FromWaypoint: stopWaypoints[index],
+			ToWaypoint:   stopWaypoints[index+1],
 		})
-		if err != nil {
-			return nil, fmt.Errorf("could not query detour: %v", err)
+	}
+	for _, detour := range detours {
+		absolute := detour.Absolute
+		if metric == detourMetricDuration {
+			absolute = detour.AbsoluteDuration
+		}
+		total := accumulated[detour.Target] - accumulated[detour.Source]
+		if total <= 0 {
+			continue
 		}
-		routeLength := 0.0
-		for _, waypoint := range route {
-			if waypoint.Dist == nil {
+		for index := detour.Source; index < detour.Target; index++ {
+			result[index].Pairs++
+			result[index].AttributedDetour = result[index].AttributedDetour + absolute*(accumulated[index+1]-accumulated[index])/total
+		}
+	}
+	for index := range result {
+		length := accumulated[index+1] - accumulated[index]
+		if result[index].Pairs > 0 && length > 0 {
+			result[index].Score = result[index].AttributedDetour / (float64(result[index].Pairs) * length)
+		}
+	}
+	return result
+}
+
+// stationTrips is the demand between two stations of a line, given by their indices.
+type stationTrips struct {
+	source int
+	target int
+	trips  float64
+}
+
+// stationDemand returns the demand of the scenario between all stops of a line. The trips in both directions
+// between two stations are counted, as lines are usually operated in both directions on the same path. A pair
+// of stations that is served several times by the line is only counted at its first occurrence. Waypoints are
+// skipped, since passengers cannot board there.
+func (o *osrmHandler) stationDemand(stations []types.Station) []stationTrips {
+	demand := make([]stationTrips, 0)
+	counted := make(map[[2]string]bool)
+	for source := range stations {
+		if stations[source].IsWaypoint {
+			continue
+		}
+		for target := source + 1; target < len(stations); target++ {
+			if stations[target].IsWaypoint {
+				continue
+			}
+			from, to := stations[source].Key, stations[target].Key
+			if from > to {
+				from, to = to, from
+			}
+			if counted[[2]string{from, to}] {
 				continue
 			}
-			routeLength = routeLength + *waypoint.Dist
+			counted[[2]string{from, to}] = true
+			current := o.manager.Trips(from, to) + o.manager.Trips(to, from)
+			if current > 0 {
+				demand = append(demand, stationTrips{source: source, target: target, trips: current})
+			}
 		}
-		absolute := distances[pair[1]] - distances[pair[0]] - routeLength
-		relative := (distances[pair[1]] - distances[pair[0]]) / routeLength
-		detourSum = detourSum + relative
-		detours = append(detours, types.Detour{
-			Absolute: absolute,
-			Relative: relative,
-			Source:   pair[0],
-			Target:   pair[1],
-		})
+	}
+	return demand
+}
+
+// demandDetour weights the detours between the stations of a line with the demand. Detours that save time do
+// not reduce the passenger minutes lost.
+func (d *detourTable) demandDetour(demand []stationTrips) types.DemandDetour {
+	result := types.DemandDetour{}
+	for _, entry := range demand {
+		detour, ok := d.detour(entry.source, entry.target)
+		if !ok {
+			continue
+		}
+		result.Trips = result.Trips + entry.trips
+		result.WeightedAverageDetour = result.WeightedAverageDetour + entry.trips*detour.Relative
+		result.WeightedAverageDurationDetour = result.WeightedAverageDurationDetour + entry.trips*detour.RelativeDuration
+		result.PassengerMinutesLost = result.PassengerMinutesLost + entry.trips*math.Max(detour.AbsoluteDuration, 0)/60
+	}
+	if result.Trips > 0 {
+		result.WeightedAverageDetour = result.WeightedAverageDetour / result.Trips
+		result.WeightedAverageDurationDetour = result.WeightedAverageDurationDetour / result.Trips
+	}
+	return result
+}
+
+// checkDetourRequest sets the default metric of the request and fails for unknown metrics and transport modes.
+func checkDetourRequest(request *types.DetourRequest) error {
+	if request.Metric == "" {
+		request.Metric = detourMetricDistance
+	}
+	if request.Metric != detourMetricDistance && request.Metric != detourMetricDuration {
+		return invalidParamsError{err: fmt.Errorf("the metric \"%s\" is unknown, use \"%s\" or \"%s\"", request.Metric, detourMetricDistance, detourMetricDuration)}
+	}
+	err := scenario.ValidateMode(request.Mode)
+	if err != nil {
+		return invalidParamsError{err: err}
+	}
+	return nil
+}
+
+// transportMode returns the mode, bus if it is empty.
+func transportMode(mode string) string {
+	return scenario.Line{Mode: mode}.TransportMode()
+}
+
+// relativeDetour returns the relative detour of the given metric.
+func relativeDetour(detour types.Detour, metric string) float64 {
+	if metric == detourMetricDuration {
+		return detour.RelativeDuration
+	}
+	return detour.Relative
+}
+
+func (o *osrmHandler) computeDetour(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
+	var request types.DetourRequest
+	_ = json.Unmarshal(params, &request)
+	err := checkDetourRequest(&request)
+	if err != nil {
+		return nil, err
+	}
+	detours := make([]types.Detour, 0)
+	demandDetour := types.DemandDetour{}
+	if len(request.Stations) >= 2 {
+		table, err := o.queryDetourTable(ctx, request.Mode, request.Stations, request.Path)
+		if err != nil {
+			return nil, err
+		}
+		detours = table.detours(osrmutils.CreateQueryPairs(request.Stations, request.Cap))
+		demandDetour = table.demandDetour(o.stationDemand(request.Stations))
+	}
+	if len(detours) == 0 {
+		return mustMarshal(types.DetourResponse{EmptyResult: true, Metric: request.Metric, DemandDetour: demandDetour}), nil
+	}
+	detourSum := 0.0
+	durationDetourSum := 0.0
+	for _, detour := range detours {
+		detourSum = detourSum + detour.Relative
+		durationDetourSum = durationDetourSum + detour.RelativeDuration
 	}
 	sort.Slice(detours, func(i, j int) bool {
-		return detours[i].Relative < detours[j].Relative
+		return relativeDetour(detours[i], request.Metric) < relativeDetour(detours[j], request.Metric)
 	})
 	return mustMarshal(types.DetourResponse{
-		AverageDetour:  detourSum / float64(len(detours)),
-		BiggestDetour:  detours[len(detours)-1],
-		MedianDetour:   detours[int(math.Floor(float64(len(detours)/2)))],
-		SmallestDetour: detours[0],
+		Metric:                request.Metric,
+		AverageDetour:         detourSum / float64(len(detours)),
+		AverageDurationDetour: durationDetourSum / float64(len(detours)),
+		BiggestDetour:         detours[len(detours)-1],
+		MedianDetour:          detours[int(math.Floor(float64(len(detours)/2)))],
+		SmallestDetour:        detours[0],
+		DemandDetour:          demandDetour,
+	}), nil
+}
+
+func (o *osrmHandler) computeDetourSegments(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
+	var request types.DetourRequest
+	_ = json.Unmarshal(params, &request)
+	err := checkDetourRequest(&request)
+	if err != nil {
+		return nil, err
+	}
+	stopWaypoints := make([]int, 0, len(request.Stations))
+	for index, waypoint := range request.Path {
+		if waypoint.Stop {
+			stopWaypoints = append(stopWaypoints, index)
+		}
+	}
+	if len(stopWaypoints) != len(request.Stations) {
+		return nil, invalidParamsError{err: fmt.Errorf("the path has %d stops, but there are %d stations", len(stopWaypoints), len(request.Stations))}
+	}
+	if len(request.Stations) < 2 {
+		return mustMarshal(types.DetourSegmentsResponse{Metric: request.Metric, Segments: []types.DetourSegment{}}), nil
+	}
+	table, err := o.queryDetourTable(ctx, request.Mode, request.Stations, request.Path)
+	if err != nil {
+		return nil, err
+	}
+	detours := table.detours(osrmutils.CreateQueryPairs(request.Stations, request.Cap))
+	return mustMarshal(types.DetourSegmentsResponse{
+		Metric:   request.Metric,
+		Segments: table.segments(detours, request.Metric, stopWaypoints),
 	}), nil
 }
+
+func (o *osrmHandler) computeDemandDetours(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
+	lines := o.manager.Lines()
+	result := make([]types.LineDemandDetour, 0, len(lines))
+	for _, line := range lines {
+		stations := line.Stations()
+		converted := make([]types.Station, 0, len(stations))
+		for _, station := range stations {
+			converted = append(converted, mapper.ToDtoStation(station, false))
+		}
+		demandDetour := types.DemandDetour{}
+		if demand := o.stationDemand(converted); len(demand) > 0 {
+			table, err := o.queryDetourTable(ctx, line.Mode, converted, mapper.ToDtoWaypoints(line.Path))
+			if err != nil {
+				return nil, fmt.Errorf("line \"%s\": %v", line.Key, err)
+			}
+			demandDetour = table.demandDetour(demand)
+		}
+		result = append(result, types.LineDemandDetour{LineKey: line.Key, LineName: line.Name, DemandDetour: demandDetour})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].PassengerMinutesLost > result[j].PassengerMinutesLost
+	})
+	return mustMarshal(result), nil
+}
+
+func (o *osrmHandler) computeDetourMatrix(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
+	var request types.DetourMatrixRequest
+	_ = json.Unmarshal(params, &request)
+	err := checkDetourRequest(&request.DetourRequest)
+	if err != nil {
+		return nil, err
+	}
+	if request.HistogramBinWidth < 0 || request.HistogramBinWidth > 0 && request.HistogramBinWidth < minHistogramBinWidth {
+		return nil, invalidParamsError{err: fmt.Errorf("the histogram bin width must be at least %v", minHistogramBinWidth)}
+	}
+	if request.HistogramBinWidth == 0 {
+		request.HistogramBinWidth = defaultHistogramBinWidth
+	}
+	detours, err := o.detours(ctx, request.DetourRequest)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, 0, len(detours))
+	for _, detour := range detours {
+		values = append(values, relativeDetour(detour, request.Metric))
+	}
+	sort.Float64s(values)
+	histogram, err := detourHistogram(values, request.HistogramBinWidth)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	return mustMarshal(types.DetourMatrixResponse{
+		Metric:      request.Metric,
+		Detours:     detours,
+		Percentiles: detourPercentiles(values),
+		Histogram:   histogram,
+	}), nil
+}
+
+// detourPercentiles returns the percentiles of the sorted values using the nearest rank method.
+func detourPercentiles(values []float64) []types.DetourPercentile {
+	result := make([]types.DetourPercentile, 0, len(detourPercentileRanks))
+	if len(values) == 0 {
+		return result
+	}
+	for _, percentile := range detourPercentileRanks {
+		rank := int(math.Ceil(float64(percentile) / 100 * float64(len(values))))
+		if rank < 1 {
+			rank = 1
+		}
+		result = append(result, types.DetourPercentile{Percentile: percentile, Value: values[rank-1]})
+	}
+	return result
+}
+
+// detourHistogram counts the sorted values in bins of the given width. The first bin starts at the largest
+// multiple of the width not above the smallest value, the last bin contains the biggest value. Fails if more
+// than maxHistogramBins bins would be needed.
+func detourHistogram(values []float64, width float64) ([]types.HistogramBin, error) {
+	result := make([]types.HistogramBin, 0)
+	if len(values) == 0 {
+		return result, nil
+	}
+	// the epsilon avoids that values on a bin boundary end up in the bin before because of rounding errors
+	bin := func(value float64) float64 {
+		return math.Floor(value/width + 1e-9)
+	}
+	first := bin(values[0])
+	if bins := bin(values[len(values)-1]) - first + 1; bins > maxHistogramBins {
+		return nil, fmt.Errorf("the histogram would have %.0f bins, but at most %d are allowed, use a bigger bin width", bins, maxHistogramBins)
+	}
+	for _, value := range values {
+		bin := int(bin(value) - first)
+		for len(result) <= bin {
+			from := (first + float64(len(result))) * width
+			result = append(result, types.HistogramBin{From: from, To: from + width})
+		}
+		result[bin].Count++
+	}
+	return result, nil
+}
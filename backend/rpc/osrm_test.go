@@ -2,30 +2,35 @@ package rpc
 
 import (
 	"backend/rpc/mapper"
-	"backend/rpc/osrmutils"
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	polyline "github.com/twpayne/go-polyline"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 )
 
 func TestOsrmHandler_QueryRoute(t *testing.T) {
 	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.RequestURI == "/route/v1/driving/polyline%28_qo%5D_%7Brc@_seK_seK%29?overview=full&annotations=true" {
-			route := osrmutils.RouteResponse{
-				Routes: []osrmutils.Route{
+			route := routing.RouteResponse{
+				Routes: []routing.Route{
 					{
 						Geometry: "k|}nHq_q{@]f@IJ??",
-						Legs: []osrmutils.Leg{
+						Legs: []routing.Leg{
 							{
-								Annotation: osrmutils.Annotation{
+								Annotation: routing.Annotation{
 									Distance: []float64{
 										22.283466,
 										6.688107,
@@ -33,7 +38,7 @@ func TestOsrmHandler_QueryRoute(t *testing.T) {
 								},
 							},
 							{
-								Annotation: osrmutils.Annotation{
+								Annotation: routing.Annotation{
 									Distance: []float64{0},
 									Duration: []float64{0},
 								},
@@ -46,14 +51,14 @@ func TestOsrmHandler_QueryRoute(t *testing.T) {
 		}
 	}))
 	defer osrmServer.Close()
-	handler := newOsrmHandler(nil, osrmServer.URL)
+	handler := newOsrmHandler(nil, routing.NewOSRM(osrmServer.URL))
 
 	t.Run("happy path", func(t *testing.T) {
 		body, _ := json.Marshal([]types.LatLng{
 			{Lat: 5, Lng: 6},
 			{Lat: 7, Lng: 8},
 		})
-		raw, err := handler.queryRoute(body)
+		raw, err := handler.queryRoute(context.Background(), body)
 		require.NoError(t, err)
 		var response []scenario.Waypoint
 		_ = json.Unmarshal(raw, &response)
@@ -87,7 +92,7 @@ func TestOsrmHandler_QueryRoute(t *testing.T) {
 			{Lat: 5, Lng: 6},
 			{Lat: 7, Lng: 9},
 		})
-		_, err := handler.queryRoute(body)
+		_, err := handler.queryRoute(context.Background(), body)
 		assert.EqualError(t, err, "could not parse response from osrm: EOF")
 	})
 }
@@ -109,11 +114,11 @@ func TestOsrmHandler_QueryAddress(t *testing.T) {
 		}
 	}))
 	defer osrmServer.Close()
-	handler := newOsrmHandler(nil, osrmServer.URL)
+	handler := newOsrmHandler(nil, routing.NewOSRM(osrmServer.URL))
 
 	t.Run("happy path", func(t *testing.T) {
 		body, _ := json.Marshal(types.LatLng{Lat: 43, Lng: 42})
-		raw, _ := handler.queryAddress(body)
+		raw, _ := handler.queryAddress(context.Background(), body)
 		var response struct {
 			Name string `json:"name"`
 		}
@@ -124,11 +129,36 @@ func TestOsrmHandler_QueryAddress(t *testing.T) {
 
 	t.Run("OSRM problem", func(t *testing.T) {
 		body, _ := json.Marshal(types.LatLng{Lat: 5, Lng: 6})
-		_, err := handler.queryAddress(body)
+		_, err := handler.queryAddress(context.Background(), body)
 		assert.EqualError(t, err, "could not parse response from osrm: EOF")
 	})
 }
 
+func TestOsrmHandler_getRouteCacheStats(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		handler := newOsrmHandler(nil, routing.NewHaversine())
+		raw, err := handler.getRouteCacheStats(nil)
+		require.NoError(t, err)
+		var stats types.RouteCacheStats
+		_ = json.Unmarshal(raw, &stats)
+		assert.Equal(t, types.RouteCacheStats{}, stats)
+	})
+	t.Run("enabled", func(t *testing.T) {
+		cache, err := routing.OpenCache(t.TempDir(), 10, 1000)
+		require.NoError(t, err)
+		handler := newOsrmHandler(nil, routing.NewCachedRouter(routing.NewHaversine(), cache, "haversine"))
+		for iteration := 0; iteration < 3; iteration++ {
+			_, err = handler.queryAddress(context.Background(), mustMarshal(types.LatLng{Lat: 43, Lng: 42}))
+			require.NoError(t, err)
+		}
+		raw, err := handler.getRouteCacheStats(nil)
+		require.NoError(t, err)
+		var stats types.RouteCacheStats
+		_ = json.Unmarshal(raw, &stats)
+		assert.Equal(t, types.RouteCacheStats{Enabled: true, Hits: 2, Misses: 1, Entries: 1, Size: 2, MaxEntries: 10, MaxSize: 1000}, stats)
+	})
+}
+
 func TestOsrmHandler_computeDetour(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
 	mockRouteLengths := map[string]float64{
@@ -148,35 +178,60 @@ func TestOsrmHandler_computeDetour(t *testing.T) {
 		"o~unH}rx{@eaF|xD": 6144.106921999997,
 		"sbvnHsxw{@a}Er~C": 5874.396053999997,
 	}
+	coordinateKey := func(coordinate []float64) string {
+		return fmt.Sprintf("%.5f,%.5f", coordinate[0], coordinate[1])
+	}
+	routeLengths := make(map[string]float64)
+	for geometry, distance := range mockRouteLengths {
+		coordinates, _, _ := polyline.DecodeCoords([]byte(geometry))
+		routeLengths[coordinateKey(coordinates[0])+";"+coordinateKey(coordinates[1])] = distance
+	}
 	polylineMatcher := regexp.MustCompile("polyline\\(([^)]+)\\)")
+	// the indices are separated by semicolons, which are not supported by url.ParseQuery
+	indexMatcher := func(name string) *regexp.Regexp {
+		return regexp.MustCompile(name + "=([0-9;]+)")
+	}
+	sourceMatcher := indexMatcher("sources")
+	targetMatcher := indexMatcher("destinations")
 	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		uri, _ := url.QueryUnescape(r.RequestURI)
-		geometry := polylineMatcher.FindStringSubmatch(uri)[1]
-		distance, ok := mockRouteLengths[geometry]
-		if !ok {
+		coordinates, _, _ := polyline.DecodeCoords([]byte(polylineMatcher.FindStringSubmatch(uri)[1]))
+		response := struct {
+			Code      string       `json:"code"`
+			Distances [][]*float64 `json:"distances"`
+			Durations [][]*float64 `json:"durations"`
+		}{Code: "Ok"}
+		found := false
+		for _, source := range strings.Split(sourceMatcher.FindStringSubmatch(uri)[1], ";") {
+			distances := make([]*float64, 0)
+			durations := make([]*float64, 0)
+			for _, target := range strings.Split(targetMatcher.FindStringSubmatch(uri)[1], ";") {
+				sourceIndex, _ := strconv.Atoi(source)
+				targetIndex, _ := strconv.Atoi(target)
+				distance, ok := routeLengths[coordinateKey(coordinates[sourceIndex])+";"+coordinateKey(coordinates[targetIndex])]
+				if !ok {
+					distances = append(distances, nil)
+					durations = append(durations, nil)
+					continue
+				}
+				found = true
+				// a direct route at 36 km/h
+				duration := distance / 10
+				distances = append(distances, &distance)
+				durations = append(durations, &duration)
+			}
+			response.Distances = append(response.Distances, distances)
+			response.Durations = append(response.Durations, durations)
+		}
+		if !found {
 			_, _ = w.Write([]byte("polyline not found"))
 			return
 		}
-		route := osrmutils.RouteResponse{
-			Routes: []osrmutils.Route{
-				{
-					Geometry: geometry,
-					Legs: []osrmutils.Leg{
-						{
-							Annotation: osrmutils.Annotation{
-								Distance: []float64{distance},
-								Duration: []float64{0},
-							},
-						},
-					},
-				},
-			},
-		}
-		_ = json.NewEncoder(w).Encode(route)
+		_ = json.NewEncoder(w).Encode(response)
 	}))
 	defer osrmServer.Close()
 
-	handler := newOsrmHandler(manager, osrmServer.URL)
+	handler := newOsrmHandler(manager, routing.NewOSRM(osrmServer.URL))
 
 	t.Run("happy path", func(t *testing.T) {
 		existingLine, _ := manager.Line("t2A39YXN2D")
@@ -186,33 +241,219 @@ func TestOsrmHandler_computeDetour(t *testing.T) {
 			Path:     line.Path,
 			Cap:      4,
 		}
-		result, err := handler.computeDetour(mustMarshal(request))
+		result, err := handler.computeDetour(context.Background(), mustMarshal(request))
 		require.NoError(t, err)
 		var response types.DetourResponse
 		_ = json.Unmarshal(result, &response)
 		require.Equal(t, types.DetourResponse{
-			AverageDetour: 1.497322385271123,
+			Metric:                "distance",
+			AverageDetour:         1.497322385271123,
+			AverageDurationDetour: 1.9500724874894797,
 			BiggestDetour: types.Detour{
-				Absolute: 3176.5483450000092,
-				Relative: 1.5316851551586694,
-				Source:   1,
-				Target:   24,
+				Absolute:         3176.5483450000092,
+				Relative:         1.5316851551586694,
+				AbsoluteDuration: 566.1508679000001,
+				RelativeDuration: 1.9476135079651244,
+				Source:           1,
+				Target:           24,
 			},
 			MedianDetour: types.Detour{
-				Absolute: 3176.5484670000096,
-				Relative: 1.4994806328200458,
-				Source:   0,
-				Target:   24,
+				Absolute:         3176.5484670000096,
+				Relative:         1.4994806328200458,
+				AbsoluteDuration: 562.1297024,
+				RelativeDuration: 1.883893012804754,
+				Source:           0,
+				Target:           24,
 			},
 			SmallestDetour: types.Detour{
-				Absolute: 3207.901361000014,
-				Relative: 1.4534531994396245,
-				Source:   0,
-				Target:   27,
+				Absolute:         3207.901361000014,
+				Relative:         1.4534531994396245,
+				AbsoluteDuration: 657.9617539000003,
+				RelativeDuration: 1.9300624577865897,
+				Source:           0,
+				Target:           27,
 			},
 		}, response)
 	})
 
+	t.Run("ranked by duration", func(t *testing.T) {
+		existingLine, _ := manager.Line("t2A39YXN2D")
+		line := mapper.ToDtoLine(existingLine)
+		request := types.DetourRequest{
+			Stations: line.Stations,
+			Path:     line.Path,
+			Cap:      4,
+			Metric:   "duration",
+		}
+		result, err := handler.computeDetour(context.Background(), mustMarshal(request))
+		require.NoError(t, err)
+		var response types.DetourResponse
+		_ = json.Unmarshal(result, &response)
+		assert.Equal(t, "duration", response.Metric)
+		assert.Equal(t, 1.497322385271123, response.AverageDetour)
+		assert.Equal(t, 1.9500724874894797, response.AverageDurationDetour)
+		assert.LessOrEqual(t, response.SmallestDetour.RelativeDuration, response.MedianDetour.RelativeDuration)
+		assert.LessOrEqual(t, response.MedianDetour.RelativeDuration, response.BiggestDetour.RelativeDuration)
+		assert.Less(t, response.SmallestDetour.RelativeDuration, 1.9300624577865897)
+	})
+
+	t.Run("matrix", func(t *testing.T) {
+		existingLine, _ := manager.Line("t2A39YXN2D")
+		line := mapper.ToDtoLine(existingLine)
+		request := types.DetourMatrixRequest{
+			DetourRequest: types.DetourRequest{
+				Stations: line.Stations,
+				Path:     line.Path,
+				Cap:      4,
+			},
+			HistogramBinWidth: 0.05,
+		}
+		result, err := handler.computeDetourMatrix(context.Background(), mustMarshal(request))
+		require.NoError(t, err)
+		var response types.DetourMatrixResponse
+		_ = json.Unmarshal(result, &response)
+		assert.Equal(t, "distance", response.Metric)
+		require.Equal(t, 15, len(response.Detours))
+		assert.Equal(t, types.Detour{
+			Absolute:         3207.901361000014,
+			Relative:         1.4534531994396245,
+			AbsoluteDuration: 657.9617539000003,
+			RelativeDuration: 1.9300624577865897,
+			Source:           0,
+			Target:           27,
+		}, response.Detours[4])
+		assert.Equal(t, 7, len(response.Percentiles))
+		assert.Equal(t, types.DetourPercentile{Percentile: 50, Value: 1.4994806328200458}, response.Percentiles[3])
+		count := 0
+		for _, bin := range response.Histogram {
+			count = count + bin.Count
+		}
+		assert.Equal(t, 15, count)
+		assert.InDelta(t, 1.45, response.Histogram[0].From, 0.0001)
+
+		request.HistogramBinWidth = 1e-12
+		_, err = handler.computeDetourMatrix(context.Background(), mustMarshal(request))
+		assert.EqualError(t, err, "the histogram bin width must be at least 0.001")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+
+	t.Run("segments", func(t *testing.T) {
+		existingLine, _ := manager.Line("t2A39YXN2D")
+		line := mapper.ToDtoLine(existingLine)
+		request := types.DetourRequest{
+			Stations: line.Stations,
+			Path:     line.Path,
+			Cap:      4,
+		}
+		result, err := handler.computeDetourSegments(context.Background(), mustMarshal(request))
+		require.NoError(t, err)
+		var response types.DetourSegmentsResponse
+		_ = json.Unmarshal(result, &response)
+		assert.Equal(t, "distance", response.Metric)
+		require.Equal(t, len(line.Stations)-1, len(response.Segments))
+		detours, err := handler.detours(context.Background(), request)
+		require.NoError(t, err)
+		expected := 0.0
+		for _, detour := range detours {
+			expected = expected + detour.Absolute
+		}
+		last := len(response.Segments) - 1
+		lastPairs := 0
+		for _, detour := range detours {
+			if detour.Target > last {
+				lastPairs++
+			}
+		}
+		attributed := 0.0
+		for index, segment := range response.Segments {
+			assert.Equal(t, index, segment.Source)
+			assert.True(t, line.Path[segment.FromWaypoint].Stop)
+			assert.Less(t, segment.FromWaypoint, segment.ToWaypoint)
+			attributed = attributed + segment.AttributedDetour
+		}
+		assert.InDelta(t, expected, attributed, 1e-6)
+		assert.Equal(t, lastPairs, response.Segments[last].Pairs)
+	})
+
+	t.Run("segments with a path not matching the stations", func(t *testing.T) {
+		existingLine, _ := manager.Line("t2A39YXN2D")
+		line := mapper.ToDtoLine(existingLine)
+		_, err := handler.computeDetourSegments(context.Background(), mustMarshal(types.DetourRequest{Stations: line.Stations[1:], Path: line.Path}))
+		assert.EqualError(t, err, fmt.Sprintf("the path has %d stops, but there are %d stations", len(line.Stations), len(line.Stations)-1))
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+
+	t.Run("weighted by demand", func(t *testing.T) {
+		manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
+		handler := newOsrmHandler(manager, routing.NewOSRM(osrmServer.URL))
+		existingLine, _ := manager.Line("t2A39YXN2D")
+		line := mapper.ToDtoLine(existingLine)
+		stations := line.Stations
+		manager.SetDemand([]scenario.Demand{
+			{Origin: stations[0].Key, Destination: stations[27].Key, Trips: 30},
+			{Origin: stations[27].Key, Destination: stations[0].Key, Trips: 10},
+			{Origin: stations[1].Key, Destination: stations[24].Key, Trips: 20},
+			// there is no route between these stations
+			{Origin: stations[0].Key, Destination: stations[5].Key, Trips: 100},
+		})
+		result, err := handler.computeDetour(context.Background(), mustMarshal(types.DetourRequest{
+			Stations: line.Stations,
+			Path:     line.Path,
+			Cap:      4,
+		}))
+		require.NoError(t, err)
+		var response types.DetourResponse
+		_ = json.Unmarshal(result, &response)
+		assert.Equal(t, 60.0, response.DemandDetour.Trips)
+		assert.InDelta(t, (40*1.4534531994396245+20*1.5316851551586694)/60, response.DemandDetour.WeightedAverageDetour, 1e-9)
+		assert.InDelta(t, (40*1.9300624577865897+20*1.9476135079651244)/60, response.DemandDetour.WeightedAverageDurationDetour, 1e-9)
+		assert.InDelta(t, (40*657.9617539000003+20*566.1508679000001)/60, response.DemandDetour.PassengerMinutesLost, 1e-6)
+		assert.Equal(t, 1.497322385271123, response.AverageDetour)
+
+		result, err = handler.computeDemandDetours(context.Background(), nil)
+		require.NoError(t, err)
+		var lines []types.LineDemandDetour
+		_ = json.Unmarshal(result, &lines)
+		require.Equal(t, len(manager.Lines()), len(lines))
+		assert.Equal(t, "t2A39YXN2D", lines[0].LineKey)
+		assert.Equal(t, existingLine.Name, lines[0].LineName)
+		assert.Equal(t, response.DemandDetour, lines[0].DemandDetour)
+	})
+
+	t.Run("weighted by demand with waypoints", func(t *testing.T) {
+		manager := scenario.Empty()
+		manager.SaveStation(scenario.Station{Key: "a", Lat: 49.79745, Lng: 9.93503})
+		manager.SaveStation(scenario.Station{Key: "w", Lat: 49.80182, Lng: 9.92265, IsWaypoint: true})
+		manager.SaveStation(scenario.Station{Key: "b", Lat: 49.80500, Lng: 9.91000})
+		manager.SetDemand([]scenario.Demand{
+			{Origin: "a", Destination: "w", Trips: 50},
+			{Origin: "a", Destination: "b", Trips: 10},
+		})
+		router := &tableCountingRouter{Router: routing.NewHaversine()}
+		handler := newOsrmHandler(manager, router)
+		stations := make([]types.Station, 0, 3)
+		for _, key := range []string{"a", "w", "b"} {
+			station, _ := manager.Station(key)
+			stations = append(stations, mapper.ToDtoStation(station, false))
+		}
+		path, err := routing.NewHaversine().Route(context.Background(), []types.LatLng{
+			{Lat: stations[0].Lat, Lng: stations[0].Lng}, {Lat: stations[1].Lat, Lng: stations[1].Lng}, {Lat: stations[2].Lat, Lng: stations[2].Lng},
+		})
+		require.NoError(t, err)
+		result, err := handler.computeDetour(context.Background(), mustMarshal(types.DetourRequest{Stations: stations, Path: path, Cap: 4}))
+		require.NoError(t, err)
+		var response types.DetourResponse
+		_ = json.Unmarshal(result, &response)
+		assert.Equal(t, 10.0, response.DemandDetour.Trips)
+		assert.Equal(t, 1, router.tables)
+	})
+
+	t.Run("unknown metric", func(t *testing.T) {
+		_, err := handler.computeDetour(context.Background(), mustMarshal(types.DetourRequest{Metric: "comfort"}))
+		assert.EqualError(t, err, "the metric \"comfort\" is unknown, use \"distance\" or \"duration\"")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+
 	t.Run("unable to retrieve osrm data", func(t *testing.T) {
 		existingLine, _ := manager.Line("S9BbG58UKu")
 		line := mapper.ToDtoLine(existingLine)
@@ -221,18 +462,120 @@ func TestOsrmHandler_computeDetour(t *testing.T) {
 			Path:     line.Path,
 			Cap:      4,
 		}
-		_, err := handler.computeDetour(mustMarshal(request))
+		_, err := handler.computeDetour(context.Background(), mustMarshal(request))
 		require.EqualError(t, err, "could not query detour: could not parse response from osrm: invalid character 'p' looking for beginning of value")
 	})
 
+	t.Run("transport mode", func(t *testing.T) {
+		existingLine, _ := manager.Line("S9BbG58UKu")
+		line := mapper.ToDtoLine(existingLine)
+		request := types.DetourRequest{
+			Stations: line.Stations,
+			Path:     line.Path,
+			Cap:      4,
+			Mode:     scenario.ModeTram,
+		}
+		modes := &routing.Modes{Router: handler.router, Routers: map[string]routing.Router{scenario.ModeTram: routing.NewHaversine()}}
+		result, err := newOsrmHandler(manager, modes).computeDetour(context.Background(), mustMarshal(request))
+		require.NoError(t, err)
+		var response types.DetourResponse
+		_ = json.Unmarshal(result, &response)
+		assert.False(t, response.EmptyResult)
+		// straight lines are never longer than the path of the line
+		assert.GreaterOrEqual(t, response.SmallestDetour.Relative, 1.0)
+
+		request.Mode = "hovercraft"
+		_, err = handler.computeDetour(context.Background(), mustMarshal(request))
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+
 	t.Run("empty result", func(t *testing.T) {
 		request := types.DetourRequest{
 			Cap: 0,
 		}
-		response, err := handler.computeDetour(mustMarshal(request))
+		response, err := handler.computeDetour(context.Background(), mustMarshal(request))
 		require.NoError(t, err)
 		var detour types.DetourResponse
 		_ = json.Unmarshal(response, &detour)
 		assert.True(t, detour.EmptyResult)
 	})
 }
+
+func TestDetourTable_segments(t *testing.T) {
+	table := detourTable{
+		distances: []float64{0, 100, 300, 400},
+		durations: []float64{0, 10, 20, 60},
+	}
+	detours := []types.Detour{
+		{Absolute: 200, AbsoluteDuration: 30, Source: 0, Target: 2},
+		{Absolute: 80, AbsoluteDuration: 0, Source: 1, Target: 3},
+	}
+	t.Run("distance", func(t *testing.T) {
+		segments := table.segments(detours, detourMetricDistance, []int{0, 4, 9, 12})
+		expected := []types.DetourSegment{
+			{Source: 0, Target: 1, FromWaypoint: 0, ToWaypoint: 4, Pairs: 1, AttributedDetour: 200.0 / 3, Score: 2.0 / 3},
+			{Source: 1, Target: 2, FromWaypoint: 4, ToWaypoint: 9, Pairs: 2, AttributedDetour: 560.0 / 3, Score: 0.7 * 2 / 3},
+			{Source: 2, Target: 3, FromWaypoint: 9, ToWaypoint: 12, Pairs: 1, AttributedDetour: 80.0 / 3, Score: 0.8 / 3},
+		}
+		require.Equal(t, len(expected), len(segments))
+		for index, segment := range segments {
+			assert.InDelta(t, expected[index].AttributedDetour, segment.AttributedDetour, 1e-9)
+			assert.InDelta(t, expected[index].Score, segment.Score, 1e-9)
+			segment.AttributedDetour, segment.Score = expected[index].AttributedDetour, expected[index].Score
+			assert.Equal(t, expected[index], segment)
+		}
+	})
+	t.Run("duration", func(t *testing.T) {
+		segments := table.segments(detours, detourMetricDuration, []int{0, 4, 9, 12})
+		assert.Equal(t, 15.0, segments[0].AttributedDetour)
+		assert.Equal(t, 15.0, segments[1].AttributedDetour)
+		assert.Equal(t, 0.0, segments[2].AttributedDetour)
+		assert.Equal(t, 0.75, segments[1].Score)
+	})
+}
+
+func TestDetourPercentiles(t *testing.T) {
+	assert.Equal(t, []types.DetourPercentile{}, detourPercentiles([]float64{}))
+	values := make([]float64, 0, 20)
+	for index := 1; index <= 20; index++ {
+		values = append(values, float64(index))
+	}
+	assert.Equal(t, []types.DetourPercentile{
+		{Percentile: 5, Value: 1},
+		{Percentile: 10, Value: 2},
+		{Percentile: 25, Value: 5},
+		{Percentile: 50, Value: 10},
+		{Percentile: 75, Value: 15},
+		{Percentile: 90, Value: 18},
+		{Percentile: 95, Value: 19},
+	}, detourPercentiles(values))
+}
+
+func TestDetourHistogram(t *testing.T) {
+	histogram, err := detourHistogram([]float64{}, 0.1)
+	require.NoError(t, err)
+	assert.Equal(t, []types.HistogramBin{}, histogram)
+	histogram, err = detourHistogram([]float64{1.12, 1.18, 1.3, 1.55}, 0.1)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(histogram))
+	counts := make([]int, 0, len(histogram))
+	for _, bin := range histogram {
+		counts = append(counts, bin.Count)
+	}
+	assert.Equal(t, []int{2, 0, 1, 0, 1}, counts)
+	assert.InDelta(t, 1.1, histogram[0].From, 0.0001)
+	assert.InDelta(t, 1.6, histogram[4].To, 0.0001)
+	_, err = detourHistogram([]float64{1, 5}, 0.0001)
+	assert.EqualError(t, err, "the histogram would have 40001 bins, but at most 10000 are allowed, use a bigger bin width")
+}
+
+// tableCountingRouter counts the table queries passed on to the router.
+type tableCountingRouter struct {
+	routing.Router
+	tables int
+}
+
+func (r *tableCountingRouter) Table(ctx context.Context, coordinates []types.LatLng) (routing.Table, error) {
+	r.tables++
+	return r.Router.Table(ctx, coordinates)
+}
@@ -0,0 +1,292 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cacheFileSuffix = ".json"
+
+// Cache stores results of routers on disk, so that they survive restarts and can be shared by all scenarios.
+// If the cache exceeds its limits, the least recently used entries are removed.
+type Cache struct {
+	directory  string
+	maxEntries int
+	maxSize    int64
+	mutex      sync.Mutex
+	entries    map[string]cacheEntry
+	size       int64
+	hits       int
+	misses     int
+}
+
+type cacheEntry struct {
+	size int64
+	used time.Time
+}
+
+// CacheStats describes the content and the usage of a cache since it was opened.
+type CacheStats struct {
+	Hits       int
+	Misses     int
+	Entries    int
+	Size       int64
+	MaxEntries int
+	MaxSize    int64
+}
+
+// OpenCache opens the cache in the directory and creates the directory if it does not exist. The cache keeps
+// at most maxEntries results with a total size of maxSize bytes, zero means that there is no limit.
+func OpenCache(directory string, maxEntries int, maxSize int64) (*Cache, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create route cache directory \"%s\": %v", directory, err)
+	}
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("could not read route cache directory \"%s\": %v", directory, err)
+	}
+	cache := &Cache{directory: directory, maxEntries: maxEntries, maxSize: maxSize, entries: make(map[string]cacheEntry)}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), cacheFileSuffix) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		cache.track(file.Name(), info.Size(), info.ModTime())
+	}
+	cache.removeFiles(cache.evict())
+	return cache, nil
+}
+
+// Stats returns the current statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return CacheStats{
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Entries:    len(c.entries),
+		Size:       c.size,
+		MaxEntries: c.maxEntries,
+		MaxSize:    c.maxSize,
+	}
+}
+
+// Clear removes all results from the cache, e.g. after the road data of the routing server has changed.
+func (c *Cache) Clear() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	files, err := os.ReadDir(c.directory)
+	if err != nil {
+		return fmt.Errorf("could not read route cache directory \"%s\": %v", c.directory, err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), cacheFileSuffix) {
+			continue
+		}
+		err = os.Remove(filepath.Join(c.directory, file.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove cached route \"%s\": %v", file.Name(), err)
+		}
+	}
+	c.entries = make(map[string]cacheEntry)
+	c.size = 0
+	return nil
+}
+
+// cacheKey returns the name of the file that stores the result of the operation with the given profile and
+// coordinates.
+func cacheKey(operation string, profile string, coordinates []types.LatLng) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s\n%s\n", operation, profile)
+	for _, coordinate := range coordinates {
+		_, _ = fmt.Fprintf(hash, "%v,%v\n", coordinate.Lat, coordinate.Lng)
+	}
+	return hex.EncodeToString(hash.Sum(nil)) + cacheFileSuffix
+}
+
+// get decodes the cached result into the value and reports whether it was found. The file is read without
+// holding the mutex, so that lookups of parallel queries do not wait for each other.
+func (c *Cache) get(key string, value any) bool {
+	file := filepath.Join(c.directory, key)
+	content, err := os.ReadFile(file)
+	if err == nil {
+		err = json.Unmarshal(content, value)
+	}
+	if err != nil {
+		// the entry may have been removed by another process or be corrupt
+		_ = os.Remove(file)
+		c.mutex.Lock()
+		c.forget(key)
+		c.misses++
+		c.mutex.Unlock()
+		return false
+	}
+	now := time.Now()
+	_ = os.Chtimes(file, now, now)
+	c.mutex.Lock()
+	// the file may have been written by another process, so the entry is created if it is missing
+	c.track(key, int64(len(content)), now)
+	c.hits++
+	c.mutex.Unlock()
+	return true
+}
+
+// put stores the value in the cache and removes the least recently used entries if the cache exceeds its limits.
+func (c *Cache) put(key string, value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if c.maxSize > 0 && int64(len(content)) > c.maxSize {
+		return nil
+	}
+	// the file is renamed into place to prevent other processes from reading incomplete results
+	temporary, err := os.CreateTemp(c.directory, "put-*")
+	if err != nil {
+		return err
+	}
+	_, err = temporary.Write(content)
+	closeErr := temporary.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), filepath.Join(c.directory, key))
+	}
+	if err != nil {
+		_ = os.Remove(temporary.Name())
+		return err
+	}
+	c.mutex.Lock()
+	c.track(key, int64(len(content)), time.Now())
+	evicted := c.evict()
+	c.mutex.Unlock()
+	c.removeFiles(evicted)
+	return nil
+}
+
+// track creates or replaces the entry of the key and updates the size of the cache. The mutex must be held.
+func (c *Cache) track(key string, size int64, used time.Time) {
+	c.size = c.size - c.entries[key].size + size
+	c.entries[key] = cacheEntry{size: size, used: used}
+}
+
+// forget removes the entry of the key and updates the size of the cache. The mutex must be held.
+func (c *Cache) forget(key string) {
+	c.size = c.size - c.entries[key].size
+	delete(c.entries, key)
+}
+
+func (c *Cache) removeFiles(keys []string) {
+	for _, key := range keys {
+		_ = os.Remove(filepath.Join(c.directory, key))
+	}
+}
+
+// evict forgets the least recently used entries until the cache is within its limits and returns their keys,
+// so that the files can be removed after the mutex is released. The mutex must be held.
+func (c *Cache) evict() []string {
+	if (c.maxEntries <= 0 || len(c.entries) <= c.maxEntries) && (c.maxSize <= 0 || c.size <= c.maxSize) {
+		return nil
+	}
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].used.Before(c.entries[keys[j]].used)
+	})
+	evicted := make([]string, 0)
+	for _, key := range keys {
+		if (c.maxEntries <= 0 || len(c.entries) <= c.maxEntries) && (c.maxSize <= 0 || c.size <= c.maxSize) {
+			break
+		}
+		c.forget(key)
+		evicted = append(evicted, key)
+	}
+	return evicted
+}
+
+// CachedRouter answers queries from the cache and passes the others on to the router. The profile identifies
+// the routing server and its road data, results of different profiles are cached separately.
+type CachedRouter struct {
+	Router  Router
+	Cache   *Cache
+	Profile string
+}
+
+// NewCachedRouter returns a router that caches the results of the router with the given profile.
+func NewCachedRouter(router Router, cache *Cache, profile string) *CachedRouter {
+	return &CachedRouter{Router: router, Cache: cache, Profile: profile}
+}
+
+// Route returns the cached route through the coordinates or queries and caches it.
+func (c *CachedRouter) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	key := cacheKey("route", c.Profile, coordinates)
+	var result []types.Waypoint
+	if c.Cache.get(key, &result) {
+		return result, nil
+	}
+	result, err := c.Router.Route(ctx, coordinates)
+	if err != nil {
+		return nil, err
+	}
+	_ = c.Cache.put(key, result)
+	return result, nil
+}
+
+// Nearest returns the cached street name at the coordinate or queries and caches it.
+func (c *CachedRouter) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	key := cacheKey("nearest", c.Profile, []types.LatLng{latLng})
+	var result string
+	if c.Cache.get(key, &result) {
+		return result, nil
+	}
+	result, err := c.Router.Nearest(ctx, latLng)
+	if err != nil {
+		return "", err
+	}
+	_ = c.Cache.put(key, result)
+	return result, nil
+}
+
+// Table returns the cached table of the coordinates or queries and caches it.
+func (c *CachedRouter) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	key := cacheKey("table", c.Profile, coordinates)
+	var result Table
+	if c.Cache.get(key, &result) {
+		return result, nil
+	}
+	result, err := c.Router.Table(ctx, coordinates)
+	if err != nil {
+		return Table{}, err
+	}
+	_ = c.Cache.put(key, result)
+	return result, nil
+}
+
+// CacheOf returns the cache of the router or of the default router of the transport modes, nil if the results
+// are not cached.
+func CacheOf(router Router) *Cache {
+	switch current := router.(type) {
+	case *CachedRouter:
+		return current.Cache
+	case *Modes:
+		return CacheOf(current.Router)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingRouter counts the queries that reach the haversine router.
+type countingRouter struct {
+	Haversine
+	queries int
+}
+
+func (c *countingRouter) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	c.queries++
+	return c.Haversine.Route(ctx, coordinates)
+}
+
+func (c *countingRouter) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	c.queries++
+	return fmt.Sprintf("%v", latLng.Lat), nil
+}
+
+func (c *countingRouter) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	c.queries++
+	return c.Haversine.Table(ctx, coordinates)
+}
+
+func TestCachedRouter(t *testing.T) {
+	coordinates := []types.LatLng{{Lat: 49.79745, Lng: 9.93503}, {Lat: 49.80182, Lng: 9.92265}}
+	t.Run("answers repeated queries from the cache", func(t *testing.T) {
+		cache, err := OpenCache(t.TempDir(), 0, 0)
+		require.NoError(t, err)
+		router := &countingRouter{Haversine: *NewHaversine()}
+		cached := NewCachedRouter(router, cache, "haversine")
+		for iteration := 0; iteration < 2; iteration++ {
+			path, err := cached.Route(context.Background(), coordinates)
+			require.NoError(t, err)
+			expected, _ := router.Haversine.Route(context.Background(), coordinates)
+			assert.Equal(t, expected, path)
+			table, err := cached.Table(context.Background(), coordinates)
+			require.NoError(t, err)
+			assert.Equal(t, 0.0, *table.Distances[0][0])
+			assert.InDelta(t, 1013, *table.Distances[0][1], 1)
+			name, err := cached.Nearest(context.Background(), coordinates[0])
+			require.NoError(t, err)
+			assert.Equal(t, "49.79745", name)
+		}
+		assert.Equal(t, 3, router.queries)
+		stats := cache.Stats()
+		assert.Equal(t, 3, stats.Hits)
+		assert.Equal(t, 3, stats.Misses)
+		assert.Equal(t, 3, stats.Entries)
+		assert.Greater(t, stats.Size, int64(0))
+	})
+	t.Run("separates profiles and coordinates", func(t *testing.T) {
+		cache, err := OpenCache(t.TempDir(), 0, 0)
+		require.NoError(t, err)
+		router := &countingRouter{Haversine: *NewHaversine()}
+		_, _ = NewCachedRouter(router, cache, "car").Route(context.Background(), coordinates)
+		_, _ = NewCachedRouter(router, cache, "bus").Route(context.Background(), coordinates)
+		_, _ = NewCachedRouter(router, cache, "bus").Route(context.Background(), []types.LatLng{coordinates[1], coordinates[0]})
+		assert.Equal(t, 3, router.queries)
+	})
+	t.Run("persists results", func(t *testing.T) {
+		directory := t.TempDir()
+		cache, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		_, _ = NewCachedRouter(NewHaversine(), cache, "haversine").Route(context.Background(), coordinates)
+
+		reopened, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, reopened.Stats().Entries)
+		assert.Equal(t, cache.Stats().Size, reopened.Stats().Size)
+		router := &countingRouter{Haversine: *NewHaversine()}
+		_, _ = NewCachedRouter(router, reopened, "haversine").Route(context.Background(), coordinates)
+		assert.Equal(t, 0, router.queries)
+	})
+	t.Run("clear", func(t *testing.T) {
+		directory := t.TempDir()
+		cache, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		router := &countingRouter{Haversine: *NewHaversine()}
+		cached := NewCachedRouter(router, cache, "haversine")
+		_, _ = cached.Route(context.Background(), coordinates)
+		require.NoError(t, cache.Clear())
+		assert.Equal(t, 0, cache.Stats().Entries)
+		assert.Equal(t, int64(0), cache.Stats().Size)
+		files, _ := os.ReadDir(directory)
+		assert.Empty(t, files)
+		_, _ = cached.Route(context.Background(), coordinates)
+		assert.Equal(t, 2, router.queries)
+	})
+	t.Run("entry written by another process", func(t *testing.T) {
+		directory := t.TempDir()
+		writer, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		reader, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		_, _ = NewCachedRouter(NewHaversine(), writer, "haversine").Route(context.Background(), coordinates)
+		router := &countingRouter{Haversine: *NewHaversine()}
+		_, _ = NewCachedRouter(router, reader, "haversine").Route(context.Background(), coordinates)
+		assert.Equal(t, 0, router.queries)
+		assert.Equal(t, 1, reader.Stats().Entries)
+		assert.Equal(t, writer.Stats().Size, reader.Stats().Size)
+	})
+	t.Run("parallel queries", func(t *testing.T) {
+		cache, err := OpenCache(t.TempDir(), 5, 0)
+		require.NoError(t, err)
+		cached := NewCachedRouter(NewHaversine(), cache, "haversine")
+		err = ForEach(context.Background(), 50, func(ctx context.Context, index int) error {
+			_, err := cached.Route(ctx, []types.LatLng{coordinates[0], {Lat: 49.8, Lng: 9.9 + float64(index%10)/100}})
+			return err
+		})
+		require.NoError(t, err)
+		stats := cache.Stats()
+		assert.Equal(t, 50, stats.Hits+stats.Misses)
+		assert.LessOrEqual(t, stats.Entries, 5)
+	})
+	t.Run("corrupt entry", func(t *testing.T) {
+		directory := t.TempDir()
+		cache, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		key := cacheKey("nearest", "haversine", coordinates[:1])
+		require.NoError(t, os.WriteFile(filepath.Join(directory, key), []byte("{"), 0644))
+		router := &countingRouter{Haversine: *NewHaversine()}
+		name, err := NewCachedRouter(router, cache, "haversine").Nearest(context.Background(), coordinates[0])
+		require.NoError(t, err)
+		assert.Equal(t, "49.79745", name)
+		assert.Equal(t, 1, router.queries)
+	})
+}
+
+func TestCache_evict(t *testing.T) {
+	t.Run("entries", func(t *testing.T) {
+		cache, err := OpenCache(t.TempDir(), 2, 0)
+		require.NoError(t, err)
+		require.NoError(t, cache.put("a.json", "first"))
+		require.NoError(t, cache.put("b.json", "second"))
+		var value string
+		// reading the first entry makes the second one the least recently used
+		require.True(t, cache.get("a.json", &value))
+		require.NoError(t, cache.put("c.json", "third"))
+		assert.Equal(t, 2, cache.Stats().Entries)
+		assert.True(t, cache.get("a.json", &value))
+		assert.False(t, cache.get("b.json", &value))
+		assert.True(t, cache.get("c.json", &value))
+	})
+	t.Run("size", func(t *testing.T) {
+		cache, err := OpenCache(t.TempDir(), 0, 20)
+		require.NoError(t, err)
+		require.NoError(t, cache.put("a.json", "0123456789"))
+		require.NoError(t, cache.put("b.json", "0123456789"))
+		assert.Equal(t, 1, cache.Stats().Entries)
+		assert.Equal(t, int64(12), cache.Stats().Size)
+		// values larger than the cache are not stored
+		require.NoError(t, cache.put("c.json", "012345678901234567890123456789"))
+		var value string
+		assert.False(t, cache.get("c.json", &value))
+		assert.True(t, cache.get("b.json", &value))
+	})
+	t.Run("on open", func(t *testing.T) {
+		directory := t.TempDir()
+		cache, err := OpenCache(directory, 0, 0)
+		require.NoError(t, err)
+		for _, key := range []string{"a.json", "b.json", "c.json"} {
+			require.NoError(t, cache.put(key, key))
+		}
+		reopened, err := OpenCache(directory, 1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, reopened.Stats().Entries)
+		files, _ := os.ReadDir(directory)
+		assert.Equal(t, 1, len(files))
+	})
+}
@@ -0,0 +1,141 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"fmt"
+	neturl "net/url"
+)
+
+// GraphHopper queries the HTTP API of a GraphHopper server.
+type GraphHopper struct {
+	URL     string
+	Profile string
+}
+
+// NewGraphHopper returns a router for the GraphHopper server with the given base URL and the "car" profile.
+func NewGraphHopper(url string) *GraphHopper {
+	return &GraphHopper{URL: url, Profile: "car"}
+}
+
+type graphHopperPoints struct {
+	// Coordinates are given as longitude, latitude.
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+type graphHopperRouteResponse struct {
+	Paths []struct {
+		Points           graphHopperPoints `json:"points"`
+		SnappedWaypoints graphHopperPoints `json:"snapped_waypoints"`
+		// Details contain intervals of the points from, to and the value of the interval.
+		Details struct {
+			Distance [][]float64 `json:"distance"`
+			Time     [][]float64 `json:"time"`
+		} `json:"details"`
+		Instructions []struct {
+			StreetName string `json:"street_name"`
+		} `json:"instructions"`
+	} `json:"paths"`
+}
+
+func (g *GraphHopper) routeUrl(coordinates []types.LatLng, parameters string) string {
+	query := neturl.Values{}
+	for _, coordinate := range coordinates {
+		query.Add("point", fmt.Sprintf("%f,%f", coordinate.Lat, coordinate.Lng))
+	}
+	query.Set("profile", g.Profile)
+	query.Set("points_encoded", "false")
+	return fmt.Sprintf("%s/route?%s&%s", g.URL, query.Encode(), parameters)
+}
+
+// Route queries the fastest route through the coordinates. The distances and durations of the waypoints are
+// taken from the path details, the stops are the points nearest to the snapped coordinates.
+func (g *GraphHopper) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	var response graphHopperRouteResponse
+	err := getJson(ctx, g.routeUrl(coordinates, "instructions=false&details=distance&details=time"), &response)
+	if err != nil {
+		return nil, fmt.Errorf("could not query GraphHopper route: %v", err)
+	}
+	if len(response.Paths) == 0 || len(response.Paths[0].Points.Coordinates) < 2 {
+		return []types.Waypoint{}, nil
+	}
+	route := response.Paths[0]
+	points := swapCoordinates(route.Points.Coordinates)
+	distances := make([]float64, len(points)-1)
+	durations := make([]float64, len(points)-1)
+	for _, interval := range route.Details.Distance {
+		if len(interval) == 3 && int(interval[1]) < len(points) {
+			spread(points, int(interval[0]), int(interval[1]), interval[2], distances)
+		}
+	}
+	for _, interval := range route.Details.Time {
+		if len(interval) == 3 && int(interval[1]) < len(points) {
+			// the time is given in milliseconds
+			spread(points, int(interval[0]), int(interval[1]), interval[2]/1000, durations)
+		}
+	}
+	return path(points, distances, durations, nearestPoints(points, swapCoordinates(route.SnappedWaypoints.Coordinates))), nil
+}
+
+// Nearest returns the street name of the first instruction of a route from the coordinate to itself, as
+// GraphHopper doesn't offer reverse geocoding without external services.
+func (g *GraphHopper) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	var response graphHopperRouteResponse
+	err := getJson(ctx, g.routeUrl([]types.LatLng{latLng, latLng}, "instructions=true&calc_points=false"), &response)
+	if err != nil {
+		return "", fmt.Errorf("could not query GraphHopper route: %v", err)
+	}
+	if len(response.Paths) == 0 || len(response.Paths[0].Instructions) == 0 {
+		return "", nil
+	}
+	return response.Paths[0].Instructions[0].StreetName, nil
+}
+
+type graphHopperMatrixRequest struct {
+	Points    [][]float64 `json:"points"`
+	OutArrays []string    `json:"out_arrays"`
+	Profile   string      `json:"profile"`
+	FailFast  bool        `json:"fail_fast"`
+}
+
+type graphHopperMatrixResponse struct {
+	Distances [][]*float64 `json:"distances"`
+	Times     [][]*float64 `json:"times"`
+}
+
+// Table queries the distances and durations between all coordinates from the matrix service of GraphHopper.
+func (g *GraphHopper) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	request := graphHopperMatrixRequest{
+		Points:    make([][]float64, 0, len(coordinates)),
+		OutArrays: []string{"distances", "times"},
+		Profile:   g.Profile,
+	}
+	for _, coordinate := range coordinates {
+		request.Points = append(request.Points, []float64{coordinate.Lng, coordinate.Lat})
+	}
+	var response graphHopperMatrixResponse
+	err := postJson(ctx, g.URL+"/matrix", request, &response)
+	if err != nil {
+		return Table{}, fmt.Errorf("could not query GraphHopper matrix: %v", err)
+	}
+	if len(response.Distances) != len(coordinates) || len(response.Times) != len(coordinates) {
+		return Table{}, fmt.Errorf("graphhopper returned a matrix with %d rows instead of %d", len(response.Distances), len(coordinates))
+	}
+	for row := range coordinates {
+		if len(response.Distances[row]) != len(coordinates) || len(response.Times[row]) != len(coordinates) {
+			return Table{}, fmt.Errorf("graphhopper returned a matrix with %d columns instead of %d", len(response.Distances[row]), len(coordinates))
+		}
+	}
+	return Table{Distances: response.Distances, Durations: response.Times}, nil
+}
+
+// swapCoordinates converts longitude, latitude pairs to latitude, longitude pairs.
+func swapCoordinates(coordinates [][]float64) [][]float64 {
+	result := make([][]float64, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		if len(coordinate) >= 2 {
+			result = append(result, []float64{coordinate[1], coordinate[0]})
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGraphHopper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/route" && r.URL.Query()["point"][0] == "1.000000,1.000000":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Connection between locations not found"}`))
+		case r.URL.Path == "/route" && r.URL.Query().Get("instructions") == "true":
+			_, _ = w.Write([]byte(`{"paths":[{"instructions":[{"street_name":"Juliuspromenade"}]}]}`))
+		case r.URL.Path == "/route":
+			assert.Equal(t, []string{"49.000000,9.000000", "49.002000,9.000000"}, r.URL.Query()["point"])
+			assert.Equal(t, "car", r.URL.Query().Get("profile"))
+			_, _ = w.Write([]byte(`{"paths":[{
+				"points":{"coordinates":[[9.0,49.0],[9.0,49.001],[9.0,49.002]]},
+				"snapped_waypoints":{"coordinates":[[9.0,49.0],[9.0,49.002]]},
+				"details":{"distance":[[0,2,220]],"time":[[0,1,10000],[1,2,12000]]}
+			}]}`))
+		case r.URL.Path == "/matrix":
+			var request graphHopperMatrixRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			assert.Equal(t, [][]float64{{9.0, 49.0}, {9.0, 49.002}}, request.Points)
+			_, _ = w.Write([]byte(`{"distances":[[0,220],[230,null]],"times":[[0,22],[23,null]]}`))
+		}
+	}))
+	defer server.Close()
+	router := NewGraphHopper(server.URL)
+	coordinates := []types.LatLng{{Lat: 49.0, Lng: 9.0}, {Lat: 49.002, Lng: 9.0}}
+	t.Run("route", func(t *testing.T) {
+		path, err := router.Route(context.Background(), coordinates)
+		require.NoError(t, err)
+		require.Equal(t, 3, len(path))
+		assert.Equal(t, []bool{true, false, true}, []bool{path[0].Stop, path[1].Stop, path[2].Stop})
+		assert.InDelta(t, 110, *path[0].Dist, 0.01)
+		assert.InDelta(t, 10, *path[0].Dur, 0.01)
+		assert.InDelta(t, 12, *path[1].Dur, 0.01)
+		assert.Nil(t, path[2].Dist)
+	})
+	t.Run("route not found", func(t *testing.T) {
+		_, err := router.Route(context.Background(), []types.LatLng{{Lat: 1, Lng: 1}, {Lat: 2, Lng: 2}})
+		assert.EqualError(t, err, "could not query GraphHopper route: the server responded with status 400: Connection between locations not found")
+	})
+	t.Run("nearest", func(t *testing.T) {
+		name, err := router.Nearest(context.Background(), coordinates[0])
+		require.NoError(t, err)
+		assert.Equal(t, "Juliuspromenade", name)
+	})
+	t.Run("table", func(t *testing.T) {
+		table, err := router.Table(context.Background(), coordinates)
+		require.NoError(t, err)
+		assert.Equal(t, 230.0, *table.Distances[1][0])
+		assert.Equal(t, 22.0, *table.Durations[0][1])
+		assert.Nil(t, table.Distances[1][1])
+	})
+}
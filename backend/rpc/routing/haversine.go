@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"context"
+)
+
+// DefaultHaversineSpeed is the speed in meters per second the haversine router assumes, 30 km/h.
+const DefaultHaversineSpeed = 30 / 3.6
+
+// Haversine is a fallback router without server, which connects the coordinates by straight lines. The
+// durations are derived from the distances with a constant speed.
+type Haversine struct {
+	// Speed is given in meters per second.
+	Speed float64
+}
+
+func NewHaversine() *Haversine {
+	return &Haversine{Speed: DefaultHaversineSpeed}
+}
+
+// Route connects the coordinates by straight lines, every coordinate is a stop.
+func (h *Haversine) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	if len(coordinates) < 2 {
+		return []types.Waypoint{}, nil
+	}
+	points := make([][]float64, 0, len(coordinates))
+	stops := make([]int, 0, len(coordinates))
+	for index, coordinate := range coordinates {
+		points = append(points, []float64{coordinate.Lat, coordinate.Lng})
+		stops = append(stops, index)
+	}
+	distances := make([]float64, len(points)-1)
+	durations := make([]float64, len(points)-1)
+	for index := range distances {
+		distances[index] = h.distance(coordinates[index], coordinates[index+1])
+		durations[index] = distances[index] / h.Speed
+	}
+	return path(points, distances, durations, stops), nil
+}
+
+// Nearest always returns an empty name, as the haversine router doesn't know any streets.
+func (h *Haversine) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	return "", nil
+}
+
+// Table returns the great-circle distances between the coordinates.
+func (h *Haversine) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	result := newTable(len(coordinates))
+	for source := range coordinates {
+		for target := range coordinates {
+			distance := h.distance(coordinates[source], coordinates[target])
+			duration := distance / h.Speed
+			result.Distances[source][target] = &distance
+			result.Durations[source][target] = &duration
+		}
+	}
+	return result, nil
+}
+
+func (h *Haversine) distance(from types.LatLng, to types.LatLng) float64 {
+	return scenario.HaversineDistance(from.Lat, from.Lng, to.Lat, to.Lng)
+}
@@ -0,0 +1,43 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	router := NewHaversine()
+	coordinates := []types.LatLng{{Lat: 49.79745, Lng: 9.93503}, {Lat: 49.80182, Lng: 9.92265}, {Lat: 49.80182, Lng: 9.92265}}
+	t.Run("route", func(t *testing.T) {
+		path, err := router.Route(context.Background(), coordinates)
+		require.NoError(t, err)
+		require.Equal(t, 3, len(path))
+		for _, waypoint := range path {
+			assert.True(t, waypoint.Stop)
+		}
+		assert.InDelta(t, 1013, *path[0].Dist, 1)
+		assert.InDelta(t, 1013/DefaultHaversineSpeed, *path[0].Dur, 1)
+		assert.Equal(t, 0.0, *path[1].Dist)
+		assert.Nil(t, path[2].Dist)
+	})
+	t.Run("route without enough coordinates", func(t *testing.T) {
+		path, err := router.Route(context.Background(), coordinates[:1])
+		require.NoError(t, err)
+		assert.Empty(t, path)
+	})
+	t.Run("table", func(t *testing.T) {
+		table, err := router.Table(context.Background(), coordinates)
+		require.NoError(t, err)
+		assert.InDelta(t, 1013, *table.Distances[0][1], 1)
+		assert.Equal(t, *table.Distances[0][1], *table.Distances[1][0])
+		assert.Equal(t, 0.0, *table.Durations[1][2])
+	})
+	t.Run("nearest", func(t *testing.T) {
+		name, err := router.Nearest(context.Background(), coordinates[0])
+		require.NoError(t, err)
+		assert.Equal(t, "", name)
+	})
+}
@@ -0,0 +1,411 @@
+package routing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// osmWay is a way of an OSM extract with the tags that matter for routing.
+type osmWay struct {
+	refs []int64
+	tags map[string]string
+}
+
+// osmData contains the coordinates of all nodes, given as latitude and longitude, and the ways with a
+// highway tag of an OSM extract.
+type osmData struct {
+	nodes map[int64][2]float64
+	ways  []osmWay
+}
+
+// routingTags are the tags of ways that are kept while reading an extract.
+var routingTags = map[string]bool{"highway": true, "maxspeed": true, "oneway": true, "junction": true, "name": true, "access": true}
+
+func (d *osmData) addWay(way osmWay) {
+	if _, ok := way.tags["highway"]; ok && len(way.refs) > 1 {
+		d.ways = append(d.ways, way)
+	}
+}
+
+// readOsmXml reads an extract in the OSM XML format.
+func readOsmXml(reader io.Reader) (*osmData, error) {
+	result := &osmData{nodes: make(map[int64][2]float64)}
+	decoder := xml.NewDecoder(reader)
+	var way *osmWay
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read OSM XML: %v", err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			attributes := make(map[string]string)
+			for _, attribute := range element.Attr {
+				attributes[attribute.Name.Local] = attribute.Value
+			}
+			switch element.Name.Local {
+			case "node":
+				id, err := strconv.ParseInt(attributes["id"], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("could not read the id of a node: %v", err)
+				}
+				lat, err := strconv.ParseFloat(attributes["lat"], 64)
+				if err != nil {
+					return nil, fmt.Errorf("could not read the latitude of node %d: %v", id, err)
+				}
+				lng, err := strconv.ParseFloat(attributes["lon"], 64)
+				if err != nil {
+					return nil, fmt.Errorf("could not read the longitude of node %d: %v", id, err)
+				}
+				result.nodes[id] = [2]float64{lat, lng}
+			case "way":
+				way = &osmWay{tags: make(map[string]string)}
+			case "nd":
+				if way != nil {
+					ref, err := strconv.ParseInt(attributes["ref"], 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("could not read a node reference of a way: %v", err)
+					}
+					way.refs = append(way.refs, ref)
+				}
+			case "tag":
+				if way != nil && routingTags[attributes["k"]] {
+					way.tags[attributes["k"]] = attributes["v"]
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == "way" && way != nil {
+				result.addWay(*way)
+				way = nil
+			}
+		}
+	}
+}
+
+// protoField is a field of a protocol buffers message. Value contains varints and fixed size numbers, data
+// the content of length delimited fields.
+type protoField struct {
+	number int
+	value  uint64
+	data   []byte
+}
+
+// parseProto calls the function for each field of the encoded protocol buffers message.
+func parseProto(data []byte, handle func(field protoField) error) error {
+	for len(data) > 0 {
+		key, size := binary.Uvarint(data)
+		if size <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[size:]
+		field := protoField{number: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			field.value, size = binary.Uvarint(data)
+			if size <= 0 {
+				return fmt.Errorf("invalid varint in field %d", field.number)
+			}
+			data = data[size:]
+		case 1:
+			if len(data) < 8 {
+				return fmt.Errorf("truncated field %d", field.number)
+			}
+			field.value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2:
+			length, size := binary.Uvarint(data)
+			if size <= 0 || uint64(len(data)-size) < length {
+				return fmt.Errorf("truncated field %d", field.number)
+			}
+			field.data = data[size : size+int(length)]
+			data = data[size+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return fmt.Errorf("truncated field %d", field.number)
+			}
+			field.value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", key&7, field.number)
+		}
+		err := handle(field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packedVarints decodes a packed repeated field of varints.
+func packedVarints(data []byte) ([]uint64, error) {
+	result := make([]uint64, 0, len(data))
+	for len(data) > 0 {
+		value, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid packed varint")
+		}
+		result = append(result, value)
+		data = data[size:]
+	}
+	return result, nil
+}
+
+// packedDeltas decodes a packed repeated field of zigzag encoded differences to the previous value.
+func packedDeltas(data []byte) ([]int64, error) {
+	values, err := packedVarints(data)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int64, 0, len(values))
+	current := int64(0)
+	for _, value := range values {
+		current = current + zigzag(value)
+		result = append(result, current)
+	}
+	return result, nil
+}
+
+func zigzag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+// supportedPbfFeatures are the required features of PBF files that readOsmPbf understands.
+var supportedPbfFeatures = map[string]bool{"OsmSchema-V0.6": true, "DenseNodes": true}
+
+// maxPbfHeaderSize and maxPbfBlobSize are the limits of the PBF specification, the latter applies to compressed
+// and uncompressed blobs.
+const (
+	maxPbfHeaderSize = 64 * 1024
+	maxPbfBlobSize   = 32 * 1024 * 1024
+)
+
+// readOsmPbf reads an extract in the OSM PBF format, a sequence of blobs containing protocol buffers messages.
+func readOsmPbf(reader io.Reader) (*osmData, error) {
+	result := &osmData{nodes: make(map[int64][2]float64)}
+	for index := 0; ; index++ {
+		var headerSize uint32
+		err := binary.Read(reader, binary.BigEndian, &headerSize)
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read blob %d: %v", index, err)
+		}
+		blobType, data, err := readPbfBlob(reader, headerSize)
+		if err != nil {
+			return nil, fmt.Errorf("could not read blob %d: %v", index, err)
+		}
+		if blobType == "OSMHeader" {
+			err = parseProto(data, func(field protoField) error {
+				if field.number == 4 && !supportedPbfFeatures[string(field.data)] {
+					return fmt.Errorf("the required feature \"%s\" is not supported", field.data)
+				}
+				return nil
+			})
+		} else if blobType == "OSMData" {
+			err = result.readPrimitiveBlock(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read blob %d: %v", index, err)
+		}
+	}
+}
+
+// readPbfBlob reads the header and the blob that follows and returns the type and the uncompressed content.
+func readPbfBlob(reader io.Reader, headerSize uint32) (string, []byte, error) {
+	if headerSize > maxPbfHeaderSize {
+		return "", nil, fmt.Errorf("the blob header has %d bytes, but at most %d are allowed", headerSize, maxPbfHeaderSize)
+	}
+	header := make([]byte, headerSize)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return "", nil, err
+	}
+	blobType := ""
+	blobSize := uint64(0)
+	err = parseProto(header, func(field protoField) error {
+		switch field.number {
+		case 1:
+			blobType = string(field.data)
+		case 3:
+			blobSize = field.value
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("blob header: %v", err)
+	}
+	if blobSize > maxPbfBlobSize {
+		return "", nil, fmt.Errorf("the blob has %d bytes, but at most %d are allowed", blobSize, maxPbfBlobSize)
+	}
+	blob := make([]byte, blobSize)
+	_, err = io.ReadFull(reader, blob)
+	if err != nil {
+		return "", nil, err
+	}
+	var raw, compressed []byte
+	err = parseProto(blob, func(field protoField) error {
+		switch field.number {
+		case 1:
+			raw = field.data
+		case 3:
+			compressed = field.data
+		case 4, 5, 6, 7:
+			return fmt.Errorf("the compression of field %d is not supported, only zlib", field.number)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if compressed == nil {
+		return blobType, raw, nil
+	}
+	decompressor, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = decompressor.Close() }()
+	raw, err = io.ReadAll(io.LimitReader(decompressor, maxPbfBlobSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(raw) > maxPbfBlobSize {
+		return "", nil, fmt.Errorf("the uncompressed blob has more than %d bytes", maxPbfBlobSize)
+	}
+	return blobType, raw, nil
+}
+
+// readPrimitiveBlock reads the nodes and ways of a block of an OSM PBF file.
+func (d *osmData) readPrimitiveBlock(data []byte) error {
+	stringTable := make([]string, 0)
+	groups := make([][]byte, 0)
+	granularity, latOffset, lngOffset := int64(100), int64(0), int64(0)
+	err := parseProto(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			return parseProto(field.data, func(field protoField) error {
+				if field.number == 1 {
+					stringTable = append(stringTable, string(field.data))
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, field.data)
+		case 17:
+			granularity = int64(field.value)
+		case 19:
+			latOffset = int64(field.value)
+		case 20:
+			lngOffset = int64(field.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	coordinate := func(lat int64, lng int64) [2]float64 {
+		return [2]float64{1e-9 * float64(latOffset+granularity*lat), 1e-9 * float64(lngOffset+granularity*lng)}
+	}
+	for _, group := range groups {
+		err = parseProto(group, func(field protoField) error {
+			switch field.number {
+			case 1:
+				return d.readNode(field.data, coordinate)
+			case 2:
+				return d.readDenseNodes(field.data, coordinate)
+			case 3:
+				return d.readWay(field.data, stringTable)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *osmData) readNode(data []byte, coordinate func(lat int64, lng int64) [2]float64) error {
+	var id, lat, lng int64
+	err := parseProto(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			id = zigzag(field.value)
+		case 8:
+			lat = zigzag(field.value)
+		case 9:
+			lng = zigzag(field.value)
+		}
+		return nil
+	})
+	d.nodes[id] = coordinate(lat, lng)
+	return err
+}
+
+func (d *osmData) readDenseNodes(data []byte, coordinate func(lat int64, lng int64) [2]float64) error {
+	var ids, lats, lngs []int64
+	err := parseProto(data, func(field protoField) error {
+		var err error
+		switch field.number {
+		case 1:
+			ids, err = packedDeltas(field.data)
+		case 8:
+			lats, err = packedDeltas(field.data)
+		case 9:
+			lngs, err = packedDeltas(field.data)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(lats) != len(ids) || len(lngs) != len(ids) {
+		return fmt.Errorf("the dense nodes have %d ids, but %d latitudes and %d longitudes", len(ids), len(lats), len(lngs))
+	}
+	for index, id := range ids {
+		d.nodes[id] = coordinate(lats[index], lngs[index])
+	}
+	return nil
+}
+
+func (d *osmData) readWay(data []byte, stringTable []string) error {
+	var keys, values []uint64
+	way := osmWay{tags: make(map[string]string)}
+	err := parseProto(data, func(field protoField) error {
+		var err error
+		switch field.number {
+		case 2:
+			keys, err = packedVarints(field.data)
+		case 3:
+			values, err = packedVarints(field.data)
+		case 8:
+			way.refs, err = packedDeltas(field.data)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("a way has %d keys, but %d values", len(keys), len(values))
+	}
+	for index, key := range keys {
+		if key >= uint64(len(stringTable)) || values[index] >= uint64(len(stringTable)) {
+			return fmt.Errorf("a tag of a way is not part of the string table")
+		}
+		if routingTags[stringTable[key]] {
+			way.tags[stringTable[key]] = stringTable[values[index]]
+		}
+	}
+	d.addWay(way)
+	return nil
+}
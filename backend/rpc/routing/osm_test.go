@@ -0,0 +1,197 @@
+package routing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// testOsmXml is a square of roads: a two-way street from 1 to 2, a oneway primary road from 2 to 3, a two-way
+// street from 3 over 4 back to 1 and a private service road and a footway from 1 to 3 that cannot be used.
+const testOsmXml = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+  <node id="1" lat="49.000" lon="9.900"/>
+  <node id="2" lat="49.000" lon="9.910"/>
+  <node id="3" lat="49.010" lon="9.910"/>
+  <node id="4" lat="49.010" lon="9.900">
+    <tag k="highway" v="bus_stop"/>
+  </node>
+  <way id="10">
+    <nd ref="1"/>
+    <nd ref="2"/>
+    <tag k="highway" v="residential"/>
+    <tag k="name" v="Main Street"/>
+    <tag k="surface" v="asphalt"/>
+  </way>
+  <way id="11">
+    <nd ref="2"/>
+    <nd ref="3"/>
+    <tag k="highway" v="primary"/>
+    <tag k="name" v="Ring Road"/>
+    <tag k="oneway" v="yes"/>
+    <tag k="maxspeed" v="50"/>
+  </way>
+  <way id="12">
+    <nd ref="3"/>
+    <nd ref="4"/>
+    <nd ref="1"/>
+    <tag k="highway" v="residential"/>
+    <tag k="name" v="Back Road"/>
+  </way>
+  <way id="13">
+    <nd ref="1"/>
+    <nd ref="3"/>
+    <tag k="highway" v="service"/>
+    <tag k="access" v="private"/>
+  </way>
+  <way id="14">
+    <nd ref="1"/>
+    <nd ref="3"/>
+    <tag k="highway" v="footway"/>
+  </way>
+  <way id="15">
+    <nd ref="1"/>
+    <nd ref="3"/>
+    <tag k="building" v="yes"/>
+  </way>
+</osm>`
+
+func appendTestVarint(data []byte, value uint64) []byte {
+	buffer := make([]byte, binary.MaxVarintLen64)
+	return append(data, buffer[:binary.PutUvarint(buffer, value)]...)
+}
+
+// protoMessage builds protocol buffers messages for tests.
+type protoMessage []byte
+
+func (m protoMessage) varint(number int, value uint64) protoMessage {
+	m = appendTestVarint(m, uint64(number)<<3)
+	return appendTestVarint(m, value)
+}
+
+func (m protoMessage) bytes(number int, data []byte) protoMessage {
+	m = appendTestVarint(m, uint64(number)<<3|2)
+	m = appendTestVarint(m, uint64(len(data)))
+	return append(m, data...)
+}
+
+func packedTestDeltas(values ...int64) []byte {
+	result := make([]byte, 0)
+	previous := int64(0)
+	for _, value := range values {
+		delta := value - previous
+		result = appendTestVarint(result, uint64(delta<<1)^uint64(delta>>63))
+		previous = value
+	}
+	return result
+}
+
+func packedTestVarints(values ...uint64) []byte {
+	result := make([]byte, 0)
+	for _, value := range values {
+		result = appendTestVarint(result, value)
+	}
+	return result
+}
+
+// appendTestBlob appends a blob with its header to the file, the content is compressed with zlib if requested.
+func appendTestBlob(t *testing.T, file []byte, blobType string, content []byte, compress bool) []byte {
+	blob := protoMessage{}.bytes(1, content)
+	if compress {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		_, err := writer.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		blob = protoMessage{}.varint(2, uint64(len(content))).bytes(3, compressed.Bytes())
+	}
+	header := protoMessage{}.bytes(1, []byte(blobType)).varint(3, uint64(len(blob)))
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(header)))
+	file = append(file, size...)
+	file = append(file, header...)
+	return append(file, blob...)
+}
+
+// testOsmPbf contains the nodes 1 to 3 of testOsmXml as dense nodes, node 4 as a plain node and the ways 10
+// and 11 with a granularity of 1000.
+func testOsmPbf(t *testing.T, features ...string) []byte {
+	headerBlock := protoMessage{}
+	for _, feature := range features {
+		headerBlock = headerBlock.bytes(4, []byte(feature))
+	}
+	stringTable := protoMessage{}
+	for _, value := range []string{"", "highway", "residential", "name", "Main Street", "primary", "oneway", "yes", "surface", "asphalt"} {
+		stringTable = stringTable.bytes(1, []byte(value))
+	}
+	denseNodes := protoMessage{}.
+		bytes(1, packedTestDeltas(1, 2, 3)).
+		bytes(8, packedTestDeltas(49000000, 49000000, 49010000)).
+		bytes(9, packedTestDeltas(9900000, 9910000, 9910000))
+	node := protoMessage{}.varint(1, 4<<1).varint(8, 49010000<<1).varint(9, 9900000<<1)
+	mainStreet := protoMessage{}.varint(1, 10).bytes(2, packedTestVarints(1, 3, 8)).bytes(3, packedTestVarints(2, 4, 9)).bytes(8, packedTestDeltas(1, 2))
+	ringRoad := protoMessage{}.varint(1, 11).bytes(2, packedTestVarints(1, 6)).bytes(3, packedTestVarints(5, 7)).bytes(8, packedTestDeltas(2, 3))
+	block := protoMessage{}.
+		bytes(1, stringTable).
+		bytes(2, protoMessage{}.bytes(2, denseNodes)).
+		bytes(2, protoMessage{}.bytes(1, node).bytes(3, mainStreet).bytes(3, ringRoad)).
+		varint(17, 1000)
+	file := appendTestBlob(t, nil, "OSMHeader", headerBlock, false)
+	return appendTestBlob(t, file, "OSMData", block, true)
+}
+
+func TestReadOsmXml(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		data, err := readOsmXml(strings.NewReader(testOsmXml))
+		require.NoError(t, err)
+		assert.Equal(t, 4, len(data.nodes))
+		assert.Equal(t, [2]float64{49.010, 9.910}, data.nodes[3])
+		require.Equal(t, 5, len(data.ways))
+		assert.Equal(t, []int64{1, 2}, data.ways[0].refs)
+		assert.Equal(t, map[string]string{"highway": "residential", "name": "Main Street"}, data.ways[0].tags)
+		assert.Equal(t, []int64{3, 4, 1}, data.ways[2].refs)
+	})
+	t.Run("invalid coordinate", func(t *testing.T) {
+		_, err := readOsmXml(strings.NewReader(`<osm><node id="1" lat="north" lon="9.9"/></osm>`))
+		assert.EqualError(t, err, "could not read the latitude of node 1: strconv.ParseFloat: parsing \"north\": invalid syntax")
+	})
+	t.Run("invalid XML", func(t *testing.T) {
+		_, err := readOsmXml(strings.NewReader(`<osm><node id="1"`))
+		assert.Error(t, err)
+	})
+}
+
+func TestReadOsmPbf(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		data, err := readOsmPbf(bytes.NewReader(testOsmPbf(t, "OsmSchema-V0.6", "DenseNodes")))
+		require.NoError(t, err)
+		require.Equal(t, 4, len(data.nodes))
+		assert.InDelta(t, 49.010, data.nodes[3][0], 1e-9)
+		assert.InDelta(t, 9.910, data.nodes[3][1], 1e-9)
+		assert.InDelta(t, 49.010, data.nodes[4][0], 1e-9)
+		assert.InDelta(t, 9.900, data.nodes[4][1], 1e-9)
+		require.Equal(t, 2, len(data.ways))
+		assert.Equal(t, []int64{1, 2}, data.ways[0].refs)
+		assert.Equal(t, map[string]string{"highway": "residential", "name": "Main Street"}, data.ways[0].tags)
+		assert.Equal(t, []int64{2, 3}, data.ways[1].refs)
+		assert.Equal(t, map[string]string{"highway": "primary", "oneway": "yes"}, data.ways[1].tags)
+	})
+	t.Run("unsupported feature", func(t *testing.T) {
+		_, err := readOsmPbf(bytes.NewReader(testOsmPbf(t, "OsmSchema-V0.6", "HistoricalInformation")))
+		assert.EqualError(t, err, "could not read blob 0: the required feature \"HistoricalInformation\" is not supported")
+	})
+	t.Run("too big when uncompressed", func(t *testing.T) {
+		file := appendTestBlob(t, nil, "OSMData", make([]byte, maxPbfBlobSize+1), true)
+		_, err := readOsmPbf(bytes.NewReader(file))
+		assert.EqualError(t, err, "could not read blob 0: the uncompressed blob has more than 33554432 bytes")
+	})
+	t.Run("truncated", func(t *testing.T) {
+		file := testOsmPbf(t, "OsmSchema-V0.6")
+		_, err := readOsmPbf(bytes.NewReader(file[:len(file)-10]))
+		assert.EqualError(t, err, "could not read blob 1: unexpected EOF")
+	})
+}
@@ -0,0 +1,229 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	polyline2 "github.com/twpayne/go-polyline"
+	neturl "net/url"
+	"strconv"
+	"strings"
+)
+
+type Annotation struct {
+	Distance []float64 `json:"distance"`
+	Duration []float64 `json:"duration"`
+}
+
+type Leg struct {
+	Annotation Annotation `json:"Annotation"`
+}
+
+type Route struct {
+	Legs     []Leg  `json:"legs"`
+	Geometry string `json:"geometry"`
+}
+
+type RouteResponse struct {
+	Routes []Route `json:"routes"`
+}
+
+// OSRM queries the HTTP API of the Open Source Routing Machine.
+type OSRM struct {
+	URL string
+	// MaxTableSize is the limit of coordinates per table request of the server.
+	MaxTableSize int
+	// Profile is the routing profile in the request URLs, "driving" if it is empty.
+	Profile string
+}
+
+// DefaultOSRMProfile is the profile of the OSRM demo server.
+const DefaultOSRMProfile = "driving"
+
+// NewOSRM returns a router for the OSRM server with the given base URL, the default profile and the default
+// table size.
+func NewOSRM(url string) *OSRM {
+	return &OSRM{URL: url, MaxTableSize: DefaultMaxTableSize, Profile: DefaultOSRMProfile}
+}
+
+func (o *OSRM) profile() string {
+	if o.Profile == "" {
+		return DefaultOSRMProfile
+	}
+	return o.Profile
+}
+
+// Route queries the fastest route through the coordinates in the given order.
+func (o *OSRM) Route(ctx context.Context, request []types.LatLng) ([]types.Waypoint, error) {
+	raw := make([][]float64, 0, len(request))
+	for _, coordinate := range request {
+		raw = append(raw, []float64{coordinate.Lat, coordinate.Lng})
+	}
+	polyline := polyline2.EncodeCoords(raw)
+	osrmResp, err := get(ctx, fmt.Sprintf("%s/route/v1/%s/polyline(%s)?overview=full&annotations=true", o.URL, o.profile(), polyline))
+	if err != nil {
+		return nil, fmt.Errorf("could not query OSRM route: %v", err)
+	}
+	defer func() { _ = osrmResp.Body.Close() }()
+	var osrmRoute RouteResponse
+	err = json.NewDecoder(osrmResp.Body).Decode(&osrmRoute)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse response from osrm: %v", err)
+	}
+	if len(osrmRoute.Routes) == 0 {
+		return []types.Waypoint{}, nil
+	}
+	geometry, _, err := polyline2.DecodeCoords([]byte(osrmRoute.Routes[0].Geometry))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse polyline \"%s\" from osrm: %v", osrmRoute.Routes[0].Geometry, err)
+	}
+	return processRoute(geometry, osrmRoute), nil
+}
+
+func processRoute(geometry [][]float64, osrmRoute RouteResponse) []types.Waypoint {
+	waypoints := make([]types.Waypoint, 0, len(geometry))
+	for _, wp := range geometry {
+		if len(waypoints) > 1 {
+			lastWp := waypoints[len(waypoints)-1]
+			// sometimes we get two identical waypoints. We filter them out.
+			if lastWp.Lat == wp[0] && lastWp.Lng == wp[1] {
+				continue
+			}
+		}
+		waypoints = append(waypoints, types.Waypoint{
+			Lat: wp[0],
+			Lng: wp[1],
+		})
+	}
+
+	wpIndex := 0
+	for _, leg := range osrmRoute.Routes[0].Legs {
+		waypoints[wpIndex].Stop = true
+		// have to check the wpIndex here and after the for loop. Because of the
+		// filtered waypoints (see above) their length isn't equal to the number
+		// of legs any more
+		for index := 0; wpIndex < len(waypoints) && index < len(leg.Annotation.
+			Distance); index++ {
+			waypoints[wpIndex].Dur = &leg.Annotation.Duration[index]
+			waypoints[wpIndex].Dist = &leg.Annotation.Distance[index]
+			wpIndex = wpIndex + 1
+		}
+		if wpIndex >= len(waypoints) {
+			break
+		}
+	}
+	waypoints[len(waypoints)-1].Stop = true
+	return waypoints
+}
+
+type tableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Distances [][]*float64 `json:"distances"`
+	Durations [][]*float64 `json:"durations"`
+}
+
+// Table queries the distances and durations between all coordinates from the table service of OSRM. Large
+// tables are split into several requests with at most MaxTableSize coordinates each, which are sent concurrently.
+func (o *OSRM) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	result := newTable(len(coordinates))
+	if len(coordinates) == 0 {
+		return result, nil
+	}
+	chunkSize := len(coordinates)
+	if chunkSize > o.MaxTableSize {
+		chunkSize = o.MaxTableSize / 2
+	}
+	if chunkSize < 1 {
+		return Table{}, fmt.Errorf("the table size %d is too small", o.MaxTableSize)
+	}
+	chunks := (len(coordinates) + chunkSize - 1) / chunkSize
+	err := ForEach(ctx, chunks*chunks, func(ctx context.Context, index int) error {
+		sourceStart := index / chunks * chunkSize
+		targetStart := index % chunks * chunkSize
+		sourceEnd := sourceStart + chunkSize
+		if sourceEnd > len(coordinates) {
+			sourceEnd = len(coordinates)
+		}
+		targetEnd := targetStart + chunkSize
+		if targetEnd > len(coordinates) {
+			targetEnd = len(coordinates)
+		}
+		return o.queryTableChunk(ctx, coordinates, sourceStart, sourceEnd, targetStart, targetEnd, &result)
+	})
+	if err != nil {
+		return Table{}, err
+	}
+	return result, nil
+}
+
+// queryTableChunk queries the routes from the sources [sourceStart, sourceEnd) to the targets [targetStart, targetEnd)
+// and stores them in the table. Concurrent calls must not overlap.
+func (o *OSRM) queryTableChunk(ctx context.Context, coordinates []types.LatLng, sourceStart, sourceEnd, targetStart, targetEnd int, table *Table) error {
+	raw := make([][]float64, 0, sourceEnd-sourceStart+targetEnd-targetStart)
+	sources := make([]string, 0, sourceEnd-sourceStart)
+	for index := sourceStart; index < sourceEnd; index++ {
+		sources = append(sources, strconv.Itoa(len(raw)))
+		raw = append(raw, []float64{coordinates[index].Lat, coordinates[index].Lng})
+	}
+	targets := make([]string, 0, targetEnd-targetStart)
+	for index := targetStart; index < targetEnd; index++ {
+		if sourceStart == targetStart {
+			targets = append(targets, strconv.Itoa(index-targetStart))
+			continue
+		}
+		targets = append(targets, strconv.Itoa(len(raw)))
+		raw = append(raw, []float64{coordinates[index].Lat, coordinates[index].Lng})
+	}
+	osrmResp, err := get(ctx, fmt.Sprintf("%s/table/v1/%s/polyline(%s)?sources=%s&destinations=%s&annotations=distance,duration",
+		o.URL, o.profile(), neturl.PathEscape(string(polyline2.EncodeCoords(raw))), strings.Join(sources, ";"), strings.Join(targets, ";")))
+	if err != nil {
+		return fmt.Errorf("could not query OSRM table: %v", err)
+	}
+	defer func() { _ = osrmResp.Body.Close() }()
+	var response tableResponse
+	err = json.NewDecoder(osrmResp.Body).Decode(&response)
+	if err != nil {
+		return fmt.Errorf("could not parse response from osrm: %v", err)
+	}
+	if response.Code != "Ok" {
+		return fmt.Errorf("osrm could not compute the table: %s %s", response.Code, response.Message)
+	}
+	if len(response.Distances) != len(sources) || len(response.Durations) != len(sources) {
+		return fmt.Errorf("osrm returned a table with %d rows instead of %d", len(response.Distances), len(sources))
+	}
+	for row := range sources {
+		if len(response.Distances[row]) != len(targets) || len(response.Durations[row]) != len(targets) {
+			return fmt.Errorf("osrm returned a table with %d columns instead of %d", len(response.Distances[row]), len(targets))
+		}
+		copy(table.Distances[sourceStart+row][targetStart:targetEnd], response.Distances[row])
+		copy(table.Durations[sourceStart+row][targetStart:targetEnd], response.Durations[row])
+	}
+	return nil
+}
+
+type osrmAddressResponse struct {
+	Waypoints []struct {
+		Name string `json:"name"`
+	} `json:"waypoints"`
+}
+
+// Nearest returns the name of the street nearest to the coordinate.
+func (o *OSRM) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	osrmResp, err := get(ctx, fmt.Sprintf("%s/nearest/v1/%s/%f,%f.json?number=1", o.URL, o.profile(), latLng.Lng, latLng.Lat))
+	if err != nil {
+		return "", fmt.Errorf("could not query OSRM Route: %v", err)
+	}
+	defer func() { _ = osrmResp.Body.Close() }()
+	var osrmWaypoints osrmAddressResponse
+	err = json.NewDecoder(osrmResp.Body).Decode(&osrmWaypoints)
+	if err != nil {
+		return "", fmt.Errorf("could not parse response from osrm: %v", err)
+	}
+	name := ""
+	if len(osrmWaypoints.Waypoints) > 0 {
+		name = osrmWaypoints.Waypoints[0].Name
+	}
+	return name, nil
+}
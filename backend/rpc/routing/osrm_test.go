@@ -0,0 +1,231 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	polyline "github.com/twpayne/go-polyline"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOSRM_Route(t *testing.T) {
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/route/v1/driving/polyline%28_qo%5D_%7Brc@_seK_seK%29?overview=full&annotations=true" {
+			route := RouteResponse{
+				Routes: []Route{
+					{
+						Geometry: "k|}nHq_q{@]f@IJ??",
+						Legs: []Leg{
+							{
+								Annotation: Annotation{
+									Distance: []float64{
+										22.283466,
+										6.688107,
+									}, Duration: []float64{2.1, 0.6},
+								},
+							},
+							{
+								Annotation: Annotation{
+									Distance: []float64{0},
+									Duration: []float64{0},
+								},
+							},
+						},
+					},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(route)
+		}
+	}))
+	defer osrmServer.Close()
+
+	t.Run("happy path", func(t *testing.T) {
+		response, err := NewOSRM(osrmServer.URL).Route(context.Background(), []types.LatLng{
+			{Lat: 5, Lng: 6},
+			{Lat: 7, Lng: 8},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, len(response), "there should be four waypoints in the response")
+		dist1 := 22.283466
+		dur1 := 2.1
+		assert.Equal(t, types.Waypoint{
+			Dist: &dist1,
+			Dur:  &dur1,
+			Lat:  49.80182,
+			Lng:  9.92265,
+			Stop: true,
+		}, response[0], "first domain.Waypoint")
+		dist2 := 6.688107
+		dur2 := 0.6
+		assert.Equal(t, types.Waypoint{
+			Dist: &dist2,
+			Dur:  &dur2,
+			Lat:  49.80197,
+			Lng:  9.922450000000001,
+			Stop: false,
+		}, response[1], "second domain.Waypoint")
+		dist3 := 0.0
+		assert.Equal(t, types.Waypoint{
+			Dist: &dist3,
+			Dur:  &dist3,
+			Lat:  49.80202,
+			Lng:  9.922390000000002,
+			Stop: true,
+		}, response[2], "third domain.Waypoint")
+	})
+
+	t.Run("OSRM not found", func(t *testing.T) {
+		_, err := NewOSRM("anything").Route(context.Background(), []types.LatLng{})
+		assert.EqualError(t, err, "could not query OSRM route: Get \"anything/route/v1/driving/polyline()?overview=full&annotations=true\": unsupported protocol scheme \"\"")
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		_, err := (&OSRM{URL: "anything", Profile: "bus"}).Route(context.Background(), []types.LatLng{})
+		assert.EqualError(t, err, "could not query OSRM route: Get \"anything/route/v1/bus/polyline()?overview=full&annotations=true\": unsupported protocol scheme \"\"")
+	})
+
+	t.Run("OSRM answer not parsable", func(t *testing.T) {
+		_, err := NewOSRM(osrmServer.URL).Route(context.Background(), []types.LatLng{
+			{Lat: 5, Lng: 6},
+			{Lat: 7, Lng: 9},
+		})
+		assert.EqualError(t, err, "could not parse response from osrm: EOF")
+	})
+}
+
+func TestOSRM_Table(t *testing.T) {
+	polylineMatcher := regexp.MustCompile("polyline\\(([^)]+)\\)\\?sources=([0-9;]+)&destinations=([0-9;]+)")
+	var requests int32
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		uri, _ := url.PathUnescape(r.RequestURI)
+		match := polylineMatcher.FindStringSubmatch(uri)
+		coordinates, _, _ := polyline.DecodeCoords([]byte(match[1]))
+		if len(coordinates) > 4 {
+			_, _ = w.Write([]byte(`{"code":"TooBig","message":"Too many table coordinates"}`))
+			return
+		}
+		// the latitude is the index of the coordinate, the distance between two coordinates 100 m per index
+		response := tableResponse{Code: "Ok"}
+		for _, source := range strings.Split(match[2], ";") {
+			distances := make([]*float64, 0)
+			durations := make([]*float64, 0)
+			for _, target := range strings.Split(match[3], ";") {
+				sourceIndex, _ := strconv.Atoi(source)
+				targetIndex, _ := strconv.Atoi(target)
+				distance := math.Abs(coordinates[targetIndex][0]-coordinates[sourceIndex][0]) * 100
+				duration := distance / 10
+				distances = append(distances, &distance)
+				durations = append(durations, &duration)
+			}
+			response.Distances = append(response.Distances, distances)
+			response.Durations = append(response.Durations, durations)
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer osrmServer.Close()
+	coordinates := make([]types.LatLng, 0)
+	for index := 0; index < 5; index++ {
+		coordinates = append(coordinates, types.LatLng{Lat: float64(index), Lng: 9})
+	}
+
+	t.Run("chunked", func(t *testing.T) {
+		requests = 0
+		table, err := (&OSRM{URL: osrmServer.URL, MaxTableSize: 4}).Table(context.Background(), coordinates)
+		require.NoError(t, err)
+		assert.Equal(t, int32(9), requests)
+		for source := range coordinates {
+			for target := range coordinates {
+				require.NotNil(t, table.Distances[source][target])
+				assert.InDelta(t, math.Abs(float64(target-source))*100, *table.Distances[source][target], 0.001)
+				assert.InDelta(t, math.Abs(float64(target-source))*10, *table.Durations[source][target], 0.001)
+			}
+		}
+	})
+	t.Run("single request", func(t *testing.T) {
+		requests = 0
+		table, err := (&OSRM{URL: osrmServer.URL, MaxTableSize: 4}).Table(context.Background(), coordinates[:4])
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), requests)
+		assert.InDelta(t, 300, *table.Distances[0][3], 0.001)
+	})
+	t.Run("no coordinates", func(t *testing.T) {
+		requests = 0
+		table, err := (&OSRM{URL: osrmServer.URL, MaxTableSize: 4}).Table(context.Background(), []types.LatLng{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), requests)
+		assert.Empty(t, table.Distances)
+		assert.Empty(t, table.Durations)
+	})
+	t.Run("OSRM error", func(t *testing.T) {
+		_, err := (&OSRM{URL: osrmServer.URL, MaxTableSize: 10}).Table(context.Background(), coordinates)
+		assert.EqualError(t, err, "osrm could not compute the table: TooBig Too many table coordinates")
+	})
+	t.Run("table size too small", func(t *testing.T) {
+		_, err := (&OSRM{URL: osrmServer.URL, MaxTableSize: 1}).Table(context.Background(), coordinates)
+		assert.EqualError(t, err, "the table size 1 is too small")
+	})
+}
+
+func TestOSRM_Nearest(t *testing.T) {
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/nearest/v1/driving/42.000000,43.000000.json?number=1" {
+			address := struct {
+				Waypoints []struct{ Name string }
+			}{Waypoints: []struct{ Name string }{{Name: "Court Street"}}}
+			_ = json.NewEncoder(w).Encode(address)
+			return
+		}
+		if r.RequestURI == "/nearest/v1/driving/42.000000,52.000000.json?number=1" {
+			address := struct {
+				Waypoints []struct{ Name string }
+			}{Waypoints: []struct{ Name string }{}}
+			_ = json.NewEncoder(w).Encode(address)
+		}
+	}))
+	defer osrmServer.Close()
+
+	t.Run("happy path", func(t *testing.T) {
+		name, err := NewOSRM(osrmServer.URL).Nearest(context.Background(), types.LatLng{Lat: 43, Lng: 42})
+		assert.NoError(t, err)
+		assert.Equal(t, "Court Street", name)
+	})
+
+	t.Run("happy path empty answer", func(t *testing.T) {
+		name, err := NewOSRM(osrmServer.URL).Nearest(context.Background(), types.LatLng{Lat: 52, Lng: 42})
+		assert.NoError(t, err)
+		assert.Equal(t, "", name)
+	})
+
+	t.Run("OSRM not found", func(t *testing.T) {
+		_, err := NewOSRM("anything").Nearest(context.Background(), types.LatLng{Lat: 5, Lng: 6})
+		assert.EqualError(t, err, "could not query OSRM Route: Get \"anything/nearest/v1/driving/6.000000,5.000000.json?number=1\": unsupported protocol scheme \"\"")
+	})
+
+	t.Run("OSRM answer not parsable", func(t *testing.T) {
+		_, err := NewOSRM(osrmServer.URL).Nearest(context.Background(), types.LatLng{Lat: 5, Lng: 6})
+		assert.EqualError(t, err, "could not parse response from osrm: EOF")
+	})
+}
+
+func TestOSRM_Route_Canceled(t *testing.T) {
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer osrmServer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+	_, err := NewOSRM(osrmServer.URL).Route(ctx, []types.LatLng{{Lat: 5, Lng: 6}, {Lat: 7, Lng: 8}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "context canceled")
+}
@@ -0,0 +1,56 @@
+package routing
+
+import (
+	"context"
+	"sync"
+)
+
+// MaxConcurrentQueries is the number of requests that are sent to the routing server at the same time by bulk queries.
+const MaxConcurrentQueries = 4
+
+// ForEach calls the function for every index from 0 to count-1 with at most MaxConcurrentQueries calls at the
+// same time. The first error cancels the context passed to the remaining calls, no further calls are started
+// then. ForEach waits for all running calls and returns the first error, or the error of the context if it
+// was canceled before all calls were started.
+func ForEach(ctx context.Context, count int, call func(ctx context.Context, index int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	indices := make(chan int)
+	var firstErr error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	var wg sync.WaitGroup
+	workers := MaxConcurrentQueries
+	if count < workers {
+		workers = count
+	}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				err := call(ctx, index)
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+feed:
+	for index := 0; index < count; index++ {
+		select {
+		case indices <- index:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+	return firstErr
+}
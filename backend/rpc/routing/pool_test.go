@@ -0,0 +1,58 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	t.Run("all calls with bounded concurrency", func(t *testing.T) {
+		var running, maxRunning int32
+		var mutex sync.Mutex
+		called := make(map[int]bool)
+		err := ForEach(context.Background(), 20, func(ctx context.Context, index int) error {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if current > maxRunning {
+				maxRunning = current
+			}
+			called[index] = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 20, len(called))
+		assert.LessOrEqual(t, maxRunning, int32(MaxConcurrentQueries))
+	})
+	t.Run("first error cancels the remaining calls", func(t *testing.T) {
+		var calls int32
+		err := ForEach(context.Background(), 100, func(ctx context.Context, index int) error {
+			atomic.AddInt32(&calls, 1)
+			if index == 0 {
+				return fmt.Errorf("failed")
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		assert.EqualError(t, err, "failed")
+		assert.Less(t, atomic.LoadInt32(&calls), int32(100))
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := ForEach(ctx, 100, func(ctx context.Context, index int) error {
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("nothing to do", func(t *testing.T) {
+		assert.NoError(t, ForEach(context.Background(), 0, func(ctx context.Context, index int) error {
+			return fmt.Errorf("must not be called")
+		}))
+	})
+}
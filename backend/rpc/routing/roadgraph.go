@@ -0,0 +1,369 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// roadSpeeds are the speeds in km/h of the highway types that can be used by road vehicles, unless a way
+// has a max speed.
+var roadSpeeds = map[string]float64{
+	"motorway":       100,
+	"motorway_link":  60,
+	"trunk":          80,
+	"trunk_link":     50,
+	"primary":        60,
+	"primary_link":   40,
+	"secondary":      50,
+	"secondary_link": 40,
+	"tertiary":       40,
+	"tertiary_link":  30,
+	"unclassified":   30,
+	"residential":    30,
+	"living_street":  10,
+	"service":        15,
+	"busway":         40,
+}
+
+// roadGridCellSize is the size in degrees of the cells of the grid that finds the node nearest to a coordinate.
+const roadGridCellSize = 0.01
+
+type roadEdge struct {
+	to       int
+	distance float64
+	duration float64
+}
+
+// RoadGraph is an embedded router on the road network of an OSM extract. Routes are the fastest paths in the
+// graph, coordinates are snapped to the nearest node of a road.
+type RoadGraph struct {
+	// points contains the latitude and longitude of every node.
+	points [][2]float64
+	edges  [][]roadEdge
+	// names contains the name of a road through every node.
+	names []string
+	// grid contains the nodes in each cell, the cells are given by the latitude and longitude divided by the
+	// cell size and are bounded by the smallest and the biggest cell that contains nodes.
+	grid    map[[2]int][]int
+	minCell [2]int
+	maxCell [2]int
+}
+
+// LoadRoadGraph reads the roads of the OSM extract in the file, which is either in the PBF format if the file
+// name ends with ".pbf" or in the XML format otherwise.
+func LoadRoadGraph(path string) (*RoadGraph, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open road graph \"%s\": %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+	var data *osmData
+	if strings.HasSuffix(path, ".pbf") {
+		data, err = readOsmPbf(file)
+	} else {
+		data, err = readOsmXml(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read road graph \"%s\": %v", path, err)
+	}
+	graph := newRoadGraph(data)
+	if len(graph.points) == 0 {
+		return nil, fmt.Errorf("the road graph \"%s\" does not contain any roads", path)
+	}
+	return graph, nil
+}
+
+func newRoadGraph(data *osmData) *RoadGraph {
+	graph := &RoadGraph{}
+	vertices := make(map[int64]int)
+	vertex := func(node int64) int {
+		index, ok := vertices[node]
+		if !ok {
+			index = len(graph.points)
+			vertices[node] = index
+			graph.points = append(graph.points, data.nodes[node])
+			graph.edges = append(graph.edges, nil)
+			graph.names = append(graph.names, "")
+		}
+		return index
+	}
+	for _, way := range data.ways {
+		speed, ok := roadSpeeds[way.tags["highway"]]
+		if !ok || way.tags["access"] == "no" || way.tags["access"] == "private" {
+			continue
+		}
+		if maxSpeed, ok := parseMaxSpeed(way.tags["maxspeed"]); ok {
+			speed = maxSpeed
+		}
+		forward, backward := wayDirections(way.tags)
+		for index := 1; index < len(way.refs); index++ {
+			from, to := way.refs[index-1], way.refs[index]
+			_, fromOk := data.nodes[from]
+			_, toOk := data.nodes[to]
+			if !fromOk || !toOk {
+				continue
+			}
+			fromVertex, toVertex := vertex(from), vertex(to)
+			for _, current := range []int{fromVertex, toVertex} {
+				if graph.names[current] == "" {
+					graph.names[current] = way.tags["name"]
+				}
+			}
+			distance := scenario.HaversineDistance(graph.points[fromVertex][0], graph.points[fromVertex][1], graph.points[toVertex][0], graph.points[toVertex][1])
+			duration := distance / (speed / 3.6)
+			if forward {
+				graph.edges[fromVertex] = append(graph.edges[fromVertex], roadEdge{to: toVertex, distance: distance, duration: duration})
+			}
+			if backward {
+				graph.edges[toVertex] = append(graph.edges[toVertex], roadEdge{to: fromVertex, distance: distance, duration: duration})
+			}
+		}
+	}
+	graph.buildGrid()
+	return graph
+}
+
+func roadGridCell(lat float64, lng float64) [2]int {
+	return [2]int{int(math.Floor(lat / roadGridCellSize)), int(math.Floor(lng / roadGridCellSize))}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (g *RoadGraph) buildGrid() {
+	g.grid = make(map[[2]int][]int)
+	for index, point := range g.points {
+		cell := roadGridCell(point[0], point[1])
+		if index == 0 {
+			g.minCell, g.maxCell = cell, cell
+		}
+		for axis := range cell {
+			if cell[axis] < g.minCell[axis] {
+				g.minCell[axis] = cell[axis]
+			}
+			if cell[axis] > g.maxCell[axis] {
+				g.maxCell[axis] = cell[axis]
+			}
+		}
+		g.grid[cell] = append(g.grid[cell], index)
+	}
+}
+
+// parseMaxSpeed understands max speeds in km/h and in mph, e.g. "50" or "30 mph".
+func parseMaxSpeed(value string) (float64, bool) {
+	factor := 1.0
+	if strings.HasSuffix(value, "mph") {
+		factor = 1.609344
+		value = strings.TrimSuffix(value, "mph")
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	return speed * factor, true
+}
+
+// wayDirections returns whether a way can be used in the direction of its nodes and in the opposite direction.
+func wayDirections(tags map[string]string) (bool, bool) {
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		return true, false
+	case "-1", "reverse":
+		return false, true
+	case "no", "false", "0":
+		return true, true
+	}
+	if tags["junction"] == "roundabout" || tags["junction"] == "circular" || tags["highway"] == "motorway" {
+		return true, false
+	}
+	return true, true
+}
+
+// nearest returns the node closest to the coordinate. The cells of the grid are searched in growing rings
+// around the cell of the coordinate until no node of a farther ring can be closer than the best node found.
+func (g *RoadGraph) nearest(latLng types.LatLng) int {
+	best, bestDistance := 0, math.Inf(1)
+	// the equirectangular projection is precise enough to compare distances
+	scale := math.Cos(latLng.Lat * math.Pi / 180)
+	center := roadGridCell(latLng.Lat, latLng.Lng)
+	// the rings beyond this one do not contain any cells with nodes
+	lastRing := 0
+	for axis := range center {
+		for _, distance := range []int{center[axis] - g.minCell[axis], g.maxCell[axis] - center[axis]} {
+			if distance > lastRing {
+				lastRing = distance
+			}
+		}
+	}
+	for ring := 0; ring <= lastRing; ring++ {
+		// a node in this ring differs by at least ring-1 cells in latitude or longitude from the coordinate
+		if gap := float64(ring-1) * roadGridCellSize * math.Min(scale, 1); ring > 0 && gap*gap > bestDistance {
+			break
+		}
+		for lat := maxInt(center[0]-ring, g.minCell[0]); lat <= minInt(center[0]+ring, g.maxCell[0]); lat++ {
+			// only the first and the last row of the ring are complete, the other rows have a cell at each end
+			lngs := []int{center[1] - ring, center[1] + ring}
+			if lat == center[0]-ring || lat == center[0]+ring {
+				lngs = lngs[:0]
+				for lng := maxInt(center[1]-ring, g.minCell[1]); lng <= minInt(center[1]+ring, g.maxCell[1]); lng++ {
+					lngs = append(lngs, lng)
+				}
+			}
+			for _, lng := range lngs {
+				for _, index := range g.grid[[2]int{lat, lng}] {
+					point := g.points[index]
+					lat, lng := point[0]-latLng.Lat, (point[1]-latLng.Lng)*scale
+					if distance := lat*lat + lng*lng; distance < bestDistance || distance == bestDistance && index < best {
+						best, bestDistance = index, distance
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+type roadQueueItem struct {
+	vertex   int
+	duration float64
+}
+
+type roadQueue []roadQueueItem
+
+func (q roadQueue) Len() int           { return len(q) }
+func (q roadQueue) Less(i, j int) bool { return q[i].duration < q[j].duration }
+func (q roadQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *roadQueue) Push(item any)     { *q = append(*q, item.(roadQueueItem)) }
+func (q *roadQueue) Pop() (item any)   { item, *q = (*q)[len(*q)-1], (*q)[:len(*q)-1]; return item }
+
+// fastestPaths computes the fastest paths from the source with Dijkstra's algorithm. It stops as soon as the
+// target is reached, or explores the whole graph if the target is negative. The durations of unreachable
+// nodes are infinite, previous contains the node before each node on its fastest path or -1.
+func (g *RoadGraph) fastestPaths(ctx context.Context, source int, target int) (durations []float64, distances []float64, previous []int, err error) {
+	durations = make([]float64, len(g.points))
+	distances = make([]float64, len(g.points))
+	previous = make([]int, len(g.points))
+	for index := range durations {
+		durations[index] = math.Inf(1)
+		previous[index] = -1
+	}
+	durations[source] = 0
+	queue := &roadQueue{{vertex: source}}
+	for iteration := 0; queue.Len() > 0; iteration++ {
+		if iteration%10000 == 0 && ctx.Err() != nil {
+			return nil, nil, nil, ctx.Err()
+		}
+		current := heap.Pop(queue).(roadQueueItem)
+		if current.duration > durations[current.vertex] {
+			continue
+		}
+		if current.vertex == target {
+			break
+		}
+		for _, edge := range g.edges[current.vertex] {
+			duration := current.duration + edge.duration
+			if duration < durations[edge.to] {
+				durations[edge.to] = duration
+				distances[edge.to] = distances[current.vertex] + edge.distance
+				previous[edge.to] = current.vertex
+				heap.Push(queue, roadQueueItem{vertex: edge.to, duration: duration})
+			}
+		}
+	}
+	return durations, distances, previous, nil
+}
+
+// Route returns the fastest path through the nodes nearest to the coordinates, or an empty path if a node
+// cannot be reached.
+func (g *RoadGraph) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	if len(coordinates) < 2 {
+		return []types.Waypoint{}, nil
+	}
+	vertices := make([]int, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		vertices = append(vertices, g.nearest(coordinate))
+	}
+	route := []int{vertices[0]}
+	stops := []int{0}
+	for index := 1; index < len(vertices); index++ {
+		_, _, previous, err := g.fastestPaths(ctx, vertices[index-1], vertices[index])
+		if err != nil {
+			return nil, err
+		}
+		leg := make([]int, 0)
+		for current := vertices[index]; current != vertices[index-1]; current = previous[current] {
+			if current < 0 {
+				return []types.Waypoint{}, nil
+			}
+			leg = append([]int{current}, leg...)
+		}
+		// coordinates at the same node need a stop of their own
+		if len(leg) == 0 {
+			leg = append(leg, vertices[index])
+		}
+		route = append(route, leg...)
+		stops = append(stops, len(route)-1)
+	}
+	points := make([][]float64, 0, len(route))
+	distances := make([]float64, len(route)-1)
+	durations := make([]float64, len(route)-1)
+	for index, vertex := range route {
+		points = append(points, []float64{g.points[vertex][0], g.points[vertex][1]})
+		if index == 0 {
+			continue
+		}
+		for _, edge := range g.edges[route[index-1]] {
+			if edge.to == vertex && (durations[index-1] == 0 || edge.duration < durations[index-1]) {
+				distances[index-1], durations[index-1] = edge.distance, edge.duration
+			}
+		}
+	}
+	return path(points, distances, durations, stops), nil
+}
+
+// Nearest returns the name of a road through the node nearest to the coordinate.
+func (g *RoadGraph) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	return g.names[g.nearest(latLng)], nil
+}
+
+// Table computes the fastest paths from every coordinate to all others.
+func (g *RoadGraph) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	vertices := make([]int, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		vertices = append(vertices, g.nearest(coordinate))
+	}
+	result := newTable(len(coordinates))
+	for source, vertex := range vertices {
+		durations, distances, _, err := g.fastestPaths(ctx, vertex, -1)
+		if err != nil {
+			return Table{}, err
+		}
+		for target, targetVertex := range vertices {
+			if math.IsInf(durations[targetVertex], 1) {
+				continue
+			}
+			distance, duration := distances[targetVertex], durations[targetVertex]
+			result.Distances[source][target] = &distance
+			result.Durations[source][target] = &duration
+		}
+	}
+	return result, nil
+}
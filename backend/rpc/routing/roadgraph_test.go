@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRoadGraph(t *testing.T) {
+	directory := t.TempDir()
+	t.Run("xml", func(t *testing.T) {
+		path := filepath.Join(directory, "extract.osm")
+		require.NoError(t, os.WriteFile(path, []byte(testOsmXml), 0644))
+		graph, err := LoadRoadGraph(path)
+		require.NoError(t, err)
+		assert.Equal(t, 4, len(graph.points))
+	})
+	t.Run("pbf", func(t *testing.T) {
+		path := filepath.Join(directory, "extract.osm.pbf")
+		require.NoError(t, os.WriteFile(path, testOsmPbf(t, "OsmSchema-V0.6", "DenseNodes"), 0644))
+		graph, err := LoadRoadGraph(path)
+		require.NoError(t, err)
+		assert.Equal(t, 3, len(graph.points))
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadRoadGraph(filepath.Join(directory, "missing.osm"))
+		assert.Error(t, err)
+	})
+	t.Run("no roads", func(t *testing.T) {
+		path := filepath.Join(directory, "empty.osm")
+		require.NoError(t, os.WriteFile(path, []byte(`<osm><node id="1" lat="49" lon="9.9"/></osm>`), 0644))
+		_, err := LoadRoadGraph(path)
+		assert.EqualError(t, err, "the road graph \""+path+"\" does not contain any roads")
+	})
+}
+
+func TestRoadGraph(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "extract.osm")
+	require.NoError(t, os.WriteFile(path, []byte(testOsmXml), 0644))
+	graph, err := LoadRoadGraph(path)
+	require.NoError(t, err)
+	// the coordinates are close to the nodes 1 and 3
+	first, third := types.LatLng{Lat: 49.0001, Lng: 9.8999}, types.LatLng{Lat: 49.0101, Lng: 9.9101}
+	mainStreet := scenario.HaversineDistance(49.000, 9.900, 49.000, 9.910)
+	ringRoad := scenario.HaversineDistance(49.000, 9.910, 49.010, 9.910)
+
+	t.Run("route along the fastest roads", func(t *testing.T) {
+		path, err := graph.Route(context.Background(), []types.LatLng{first, third})
+		require.NoError(t, err)
+		require.Equal(t, 3, len(path))
+		assert.Equal(t, types.Waypoint{Lat: 49.000, Lng: 9.910}, types.Waypoint{Lat: path[1].Lat, Lng: path[1].Lng})
+		assert.True(t, path[0].Stop)
+		assert.False(t, path[1].Stop)
+		assert.True(t, path[2].Stop)
+		assert.InDelta(t, mainStreet, *path[0].Dist, 0.001)
+		assert.InDelta(t, mainStreet/(30/3.6), *path[0].Dur, 0.001)
+		assert.InDelta(t, ringRoad/(50/3.6), *path[1].Dur, 0.001)
+		assert.Nil(t, path[2].Dist)
+	})
+	t.Run("route against a oneway road", func(t *testing.T) {
+		path, err := graph.Route(context.Background(), []types.LatLng{third, first})
+		require.NoError(t, err)
+		require.Equal(t, 3, len(path))
+		assert.Equal(t, types.Waypoint{Lat: 49.010, Lng: 9.900}, types.Waypoint{Lat: path[1].Lat, Lng: path[1].Lng})
+	})
+	t.Run("route through the same node", func(t *testing.T) {
+		path, err := graph.Route(context.Background(), []types.LatLng{first, first, third})
+		require.NoError(t, err)
+		require.Equal(t, 4, len(path))
+		assert.True(t, path[1].Stop)
+		assert.Equal(t, 0.0, *path[0].Dist)
+	})
+	t.Run("route without enough coordinates", func(t *testing.T) {
+		path, err := graph.Route(context.Background(), []types.LatLng{first})
+		require.NoError(t, err)
+		assert.Empty(t, path)
+	})
+	t.Run("table", func(t *testing.T) {
+		table, err := graph.Table(context.Background(), []types.LatLng{first, third})
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, *table.Distances[0][0])
+		assert.InDelta(t, mainStreet+ringRoad, *table.Distances[0][1], 0.001)
+		assert.InDelta(t, mainStreet/(30/3.6)+ringRoad/(50/3.6), *table.Durations[0][1], 0.001)
+		// the back road is slightly shorter because it runs further north
+		assert.InDelta(t, (mainStreet+ringRoad)/(30/3.6), *table.Durations[1][0], 1)
+	})
+	t.Run("nearest", func(t *testing.T) {
+		name, err := graph.Nearest(context.Background(), first)
+		require.NoError(t, err)
+		assert.Equal(t, "Main Street", name)
+		name, err = graph.Nearest(context.Background(), types.LatLng{Lat: 49.0099, Lng: 9.9})
+		require.NoError(t, err)
+		assert.Equal(t, "Back Road", name)
+	})
+}
+
+func TestRoadGraph_unreachable(t *testing.T) {
+	graph := newRoadGraph(&osmData{
+		nodes: map[int64][2]float64{1: {49.000, 9.900}, 2: {49.000, 9.910}},
+		ways:  []osmWay{{refs: []int64{1, 2}, tags: map[string]string{"highway": "primary", "oneway": "-1"}}},
+	})
+	coordinates := []types.LatLng{{Lat: 49.000, Lng: 9.900}, {Lat: 49.000, Lng: 9.910}}
+	path, err := graph.Route(context.Background(), coordinates)
+	require.NoError(t, err)
+	assert.Empty(t, path)
+	table, err := graph.Table(context.Background(), coordinates)
+	require.NoError(t, err)
+	assert.Nil(t, table.Distances[0][1])
+	assert.NotNil(t, table.Distances[1][0])
+}
+
+func TestRoadGraph_nearest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	graph := &RoadGraph{}
+	for index := 0; index < 2000; index++ {
+		graph.points = append(graph.points, [2]float64{49 + random.Float64()*0.5, 9.5 + random.Float64()})
+	}
+	graph.buildGrid()
+	bruteForce := func(latLng types.LatLng) int {
+		best, bestDistance := 0, math.Inf(1)
+		scale := math.Cos(latLng.Lat * math.Pi / 180)
+		for index, point := range graph.points {
+			lat, lng := point[0]-latLng.Lat, (point[1]-latLng.Lng)*scale
+			if distance := lat*lat + lng*lng; distance < bestDistance {
+				best, bestDistance = index, distance
+			}
+		}
+		return best
+	}
+	for index := 0; index < 500; index++ {
+		// some coordinates are outside of the extract
+		latLng := types.LatLng{Lat: 48.5 + random.Float64()*1.5, Lng: 9 + random.Float64()*2}
+		assert.Equal(t, bruteForce(latLng), graph.nearest(latLng), "%v", latLng)
+	}
+	far := types.LatLng{Lat: -30, Lng: 120}
+	assert.Equal(t, bruteForce(far), graph.nearest(far))
+}
+
+func TestParseMaxSpeed(t *testing.T) {
+	for value, expected := range map[string]float64{"50": 50, "30 mph": 48.28032, "20mph": 32.18688} {
+		speed, ok := parseMaxSpeed(value)
+		assert.True(t, ok, value)
+		assert.InDelta(t, expected, speed, 0.0001, value)
+	}
+	for _, value := range []string{"", "none", "signals", "0"} {
+		_, ok := parseMaxSpeed(value)
+		assert.False(t, ok, value)
+	}
+}
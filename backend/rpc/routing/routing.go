@@ -0,0 +1,238 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"backend/scenario"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	EngineOSRM        = "osrm"
+	EngineGraphHopper = "graphhopper"
+	EngineValhalla    = "valhalla"
+	EngineHaversine   = "haversine"
+)
+
+// Engines contains the names of all routing engines that can be passed to New.
+var Engines = []string{EngineOSRM, EngineGraphHopper, EngineValhalla, EngineHaversine}
+
+// DefaultMaxTableSize is the default limit of coordinates per table request of the routing server.
+const DefaultMaxTableSize = 100
+
+// Router computes routes on the road network.
+type Router interface {
+	// Route returns the path of the fastest route through the coordinates in the given order. The waypoints of
+	// the path at the coordinates are marked as stops, the distance and duration of each waypoint lead to the
+	// next waypoint. Returns an empty path if there is no route.
+	Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error)
+	// Nearest returns the name of the street nearest to the coordinate, or an empty name if it is unknown.
+	Nearest(ctx context.Context, latLng types.LatLng) (string, error)
+	// Table returns the distances and durations of the fastest routes between all coordinates.
+	Table(ctx context.Context, coordinates []types.LatLng) (Table, error)
+}
+
+// Table contains the distances in meters and the durations in seconds of the fastest routes between all
+// coordinates. Distances[i][j] is the distance from coordinate i to coordinate j. Entries are nil if there is no route.
+type Table struct {
+	Distances [][]*float64
+	Durations [][]*float64
+}
+
+func newTable(size int) Table {
+	result := Table{
+		Distances: make([][]*float64, size),
+		Durations: make([][]*float64, size),
+	}
+	for index := 0; index < size; index++ {
+		result.Distances[index] = make([]*float64, size)
+		result.Durations[index] = make([]*float64, size)
+	}
+	return result
+}
+
+// New returns the router of the engine with the given name. The URL is the base endpoint of the server, it is
+// ignored by the haversine router.
+func New(engine string, url string) (Router, error) {
+	return NewProfile(engine, url, "")
+}
+
+// NewProfile returns the router of the engine with the given name that uses the profile of the server, i.e. the
+// OSRM profile, the GraphHopper profile or the Valhalla costing. An empty profile selects the default profile of
+// the engine. The haversine router ignores the URL and the profile.
+func NewProfile(engine string, url string, profile string) (Router, error) {
+	switch engine {
+	case EngineOSRM:
+		router := NewOSRM(url)
+		if profile != "" {
+			router.Profile = profile
+		}
+		return router, nil
+	case EngineGraphHopper:
+		router := NewGraphHopper(url)
+		if profile != "" {
+			router.Profile = profile
+		}
+		return router, nil
+	case EngineValhalla:
+		router := NewValhalla(url)
+		if profile != "" {
+			router.Costing = profile
+		}
+		return router, nil
+	case EngineHaversine:
+		return NewHaversine(), nil
+	}
+	return nil, fmt.Errorf("the routing engine \"%s\" is unknown, use one of %v", engine, Engines)
+}
+
+// Modes routes the lines of some transport modes with routers of their own, e.g. with a different profile or
+// server, and the lines of all other modes with the default router.
+type Modes struct {
+	Router
+	Routers map[string]Router
+}
+
+// ForMode returns the router for lines of the transport mode.
+func (m *Modes) ForMode(mode string) Router {
+	if router, ok := m.Routers[mode]; ok {
+		return router
+	}
+	return m.Router
+}
+
+// HasModeRouter reports whether the router distinguishes transport modes and has a router of its own for the mode.
+func HasModeRouter(router Router, mode string) bool {
+	modes, ok := router.(*Modes)
+	if !ok {
+		return false
+	}
+	_, ok = modes.Routers[mode]
+	return ok
+}
+
+// ForMode returns the router for lines of the transport mode if the router distinguishes transport modes,
+// otherwise the router itself.
+func ForMode(router Router, mode string) Router {
+	if modes, ok := router.(*Modes); ok {
+		return modes.ForMode(mode)
+	}
+	return router
+}
+
+var netClient = &http.Client{
+	Timeout: time.Second * 10,
+}
+
+// get sends a GET request to the routing server that is aborted when the context is canceled.
+func get(ctx context.Context, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return netClient.Do(request)
+}
+
+// postJson sends the body as JSON to the routing server and decodes the JSON response into the result.
+func postJson(ctx context.Context, url string, body any, result any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := netClient.Do(request)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(response, result)
+}
+
+// getJson sends a GET request to the routing server and decodes the JSON response into the result.
+func getJson(ctx context.Context, url string, result any) error {
+	response, err := get(ctx, url)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(response, result)
+}
+
+// decodeResponse decodes the JSON body of a successful response into the result and closes the body. For
+// other responses, it fails with the message that the server reports.
+func decodeResponse(response *http.Response, result any) error {
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		var failure struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&failure)
+		message := failure.Message
+		if message == "" {
+			message = failure.Error
+		}
+		return fmt.Errorf("the server responded with status %d: %s", response.StatusCode, message)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// spread distributes the value among the segments between the points from and to in proportion to their
+// length and adds it to the values of the segments. Segment i leads from point i to point i+1.
+func spread(points [][]float64, from int, to int, value float64, segments []float64) {
+	total := 0.0
+	for index := from; index < to; index++ {
+		total = total + scenario.HaversineDistance(points[index][0], points[index][1], points[index+1][0], points[index+1][1])
+	}
+	for index := from; index < to; index++ {
+		if total == 0 {
+			segments[index] = segments[index] + value/float64(to-from)
+			continue
+		}
+		length := scenario.HaversineDistance(points[index][0], points[index][1], points[index+1][0], points[index+1][1])
+		segments[index] = segments[index] + value*length/total
+	}
+}
+
+// nearestPoints returns for each coordinate the index of the nearest point that does not come before the
+// point of the previous coordinate.
+func nearestPoints(points [][]float64, coordinates [][]float64) []int {
+	result := make([]int, 0, len(coordinates))
+	start := 0
+	for _, coordinate := range coordinates {
+		best := start
+		for index := start; index < len(points); index++ {
+			if scenario.HaversineDistance(coordinate[0], coordinate[1], points[index][0], points[index][1]) <
+				scenario.HaversineDistance(coordinate[0], coordinate[1], points[best][0], points[best][1]) {
+				best = index
+			}
+		}
+		result = append(result, best)
+		start = best
+	}
+	return result
+}
+
+// path creates the waypoints of the points. The distances and durations are the values of the segments from
+// each point to the next one, stops contains the indices of the points that are stops.
+func path(points [][]float64, distances []float64, durations []float64, stops []int) []types.Waypoint {
+	result := make([]types.Waypoint, 0, len(points))
+	for index, point := range points {
+		waypoint := types.Waypoint{Lat: point[0], Lng: point[1]}
+		if index+1 < len(points) {
+			distance, duration := distances[index], durations[index]
+			waypoint.Dist, waypoint.Dur = &distance, &duration
+		}
+		result = append(result, waypoint)
+	}
+	for _, stop := range stops {
+		result[stop].Stop = true
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package routing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, engine := range Engines {
+		router, err := New(engine, "http://localhost:5000")
+		require.NoError(t, err)
+		assert.NotNil(t, router)
+	}
+	router, err := New(EngineGraphHopper, "http://localhost:8989")
+	require.NoError(t, err)
+	assert.Equal(t, &GraphHopper{URL: "http://localhost:8989", Profile: "car"}, router)
+	_, err = New("google", "")
+	assert.EqualError(t, err, "the routing engine \"google\" is unknown, use one of [osrm graphhopper valhalla haversine]")
+}
+
+func TestNewProfile(t *testing.T) {
+	router, err := NewProfile(EngineOSRM, "http://localhost:5000", "")
+	require.NoError(t, err)
+	assert.Equal(t, NewOSRM("http://localhost:5000"), router)
+	router, err = NewProfile(EngineOSRM, "http://localhost:5000", "bus")
+	require.NoError(t, err)
+	assert.Equal(t, &OSRM{URL: "http://localhost:5000", MaxTableSize: DefaultMaxTableSize, Profile: "bus"}, router)
+	router, err = NewProfile(EngineGraphHopper, "http://localhost:8989", "bike")
+	require.NoError(t, err)
+	assert.Equal(t, &GraphHopper{URL: "http://localhost:8989", Profile: "bike"}, router)
+	router, err = NewProfile(EngineValhalla, "http://localhost:8002", "bus")
+	require.NoError(t, err)
+	assert.Equal(t, &Valhalla{URL: "http://localhost:8002", Costing: "bus"}, router)
+	_, err = NewProfile("google", "", "car")
+	assert.Error(t, err)
+}
+
+func TestModes(t *testing.T) {
+	cache, err := OpenCache(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	bus := NewCachedRouter(NewOSRM("http://localhost:5000"), cache, "osrm")
+	tram := NewHaversine()
+	modes := &Modes{Router: bus, Routers: map[string]Router{"tram": tram}}
+	assert.Equal(t, tram, ForMode(modes, "tram"))
+	assert.Equal(t, bus, ForMode(modes, "bus"))
+	assert.Equal(t, tram, ForMode(tram, "bus"))
+	assert.True(t, HasModeRouter(modes, "tram"))
+	assert.False(t, HasModeRouter(modes, "bus"))
+	assert.False(t, HasModeRouter(tram, "tram"))
+	assert.Equal(t, cache, CacheOf(modes))
+	assert.Nil(t, CacheOf(tram))
+}
+
+func TestSpread(t *testing.T) {
+	points := [][]float64{{49.0, 9.0}, {49.001, 9.0}, {49.003, 9.0}, {49.003, 9.0}}
+	segments := make([]float64, 3)
+	spread(points, 0, 2, 30, segments)
+	assert.InDelta(t, 10, segments[0], 0.001)
+	assert.InDelta(t, 20, segments[1], 0.001)
+	spread(points, 2, 3, 4, segments)
+	assert.Equal(t, 4.0, segments[2])
+}
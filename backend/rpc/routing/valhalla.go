@@ -0,0 +1,176 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"fmt"
+	polyline2 "github.com/twpayne/go-polyline"
+)
+
+// Valhalla queries the HTTP API of a Valhalla server.
+type Valhalla struct {
+	URL     string
+	Costing string
+}
+
+// NewValhalla returns a router for the Valhalla server with the given base URL and the "auto" costing.
+func NewValhalla(url string) *Valhalla {
+	return &Valhalla{URL: url, Costing: "auto"}
+}
+
+// valhallaShape decodes the shapes of Valhalla, which are polylines with a precision of six digits.
+var valhallaShape = polyline2.Codec{Dim: 2, Scale: 1e6}
+
+type valhallaLocation struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func valhallaLocations(coordinates []types.LatLng) []valhallaLocation {
+	result := make([]valhallaLocation, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		result = append(result, valhallaLocation{Lat: coordinate.Lat, Lon: coordinate.Lng})
+	}
+	return result
+}
+
+type valhallaRouteRequest struct {
+	Locations         []valhallaLocation `json:"locations"`
+	Costing           string             `json:"costing"`
+	DirectionsType    string             `json:"directions_type"`
+	DirectionsOptions struct {
+		Units string `json:"units"`
+	} `json:"directions_options"`
+}
+
+type valhallaRouteResponse struct {
+	Trip struct {
+		Legs []struct {
+			Shape   string `json:"shape"`
+			Summary struct {
+				// Length is given in kilometers, time in seconds.
+				Length float64 `json:"length"`
+				Time   float64 `json:"time"`
+			} `json:"summary"`
+		} `json:"legs"`
+	} `json:"trip"`
+}
+
+// Route queries the fastest route through the coordinates. Valhalla only reports the length and the time of
+// each leg, they are distributed among the waypoints of the leg in proportion to their distance.
+func (v *Valhalla) Route(ctx context.Context, coordinates []types.LatLng) ([]types.Waypoint, error) {
+	request := valhallaRouteRequest{Locations: valhallaLocations(coordinates), Costing: v.Costing, DirectionsType: "none"}
+	request.DirectionsOptions.Units = "kilometers"
+	var response valhallaRouteResponse
+	err := postJson(ctx, v.URL+"/route", request, &response)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Valhalla route: %v", err)
+	}
+	points := make([][]float64, 0)
+	stops := make([]int, 0, len(response.Trip.Legs)+1)
+	legs := make([][2]int, 0, len(response.Trip.Legs))
+	for _, leg := range response.Trip.Legs {
+		shape, _, err := valhallaShape.DecodeCoords([]byte(leg.Shape))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse shape \"%s\" from valhalla: %v", leg.Shape, err)
+		}
+		// consecutive legs share their first and last point
+		if len(points) > 0 && len(shape) > 0 {
+			shape = shape[1:]
+		}
+		start := len(points)
+		if start > 0 {
+			start = start - 1
+		}
+		stops = append(stops, start)
+		points = append(points, shape...)
+		legs = append(legs, [2]int{start, len(points) - 1})
+	}
+	if len(points) < 2 {
+		return []types.Waypoint{}, nil
+	}
+	stops = append(stops, len(points)-1)
+	distances := make([]float64, len(points)-1)
+	durations := make([]float64, len(points)-1)
+	for index, leg := range response.Trip.Legs {
+		spread(points, legs[index][0], legs[index][1], leg.Summary.Length*1000, distances)
+		spread(points, legs[index][0], legs[index][1], leg.Summary.Time, durations)
+	}
+	return path(points, distances, durations, stops), nil
+}
+
+type valhallaLocateRequest struct {
+	Locations []valhallaLocation `json:"locations"`
+	Costing   string             `json:"costing"`
+	Verbose   bool               `json:"verbose"`
+}
+
+type valhallaLocateResponse []struct {
+	Edges []struct {
+		EdgeInfo struct {
+			Names []string `json:"names"`
+		} `json:"edge_info"`
+	} `json:"edges"`
+}
+
+// Nearest returns the first name of the edges the locate service finds for the coordinate.
+func (v *Valhalla) Nearest(ctx context.Context, latLng types.LatLng) (string, error) {
+	request := valhallaLocateRequest{Locations: valhallaLocations([]types.LatLng{latLng}), Costing: v.Costing, Verbose: true}
+	var response valhallaLocateResponse
+	err := postJson(ctx, v.URL+"/locate", request, &response)
+	if err != nil {
+		return "", fmt.Errorf("could not query Valhalla location: %v", err)
+	}
+	for _, location := range response {
+		for _, edge := range location.Edges {
+			if len(edge.EdgeInfo.Names) > 0 {
+				return edge.EdgeInfo.Names[0], nil
+			}
+		}
+	}
+	return "", nil
+}
+
+type valhallaMatrixRequest struct {
+	Sources []valhallaLocation `json:"sources"`
+	Targets []valhallaLocation `json:"targets"`
+	Costing string             `json:"costing"`
+	Units   string             `json:"units"`
+}
+
+type valhallaMatrixResponse struct {
+	SourcesToTargets [][]struct {
+		// Distance is given in kilometers, time in seconds. Both are null if there is no route.
+		Distance *float64 `json:"distance"`
+		Time     *float64 `json:"time"`
+	} `json:"sources_to_targets"`
+}
+
+// Table queries the distances and durations between all coordinates from the matrix service of Valhalla.
+func (v *Valhalla) Table(ctx context.Context, coordinates []types.LatLng) (Table, error) {
+	locations := valhallaLocations(coordinates)
+	request := valhallaMatrixRequest{Sources: locations, Targets: locations, Costing: v.Costing, Units: "kilometers"}
+	var response valhallaMatrixResponse
+	err := postJson(ctx, v.URL+"/sources_to_targets", request, &response)
+	if err != nil {
+		return Table{}, fmt.Errorf("could not query Valhalla matrix: %v", err)
+	}
+	if len(response.SourcesToTargets) != len(coordinates) {
+		return Table{}, fmt.Errorf("valhalla returned a matrix with %d rows instead of %d", len(response.SourcesToTargets), len(coordinates))
+	}
+	result := newTable(len(coordinates))
+	for source, row := range response.SourcesToTargets {
+		if len(row) != len(coordinates) {
+			return Table{}, fmt.Errorf("valhalla returned a matrix with %d columns instead of %d", len(row), len(coordinates))
+		}
+		for target, entry := range row {
+			if entry.Distance == nil || entry.Time == nil {
+				continue
+			}
+			distance := *entry.Distance * 1000
+			result.Distances[source][target] = &distance
+			result.Durations[source][target] = entry.Time
+		}
+	}
+	return result, nil
+}
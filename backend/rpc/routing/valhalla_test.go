@@ -0,0 +1,62 @@
+package routing
+
+import (
+	"backend/rpc/types"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValhalla(t *testing.T) {
+	shape := func(points [][]float64) string {
+		return string(valhallaShape.EncodeCoords(nil, points))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/route":
+			var request valhallaRouteRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			assert.Equal(t, "auto", request.Costing)
+			assert.Equal(t, 3, len(request.Locations))
+			_ = json.NewEncoder(w).Encode(map[string]any{"trip": map[string]any{"legs": []any{
+				map[string]any{"shape": shape([][]float64{{49.0, 9.0}, {49.001, 9.0}, {49.003, 9.0}}), "summary": map[string]any{"length": 0.3, "time": 30}},
+				map[string]any{"shape": shape([][]float64{{49.003, 9.0}, {49.004, 9.0}}), "summary": map[string]any{"length": 0.1, "time": 12}},
+			}}})
+		case "/locate":
+			_, _ = w.Write([]byte(`[{"edges":[{"edge_info":{"names":[]}},{"edge_info":{"names":["Kaiserstraße"]}}]}]`))
+		case "/sources_to_targets":
+			var request valhallaMatrixRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			assert.Equal(t, request.Sources, request.Targets)
+			_, _ = w.Write([]byte(`{"sources_to_targets":[[{"distance":0,"time":0},{"distance":0.4,"time":40}],[{"distance":null,"time":null},{"distance":0,"time":0}]]}`))
+		}
+	}))
+	defer server.Close()
+	router := NewValhalla(server.URL)
+	t.Run("route", func(t *testing.T) {
+		path, err := router.Route(context.Background(), []types.LatLng{{Lat: 49.0, Lng: 9.0}, {Lat: 49.003, Lng: 9.0}, {Lat: 49.004, Lng: 9.0}})
+		require.NoError(t, err)
+		require.Equal(t, 4, len(path))
+		assert.Equal(t, []bool{true, false, true, true}, []bool{path[0].Stop, path[1].Stop, path[2].Stop, path[3].Stop})
+		assert.InDelta(t, 100, *path[0].Dist, 0.01)
+		assert.InDelta(t, 200, *path[1].Dist, 0.01)
+		assert.InDelta(t, 20, *path[1].Dur, 0.01)
+		assert.InDelta(t, 12, *path[2].Dur, 0.01)
+	})
+	t.Run("nearest", func(t *testing.T) {
+		name, err := router.Nearest(context.Background(), types.LatLng{Lat: 49.0, Lng: 9.0})
+		require.NoError(t, err)
+		assert.Equal(t, "Kaiserstraße", name)
+	})
+	t.Run("table", func(t *testing.T) {
+		table, err := router.Table(context.Background(), []types.LatLng{{Lat: 49.0, Lng: 9.0}, {Lat: 49.004, Lng: 9.0}})
+		require.NoError(t, err)
+		assert.InDelta(t, 400, *table.Distances[0][1], 0.001)
+		assert.Equal(t, 40.0, *table.Durations[0][1])
+		assert.Nil(t, table.Distances[1][0])
+	})
+}
@@ -1,8 +1,11 @@
 package rpc
 
 import (
+	"backend/rpc/routing"
 	"backend/scenario"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -34,23 +37,51 @@ type Handler interface {
 }
 
 type rpcMethod struct {
-	description    string
-	input          reflect.Type
-	output         reflect.Type
-	method         Method
+	description string
+	input       reflect.Type
+	output      reflect.Type
+	method      Method
+	// contextMethod is used instead of method by methods that query external services. Its context is
+	// canceled when the client disconnects.
+	contextMethod  ContextMethod
 	persistChanged bool
+	// persistRequested is used instead of persistChanged by methods that only change the scenario on request,
+	// e.g. after a preview. It reports whether the parameters request the changes.
+	persistRequested func(message json.RawMessage) bool
 }
 
 type Method func(message json.RawMessage) (json.RawMessage, error)
 
-func HandleFunc(manager *scenario.Manager, osrmUrl string) http.HandlerFunc {
+type ContextMethod func(ctx context.Context, message json.RawMessage) (json.RawMessage, error)
+
+func (m rpcMethod) call(ctx context.Context, message json.RawMessage) (json.RawMessage, error) {
+	if m.contextMethod != nil {
+		return m.contextMethod(ctx, message)
+	}
+	return m.method(message)
+}
+
+// invalidParamsError signals that the method could not be executed because of invalid parameters.
+// It is reported with the JSON-RPC error code -32602.
+type invalidParamsError struct {
+	err error
+}
+
+func (e invalidParamsError) Error() string {
+	return e.err.Error()
+}
+
+func HandleFunc(manager *scenario.Manager, router routing.Router) http.HandlerFunc {
 	handlers := make(map[string]Handler)
-	handlers["osrm"] = newOsrmHandler(manager, osrmUrl)
-	handlers["lines"] = newLineHandler(manager, osrmUrl)
-	handlers["stations"] = newStationHandler(manager, osrmUrl)
+	handlers["osrm"] = newOsrmHandler(manager, router)
+	handlers["lines"] = newLineHandler(manager, router)
+	handlers["stations"] = newStationHandler(manager, router)
 	handlers["docs"] = &docHandler{handlers: handlers}
 	handlers["timetables"] = newTimetableHandler(manager)
 	handlers["vehicles"] = newVehicleHandler(manager)
+	handlers["calendars"] = newCalendarHandler(manager)
+	handlers["demand"] = newDemandHandler(manager)
+	handlers["journeys"] = newJourneyHandler(manager)
 	handlers["properties"] = NewPropertiesHandler(manager)
 	return func(resp http.ResponseWriter, req *http.Request) {
 		resp.Header().Set("Content-Type", "application/json")
@@ -78,12 +109,17 @@ func HandleFunc(manager *scenario.Manager, osrmUrl string) http.HandlerFunc {
 			writeError(resp, -32601, request.Id, "the requested method \"%s\" was not found", request.Method)
 			return
 		}
-		result, err := method.method(request.Params)
+		result, err := method.call(req.Context(), request.Params)
+		var paramsErr invalidParamsError
+		if errors.As(err, &paramsErr) {
+			writeError(resp, -32602, request.Id, "the parameters of method \"%s\" are invalid: %v", request.Method, err)
+			return
+		}
 		if err != nil {
 			writeError(resp, -32603, request.Id, "the method \"%s\" could not be executed properly: %v", request.Method, err)
 			return
 		}
-		if method.persistChanged {
+		if method.persistChanged || method.persistRequested != nil && method.persistRequested(request.Params) {
 			err = manager.Persist()
 			if err != nil {
 				writeError(resp, 1, request.Id, "the method \"%s\" was executed properly, but the changes could not be persisted to file: %v", request.Method, err)
@@ -1,9 +1,11 @@
 package rpc
 
 import (
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,7 +21,7 @@ func TestHandleFunc(t *testing.T) {
 	manager, err := scenario.LoadScenario(filepath.Join("..", "testdata"))
 	require.NoError(t, err)
 
-	handler := HandleFunc(manager, "")
+	handler := HandleFunc(manager, routing.NewOSRM(""))
 
 	t.Run("normal execution", func(t *testing.T) {
 		t.Parallel()
@@ -50,7 +52,7 @@ func TestHandleFunc(t *testing.T) {
 		dir, _ := ioutil.TempDir(os.TempDir(), "*")
 		defer func() { _ = os.RemoveAll(dir) }()
 		manager, err := scenario.LoadScenario(filepath.Join(dir, "empty"))
-		persistHandler := HandleFunc(manager, "")
+		persistHandler := HandleFunc(manager, routing.NewOSRM(""))
 		require.NoError(t, err)
 		id := "id"
 		rpcRequest := Request{
@@ -67,6 +69,30 @@ func TestHandleFunc(t *testing.T) {
 		assert.DirExistsf(t, filepath.Join(dir, "empty", "lines"), "")
 	})
 
+	t.Run("persistence on request", func(t *testing.T) {
+		dir := t.TempDir()
+		manager, err := scenario.LoadScenario(filepath.Join(dir, "empty"))
+		require.NoError(t, err)
+		manager.SaveTimetable(scenario.Timetable{Key: "tt"})
+		persistHandler := HandleFunc(manager, routing.NewOSRM(""))
+		compact := func(apply bool) {
+			id := "id"
+			payload := mustMarshal(Request{
+				Jsonrpc: "2.0",
+				Method:  "compactTours",
+				Params:  mustMarshal(types.CompactionRequest{Key: "tt", Apply: apply}),
+				Id:      &id,
+			})
+			recorder := httptest.NewRecorder()
+			persistHandler.ServeHTTP(recorder, httptest.NewRequest("POST", "http://localhost/timetables", bytes.NewReader(payload)))
+			require.Equal(t, http.StatusOK, recorder.Code)
+		}
+		compact(false)
+		assert.NoDirExists(t, filepath.Join(dir, "empty", "timetables"))
+		compact(true)
+		assert.DirExists(t, filepath.Join(dir, "empty", "timetables"))
+	})
+
 	t.Run("handler not found", func(t *testing.T) {
 		t.Parallel()
 		id := "666"
@@ -135,6 +161,29 @@ func TestHandleFunc(t *testing.T) {
 		assert.Equal(t, -32603, response.Error.Code)
 	})
 
+	t.Run("invalid params", func(t *testing.T) {
+		t.Parallel()
+		id := "999"
+		params := types.Timetable{Tours: []types.Tour{{Events: []types.ArrivalDeparture{{Departure: "4:7x"}}}}}
+		rpcRequest := Request{
+			Jsonrpc: "2.0",
+			Method:  "saveTimetable",
+			Params:  mustMarshal(params),
+			Id:      &id,
+		}
+		payload := mustMarshal(rpcRequest)
+		request := httptest.NewRequest("POST", "http://localhost/timetables", bytes.NewReader(payload))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		var response Response
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, "999", *response.Id)
+		assert.Nil(t, response.Result)
+		assert.Equal(t, "the parameters of method \"saveTimetable\" are invalid: tour 0, departure of event 0: the time \"4:7x\" is not in the format h:mm or h:mm:ss", response.Error.Message)
+		assert.Equal(t, -32602, response.Error.Code)
+	})
+
 	t.Run("unparsable request", func(t *testing.T) {
 		t.Parallel()
 		request := httptest.NewRequest("POST", "http://localhost/lines", bytes.NewReader([]byte("{{{")))
@@ -150,3 +199,27 @@ func TestHandleFunc(t *testing.T) {
 		assert.Equal(t, -32700, response.Error.Code)
 	})
 }
+
+func TestHandleFunc_ClientDisconnected(t *testing.T) {
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer osrmServer.Close()
+	handler := HandleFunc(scenario.Empty(), routing.NewOSRM(osrmServer.URL))
+	id := "id"
+	payload := mustMarshal(Request{
+		Jsonrpc: "2.0",
+		Method:  "queryRoute",
+		Params:  mustMarshal([]types.LatLng{{Lat: 5, Lng: 6}, {Lat: 7, Lng: 8}}),
+		Id:      &id,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest("POST", "http://localhost/osrm", bytes.NewReader(payload)).WithContext(ctx)
+	recorder := httptest.NewRecorder()
+	go cancel()
+	handler.ServeHTTP(recorder, request)
+	var response Response
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	require.NotNil(t, response.Error)
+	assert.Contains(t, response.Error.Message, "context canceled")
+}
@@ -2,24 +2,27 @@ package rpc
 
 import (
 	"backend/rpc/mapper"
-	"backend/rpc/osrmutils"
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
 )
 
+const defaultMaxWaitMinutes = 30
+
 type stationHandler struct {
 	manager *scenario.Manager
-	osrmUrl string
+	router  routing.Router
 }
 
-func newStationHandler(manager *scenario.Manager, osrmUrl string) *stationHandler {
+func newStationHandler(manager *scenario.Manager, router routing.Router) *stationHandler {
 	return &stationHandler{
 		manager: manager,
-		osrmUrl: osrmUrl,
+		router:  router,
 	}
 }
 
@@ -32,11 +35,31 @@ func (s *stationHandler) Methods() map[string]rpcMethod {
 		},
 		"updateStations": {
 			description: "Updates all stations in the list. Stations with empty key will be created. Stations with" +
-				"an existing key will be updated. If the list contains a station with non-existing, non-empty key, an error is returned.",
+				"an existing key will be updated. If the list contains a station with non-existing, non-empty key, an error is returned. " +
+				"The lines serving changed stations are rerouted with the router of their transport mode, " +
+				"manually drawn segments are kept and only moved to the changed stations.",
 			input:          reflect.TypeOf([]types.Station{}),
-			method:         s.UpdateStations,
+			contextMethod:  s.UpdateStations,
 			persistChanged: true,
 		},
+		"getDepartures": {
+			description: "Returns the departures at the given station between from and to (both inclusive, h:mm or h:mm:ss) of all timetables, " +
+				"sorted by time. Times after midnight are given as 24:00 and later. If a date (yyyy-mm-dd) is given, only timetables " +
+				"operated on that date are considered. Trips ending at the station are omitted.",
+			input:  reflect.TypeOf(types.DepartureRequest{}),
+			output: reflect.TypeOf([]types.Departure{}),
+			method: s.getDepartures,
+		},
+		"analyzeTransfers": {
+			description: "Analyzes the transfers between different lines at all stations served by more than one line. Each arrival is " +
+				"connected to the first departure of another line leaving at least the minimal transfer time later and at most the maximal " +
+				"wait (default 30 minutes) later. Departures leaving earlier are reported as missed connections. The score rates the " +
+				"connections of a station from 0 (no connections) to 100 (all connections at minimal transfer time). " +
+				"If a date (yyyy-mm-dd) is given, only timetables operated on that date are considered. Worst stations come first.",
+			input:  reflect.TypeOf(types.TransferRequest{}),
+			output: reflect.TypeOf([]types.StationTransfers{}),
+			method: s.analyzeTransfers,
+		},
 	}
 }
 
@@ -59,7 +82,7 @@ func (s *stationHandler) queryStations(params json.RawMessage) (json.RawMessage,
 	return mustMarshal(result), nil
 }
 
-func (s *stationHandler) UpdateStations(params json.RawMessage) (json.RawMessage, error) {
+func (s *stationHandler) UpdateStations(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
 	var request types.StationUpdate
 	_ = json.Unmarshal(params, &request)
 	for _, deleted := range request.Deleted {
@@ -88,6 +111,8 @@ func (s *stationHandler) UpdateStations(params json.RawMessage) (json.RawMessage
 	for _, deletion := range request.Deleted {
 		s.manager.DeleteStation(deletion)
 	}
+	lines := make([]scenario.Line, 0, len(affectedLines))
+	routes := make([][]types.LatLng, 0, len(affectedLines))
 	for _, lineKey := range affectedLines {
 		line, _ := s.manager.Line(lineKey)
 		stations := line.Stations()
@@ -98,12 +123,71 @@ func (s *stationHandler) UpdateStations(params json.RawMessage) (json.RawMessage
 				Lng: station.Lng,
 			})
 		}
-		waypoints, err := osrmutils.QueryRoute(s.osrmUrl, latlngs)
+		lines = append(lines, line)
+		routes = append(routes, latlngs)
+	}
+	err := routing.ForEach(ctx, len(lines), func(ctx context.Context, index int) error {
+		path, err := routeLine(ctx, s.router, lines[index], routes[index])
 		if err != nil {
-			return nil, fmt.Errorf("could not update the route line \"%s\" → the line's route is deprecated now", line.Key)
+			return fmt.Errorf("could not update the route line \"%s\" → the line's route is deprecated now", lines[index].Key)
 		}
-		line.Path = mapper.ToVoWaypoints(waypoints)
+		line := lines[index]
+		line.Path = path
 		s.manager.SaveLine(line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return nil, nil
 }
+
+func (s *stationHandler) getDepartures(params json.RawMessage) (json.RawMessage, error) {
+	var request types.DepartureRequest
+	_ = json.Unmarshal(params, &request)
+	if _, ok := s.manager.Station(request.StationKey); !ok {
+		return nil, fmt.Errorf("could not find station with key \"%s\"", request.StationKey)
+	}
+	from, err := scenario.ParseTime(request.From)
+	if err != nil {
+		return nil, invalidParamsError{err: fmt.Errorf("from: %v", err)}
+	}
+	to, err := scenario.ParseTime(request.To)
+	if err != nil {
+		return nil, invalidParamsError{err: fmt.Errorf("to: %v", err)}
+	}
+	date, err := scenario.ParseOptionalDate(request.Date)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	departures := s.manager.Departures(request.StationKey, from, to, date)
+	return mustMarshal(mapper.ToDtoDepartures(departures)), nil
+}
+
+func (s *stationHandler) analyzeTransfers(params json.RawMessage) (json.RawMessage, error) {
+	var request types.TransferRequest
+	_ = json.Unmarshal(params, &request)
+	if request.MinTransferMinutes < 0 {
+		return nil, invalidParamsError{err: fmt.Errorf("the minimal transfer time must not be negative")}
+	}
+	if request.MaxWaitMinutes <= 0 {
+		request.MaxWaitMinutes = defaultMaxWaitMinutes
+	}
+	if request.MaxWaitMinutes < request.MinTransferMinutes {
+		return nil, invalidParamsError{err: fmt.Errorf("the maximal wait must not be smaller than the minimal transfer time")}
+	}
+	date, err := scenario.ParseOptionalDate(request.Date)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	transfers := s.manager.AnalyzeTransfers(scenario.TransferOptions{
+		MinTransferSeconds: request.MinTransferMinutes * 60,
+		MaxWaitSeconds:     request.MaxWaitMinutes * 60,
+		Date:               date,
+	})
+	result := make([]types.StationTransfers, 0, len(transfers))
+	for _, station := range transfers {
+		result = append(result, mapper.ToDtoStationTransfers(station))
+	}
+	return mustMarshal(result), nil
+}
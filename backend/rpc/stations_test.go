@@ -1,11 +1,13 @@
 package rpc
 
 import (
-	"backend/rpc/osrmutils"
+	"backend/rpc/routing"
 	"backend/rpc/types"
 	"backend/scenario"
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -14,7 +16,7 @@ import (
 
 func TestStationHandler_QueryStations(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
-	handler := newStationHandler(manager, "")
+	handler := newStationHandler(manager, routing.NewOSRM(""))
 
 	manager.SaveStation(scenario.Station{Key: "an unused station"})
 
@@ -55,38 +57,38 @@ func TestStationHandler_QueryStations(t *testing.T) {
 
 func TestStationHandler_UpdateStations(t *testing.T) {
 	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
-	handler := newStationHandler(manager, "")
+	handler := newStationHandler(manager, routing.NewOSRM(""))
 
 	t.Run("test unknown deleted station", func(t *testing.T) {
 		request, _ := json.Marshal(types.StationUpdate{
 			Deleted: []string{"not there"},
 		})
-		_, err := handler.UpdateStations(request)
+		_, err := handler.UpdateStations(context.Background(), request)
 		assert.EqualError(t, err, "could not find station to delete with key \"not there\"")
 	})
 	t.Run("test station still in use", func(t *testing.T) {
 		request, _ := json.Marshal(types.StationUpdate{
 			Deleted: []string{"ORxFvp_ICt"},
 		})
-		_, err := handler.UpdateStations(request)
+		_, err := handler.UpdateStations(context.Background(), request)
 		assert.EqualError(t, err, "could not delete station \"ORxFvp_ICt\" because it's still used by a line")
 	})
 	t.Run("should do nothing with empty input", func(t *testing.T) {
 		request := json.RawMessage("{}")
-		_, err := handler.UpdateStations(request)
+		_, err := handler.UpdateStations(context.Background(), request)
 		assert.NoError(t, err)
 		assert.Equal(t, 313, len(manager.Stations()))
 	})
 
 	osrmCalled := 0
 	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		route := osrmutils.RouteResponse{
-			Routes: []osrmutils.Route{
+		route := routing.RouteResponse{
+			Routes: []routing.Route{
 				{
 					Geometry: "k|}nHq_q{@]f@IJ??",
-					Legs: []osrmutils.Leg{
+					Legs: []routing.Leg{
 						{
-							Annotation: osrmutils.Annotation{
+							Annotation: routing.Annotation{
 								Distance: []float64{
 									22.283466,
 									6.688107,
@@ -94,7 +96,7 @@ func TestStationHandler_UpdateStations(t *testing.T) {
 							},
 						},
 						{
-							Annotation: osrmutils.Annotation{
+							Annotation: routing.Annotation{
 								Distance: []float64{0},
 								Duration: []float64{0},
 							},
@@ -108,7 +110,7 @@ func TestStationHandler_UpdateStations(t *testing.T) {
 		osrmCalled = osrmCalled + 1
 	}))
 	defer osrmServer.Close()
-	handler.osrmUrl = osrmServer.URL
+	handler.router = routing.NewOSRM(osrmServer.URL)
 
 	line29, _ := manager.Line("luhFjA1KKO")
 	assert.Equal(t, "3ej_MC2BRN", line29.Stops[1])
@@ -135,7 +137,7 @@ func TestStationHandler_UpdateStations(t *testing.T) {
 				},
 			},
 		})
-		_, err := handler.UpdateStations(request)
+		_, err := handler.UpdateStations(context.Background(), request)
 		assert.NoError(t, err)
 		assert.Equal(t, 7, osrmCalled)
 		barbarossaPlatz, _ := manager.Station("ORxFvp_ICt")
@@ -154,4 +156,125 @@ func TestStationHandler_UpdateStations(t *testing.T) {
 		assert.True(t, neustadt.IsWaypoint)
 		assert.Equal(t, 314, len(manager.Stations()))
 	})
+	t.Run("should reroute lines with the router of their transport mode", func(t *testing.T) {
+		manager := scenario.Empty()
+		manager.SaveStation(scenario.Station{Key: "a", Lat: 49.79745, Lng: 9.93503})
+		manager.SaveStation(scenario.Station{Key: "b", Lat: 49.80182, Lng: 9.92265})
+		manager.SaveLine(scenario.Line{Key: "tram", Stops: []string{"a", "b"}, Mode: scenario.ModeTram})
+		modes := &routing.Modes{Router: routing.NewOSRM("unreachable"), Routers: map[string]routing.Router{scenario.ModeTram: routing.NewHaversine()}}
+		handler := newStationHandler(manager, modes)
+		_, err := handler.UpdateStations(context.Background(), mustMarshal(types.StationUpdate{
+			ChangedOrAdded: []types.Station{{Key: "a", Lat: 49.79, Lng: 9.93}},
+		}))
+		require.NoError(t, err)
+		line, _ := manager.Line("tram")
+		require.Equal(t, 2, len(line.Path))
+		assert.Equal(t, 49.79, line.Path[0].Lat)
+		assert.InDelta(t, scenario.HaversineDistance(49.79, 9.93, 49.80182, 9.92265), line.Path[0].Dist, 0.001)
+	})
+	t.Run("should keep manually drawn segments", func(t *testing.T) {
+		manager := scenario.Empty()
+		manager.SaveStation(scenario.Station{Key: "a", Lat: 49.79745, Lng: 9.93503})
+		manager.SaveStation(scenario.Station{Key: "b", Lat: 49.80182, Lng: 9.92265})
+		manager.SaveStation(scenario.Station{Key: "c", Lat: 49.80500, Lng: 9.91000})
+		manager.SaveLine(scenario.Line{Key: "ferry", Stops: []string{"a", "b", "c"}, Mode: scenario.ModeFerry, Path: []scenario.Waypoint{
+			{Lat: 49.79745, Lng: 9.93503, Stop: true},
+			{Lat: 49.80182, Lng: 9.92265, Stop: true, Manual: true},
+			{Lat: 49.80400, Lng: 9.92000, Manual: true},
+			{Lat: 49.80500, Lng: 9.91000, Stop: true},
+		}})
+		handler := newStationHandler(manager, routing.NewHaversine())
+		_, err := handler.UpdateStations(context.Background(), mustMarshal(types.StationUpdate{
+			ChangedOrAdded: []types.Station{{Key: "b", Lat: 49.802, Lng: 9.923}},
+		}))
+		require.NoError(t, err)
+		line, _ := manager.Line("ferry")
+		require.Equal(t, 4, len(line.Path))
+		assert.Equal(t, []bool{false, true, true, false}, []bool{line.Path[0].Manual, line.Path[1].Manual, line.Path[2].Manual, line.Path[3].Manual})
+		assert.Equal(t, []bool{true, true, false, true}, []bool{line.Path[0].Stop, line.Path[1].Stop, line.Path[2].Stop, line.Path[3].Stop})
+		assert.Equal(t, 49.802, line.Path[1].Lat)
+		assert.Equal(t, 9.923, line.Path[1].Lng)
+		assert.Equal(t, 49.804, line.Path[2].Lat)
+		assert.InDelta(t, scenario.HaversineDistance(49.79745, 9.93503, 49.802, 9.923), line.Path[0].Dist, 0.001)
+		manualDistance := scenario.HaversineDistance(49.802, 9.923, 49.804, 9.92)
+		assert.InDelta(t, manualDistance, line.Path[1].Dist, 0.001)
+		assert.InDelta(t, manualDistance/(20/3.6), line.Path[1].Dur, 0.001)
+	})
+}
+
+func TestStationHandler_GetDepartures(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "a", Name: "Main Station"})
+	manager.SaveStation(scenario.Station{Key: "b", Name: "Harbour"})
+	manager.SaveLine(scenario.Line{Key: "line1", Name: "Line 1", Color: "#ff0000", Stops: []string{"a", "b"}})
+	manager.SaveTimetable(scenario.Timetable{
+		Key:         "timetable1",
+		LineKey:     "line1",
+		Name:        "Working Day",
+		StationKeys: []string{"a", "b"},
+		Tours: []scenario.Tour{
+			{
+				IntervalMinutes: 60,
+				LastTour:        scenario.MustParseTime("10:00"),
+				Events:          []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("6:00")}, {Arrival: scenario.MustParseTime("6:10")}},
+			},
+		},
+	})
+	handler := newStationHandler(manager, routing.NewOSRM(""))
+	t.Run("success", func(t *testing.T) {
+		rawResult, err := handler.getDepartures(mustMarshal(types.DepartureRequest{StationKey: "a", From: "7:00", To: "9:00"}))
+		assert.NoError(t, err)
+		var result []types.Departure
+		_ = json.Unmarshal(rawResult, &result)
+		departure := types.Departure{
+			LineKey:         "line1",
+			LineName:        "Line 1",
+			LineColor:       "#ff0000",
+			TimetableKey:    "timetable1",
+			TimetableName:   "Working Day",
+			DestinationKey:  "b",
+			DestinationName: "Harbour",
+		}
+		expected := make([]types.Departure, 0, 3)
+		for _, time := range []string{"7:00", "8:00", "9:00"} {
+			departure.Time = time
+			expected = append(expected, departure)
+		}
+		assert.Equal(t, expected, result)
+	})
+	t.Run("unknown station", func(t *testing.T) {
+		_, err := handler.getDepartures(mustMarshal(types.DepartureRequest{StationKey: "x", From: "7:00", To: "9:00"}))
+		assert.EqualError(t, err, "could not find station with key \"x\"")
+	})
+	t.Run("invalid window", func(t *testing.T) {
+		_, err := handler.getDepartures(mustMarshal(types.DepartureRequest{StationKey: "a", From: "7:00", To: "9"}))
+		assert.EqualError(t, err, "to: the time \"9\" is not in the format h:mm or h:mm:ss")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+}
+
+func TestStationHandler_AnalyzeTransfers(t *testing.T) {
+	manager, _ := scenario.LoadScenario(filepath.Join("..", "testdata"))
+	handler := newStationHandler(manager, routing.NewOSRM(""))
+	t.Run("success", func(t *testing.T) {
+		rawResult, err := handler.analyzeTransfers(mustMarshal(types.TransferRequest{MinTransferMinutes: 3}))
+		assert.NoError(t, err)
+		var result []types.StationTransfers
+		_ = json.Unmarshal(rawResult, &result)
+		assert.NotEmpty(t, result)
+		for index, station := range result {
+			assert.GreaterOrEqual(t, station.Score, 0.0)
+			assert.LessOrEqual(t, station.Score, 100.0)
+			assert.LessOrEqual(t, station.Connections, station.Arrivals)
+			assert.LessOrEqual(t, station.AverageWaitMinutes, 30.0)
+			if index > 0 {
+				assert.LessOrEqual(t, result[index-1].Score, station.Score)
+			}
+		}
+	})
+	t.Run("invalid wait", func(t *testing.T) {
+		_, err := handler.analyzeTransfers(mustMarshal(types.TransferRequest{MinTransferMinutes: 10, MaxWaitMinutes: 5}))
+		assert.EqualError(t, err, "the maximal wait must not be smaller than the minimal transfer time")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
 }
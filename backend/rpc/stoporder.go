@@ -0,0 +1,300 @@
+package rpc
+
+import (
+	"backend/rpc/routing"
+	"backend/rpc/types"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultStopOrderCandidates = 5
+
+// maxStopOrderIterations limits the number of improving moves of the local search.
+const maxStopOrderIterations = 100
+
+// stopOrderProblem describes the stations of a line and the routes between them. All indices refer to the
+// stations in the current order of the line.
+type stopOrderProblem struct {
+	waypoint       []bool
+	removable      []bool
+	fixedTerminals bool
+	metric         string
+	table          routing.Table
+}
+
+// stopOrder is a candidate sequence of stations with its predicted figures. The path of the candidate consists
+// of the routes between consecutive stations.
+type stopOrder struct {
+	stations              []int
+	averageDistanceDetour float64
+	averageDurationDetour float64
+	length                float64
+	duration              float64
+}
+
+func (o stopOrder) key() string {
+	parts := make([]string, 0, len(o.stations))
+	for _, station := range o.stations {
+		parts = append(parts, strconv.Itoa(station))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *stopOrderProblem) score(order stopOrder) float64 {
+	if p.metric == detourMetricDuration {
+		return order.averageDurationDetour
+	}
+	return order.averageDistanceDetour
+}
+
+// evaluate predicts the figures of the sequence of stations. The relative detours are averaged over all pairs
+// of stops, waypoints are only passed. Fails if two consecutive stations are not connected or if no pair of
+// stops has a route.
+func (p *stopOrderProblem) evaluate(stations []int) (stopOrder, bool) {
+	lengths := make([]float64, len(stations))
+	durations := make([]float64, len(stations))
+	for index := 1; index < len(stations); index++ {
+		distance := p.table.Distances[stations[index-1]][stations[index]]
+		duration := p.table.Durations[stations[index-1]][stations[index]]
+		if distance == nil || duration == nil {
+			return stopOrder{}, false
+		}
+		lengths[index] = lengths[index-1] + *distance
+		durations[index] = durations[index-1] + *duration
+	}
+	result := stopOrder{stations: stations, length: lengths[len(stations)-1], duration: durations[len(stations)-1]}
+	pairs := 0
+	for source := range stations {
+		if p.waypoint[stations[source]] {
+			continue
+		}
+		for target := source + 1; target < len(stations); target++ {
+			if p.waypoint[stations[target]] {
+				continue
+			}
+			distance := p.table.Distances[stations[source]][stations[target]]
+			duration := p.table.Durations[stations[source]][stations[target]]
+			// the detour of stations without route or at the same location is undefined
+			if distance == nil || *distance == 0 || duration == nil || *duration == 0 {
+				continue
+			}
+			pairs++
+			result.averageDistanceDetour = result.averageDistanceDetour + (lengths[target]-lengths[source]) / *distance
+			result.averageDurationDetour = result.averageDurationDetour + (durations[target]-durations[source]) / *duration
+		}
+	}
+	if pairs == 0 {
+		return stopOrder{}, false
+	}
+	result.averageDistanceDetour = result.averageDistanceDetour / float64(pairs)
+	result.averageDurationDetour = result.averageDurationDetour / float64(pairs)
+	return result, true
+}
+
+// neighbours returns the sequences that differ from the given one by moving a station, reversing a part of the
+// sequence or dropping a removable station. Terminals are neither moved nor dropped if they are fixed.
+func (p *stopOrderProblem) neighbours(stations []int) [][]int {
+	first, last := 0, len(stations)-1
+	if p.fixedTerminals {
+		first, last = 1, len(stations)-2
+	}
+	result := make([][]int, 0)
+	for from := first; from <= last; from++ {
+		for to := first; to <= last; to++ {
+			if from == to {
+				continue
+			}
+			moved := make([]int, 0, len(stations))
+			moved = append(moved, stations[:from]...)
+			moved = append(moved, stations[from+1:]...)
+			moved = append(moved[:to], append([]int{stations[from]}, moved[to:]...)...)
+			result = append(result, moved)
+		}
+		for to := from + 2; to <= last; to++ {
+			reversed := append([]int{}, stations...)
+			for left, right := from, to; left < right; left, right = left+1, right-1 {
+				reversed[left], reversed[right] = reversed[right], reversed[left]
+			}
+			result = append(result, reversed)
+		}
+		if p.removable[stations[from]] && len(stations) > 2 {
+			dropped := make([]int, 0, len(stations)-1)
+			dropped = append(dropped, stations[:from]...)
+			dropped = append(dropped, stations[from+1:]...)
+			result = append(result, dropped)
+		}
+	}
+	return result
+}
+
+// stopOrderHeap keeps the best evaluated sequences with the worst one on top, so it can be replaced by a better one.
+type stopOrderHeap struct {
+	problem *stopOrderProblem
+	orders  []stopOrder
+}
+
+func (h *stopOrderHeap) Len() int { return len(h.orders) }
+func (h *stopOrderHeap) Less(i, j int) bool {
+	return h.problem.better(h.orders[j], h.orders[i])
+}
+func (h *stopOrderHeap) Swap(i, j int) { h.orders[i], h.orders[j] = h.orders[j], h.orders[i] }
+func (h *stopOrderHeap) Push(item any) { h.orders = append(h.orders, item.(stopOrder)) }
+func (h *stopOrderHeap) Pop() (item any) {
+	item, h.orders = h.orders[len(h.orders)-1], h.orders[:len(h.orders)-1]
+	return item
+}
+
+// better orders sequences by their score and sequences with the same score by their stations.
+func (p *stopOrderProblem) better(a stopOrder, b stopOrder) bool {
+	if p.score(a) != p.score(b) {
+		return p.score(a) < p.score(b)
+	}
+	return a.key() < b.key()
+}
+
+// optimize searches for better sequences of stations by repeatedly taking the best neighbour of the current
+// sequence until no neighbour improves the score. It returns the evaluation of the current sequence and up to
+// count evaluated sequences that are better, ordered by their score.
+func (p *stopOrderProblem) optimize(ctx context.Context, count int) (stopOrder, []stopOrder, error) {
+	stations := make([]int, 0, len(p.waypoint))
+	for index := range p.waypoint {
+		stations = append(stations, index)
+	}
+	current, ok := p.evaluate(stations)
+	if !ok {
+		return stopOrder{}, nil, fmt.Errorf("the stations of the line are not connected by routes")
+	}
+	candidates := &stopOrderHeap{problem: p, orders: make([]stopOrder, 0, count)}
+	kept := make(map[string]bool)
+	keep := func(candidate stopOrder) {
+		key := candidate.key()
+		if kept[key] || !p.better(candidate, current) {
+			return
+		}
+		if candidates.Len() < count {
+			heap.Push(candidates, candidate)
+		} else if count > 0 && p.better(candidate, candidates.orders[0]) {
+			delete(kept, candidates.orders[0].key())
+			candidates.orders[0] = candidate
+			heap.Fix(candidates, 0)
+		} else {
+			return
+		}
+		kept[key] = true
+	}
+	best := current
+	for iteration := 0; iteration < maxStopOrderIterations; iteration++ {
+		if ctx.Err() != nil {
+			return stopOrder{}, nil, ctx.Err()
+		}
+		improved := best
+		for _, neighbour := range p.neighbours(best.stations) {
+			candidate, ok := p.evaluate(neighbour)
+			if !ok {
+				continue
+			}
+			keep(candidate)
+			if p.score(candidate) < p.score(improved) {
+				improved = candidate
+			}
+		}
+		if improved.key() == best.key() {
+			break
+		}
+		best = improved
+	}
+	result := make([]stopOrder, candidates.Len())
+	for index := len(result) - 1; index >= 0; index-- {
+		result[index] = heap.Pop(candidates).(stopOrder)
+	}
+	return current, result, nil
+}
+
+func (o *osrmHandler) optimizeStopOrder(ctx context.Context, params json.RawMessage) (json.RawMessage, error) {
+	var request types.StopOrderRequest
+	_ = json.Unmarshal(params, &request)
+	metricRequest := types.DetourRequest{Metric: request.Metric}
+	err := checkDetourRequest(&metricRequest)
+	if err != nil {
+		return nil, err
+	}
+	if request.Candidates < 0 {
+		return nil, invalidParamsError{err: fmt.Errorf("the number of candidates must not be negative")}
+	}
+	if request.Candidates == 0 {
+		request.Candidates = defaultStopOrderCandidates
+	}
+	line, ok := o.manager.Line(request.LineKey)
+	if !ok {
+		return nil, fmt.Errorf("could not find line with key \"%s\"", request.LineKey)
+	}
+	stations := line.Stations()
+	required := make(map[string]bool)
+	for _, key := range request.RequiredStations {
+		required[key] = true
+	}
+	problem := stopOrderProblem{
+		waypoint:       make([]bool, 0, len(stations)),
+		removable:      make([]bool, 0, len(stations)),
+		fixedTerminals: request.FixedTerminals,
+		metric:         metricRequest.Metric,
+	}
+	coordinates := make([]types.LatLng, 0, len(stations))
+	for _, station := range stations {
+		problem.waypoint = append(problem.waypoint, station.IsWaypoint)
+		problem.removable = append(problem.removable, station.IsWaypoint && !required[station.Key])
+		coordinates = append(coordinates, types.LatLng{Lat: station.Lat, Lng: station.Lng})
+		delete(required, station.Key)
+	}
+	for _, key := range request.RequiredStations {
+		if required[key] {
+			return nil, invalidParamsError{err: fmt.Errorf("the required station \"%s\" is not served by line \"%s\"", key, line.Key)}
+		}
+	}
+	if len(stations) < 2 {
+		return nil, invalidParamsError{err: fmt.Errorf("line \"%s\" must have at least two stations", line.Key)}
+	}
+	problem.table, err = routing.ForMode(o.router, line.TransportMode()).Table(ctx, coordinates)
+	if err != nil {
+		return nil, fmt.Errorf("could not query routes between the stations: %v", err)
+	}
+	current, candidates, err := problem.optimize(ctx, request.Candidates)
+	if err != nil {
+		return nil, err
+	}
+	toDto := func(order stopOrder) types.StopOrderCandidate {
+		result := types.StopOrderCandidate{
+			Stations:              make([]string, 0, len(order.stations)),
+			DroppedStations:       make([]string, 0),
+			AverageDetour:         problem.score(order),
+			AverageDistanceDetour: order.averageDistanceDetour,
+			AverageDurationDetour: order.averageDurationDetour,
+			Length:                order.length,
+			Duration:              order.duration,
+		}
+		served := make(map[int]bool)
+		for _, station := range order.stations {
+			result.Stations = append(result.Stations, stations[station].Key)
+			served[station] = true
+		}
+		for index, station := range stations {
+			if !served[index] {
+				result.DroppedStations = append(result.DroppedStations, station.Key)
+			}
+		}
+		return result
+	}
+	response := types.StopOrderResponse{
+		Metric:     problem.metric,
+		Current:    toDto(current),
+		Candidates: make([]types.StopOrderCandidate, 0, len(candidates)),
+	}
+	for _, candidate := range candidates {
+		response.Candidates = append(response.Candidates, toDto(candidate))
+	}
+	return mustMarshal(response), nil
+}
@@ -0,0 +1,206 @@
+package rpc
+
+import (
+	"backend/rpc/routing"
+	"backend/rpc/types"
+	"backend/scenario"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	polyline "github.com/twpayne/go-polyline"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+// lineTable returns a table of stations on a straight line at the given positions in meters.
+func lineTable(positions []float64) routing.Table {
+	table := routing.Table{}
+	for _, from := range positions {
+		distances := make([]*float64, 0, len(positions))
+		durations := make([]*float64, 0, len(positions))
+		for _, to := range positions {
+			distance := math.Abs(to - from)
+			duration := distance / 10
+			distances = append(distances, &distance)
+			durations = append(durations, &duration)
+		}
+		table.Distances = append(table.Distances, distances)
+		table.Durations = append(table.Durations, durations)
+	}
+	return table
+}
+
+func TestStopOrderProblem_optimize(t *testing.T) {
+	t.Run("reorder", func(t *testing.T) {
+		problem := stopOrderProblem{
+			waypoint:  []bool{false, false, false, false},
+			removable: []bool{false, false, false, false},
+			metric:    detourMetricDistance,
+			table:     lineTable([]float64{0, 200, 100, 300}),
+		}
+		current, candidates, err := problem.optimize(context.Background(), 3)
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2, 3}, current.stations)
+		assert.Equal(t, 500.0, current.length)
+		require.NotEmpty(t, candidates)
+		assert.LessOrEqual(t, len(candidates), 3)
+		assert.Equal(t, 1.0, candidates[0].averageDistanceDetour)
+		assert.Equal(t, 300.0, candidates[0].length)
+		assert.Contains(t, [][]int{{0, 2, 1, 3}, {3, 1, 2, 0}}, candidates[0].stations)
+		for _, candidate := range candidates {
+			assert.Less(t, candidate.averageDistanceDetour, current.averageDistanceDetour)
+		}
+	})
+	t.Run("fixed terminals", func(t *testing.T) {
+		problem := stopOrderProblem{
+			waypoint:       []bool{false, false, false, false},
+			removable:      []bool{false, false, false, false},
+			fixedTerminals: true,
+			metric:         detourMetricDuration,
+			table:          lineTable([]float64{100, 200, 0, 300}),
+		}
+		_, candidates, err := problem.optimize(context.Background(), 5)
+		require.NoError(t, err)
+		require.NotEmpty(t, candidates)
+		for _, candidate := range candidates {
+			assert.Equal(t, 0, candidate.stations[0])
+			assert.Equal(t, 3, candidate.stations[3])
+		}
+		assert.Equal(t, []int{0, 2, 1, 3}, candidates[0].stations)
+	})
+	t.Run("drop waypoint", func(t *testing.T) {
+		problem := stopOrderProblem{
+			waypoint:       []bool{false, true, false},
+			removable:      []bool{false, true, false},
+			fixedTerminals: true,
+			metric:         detourMetricDistance,
+			table:          lineTable([]float64{0, 500, 100}),
+		}
+		_, candidates, err := problem.optimize(context.Background(), 5)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(candidates))
+		assert.Equal(t, []int{0, 2}, candidates[0].stations)
+
+		problem.removable[1] = false
+		_, candidates, err = problem.optimize(context.Background(), 5)
+		require.NoError(t, err)
+		assert.Empty(t, candidates)
+	})
+	t.Run("bounded candidates", func(t *testing.T) {
+		problem := stopOrderProblem{
+			waypoint:  []bool{false, false, false, false, false, false},
+			removable: []bool{false, false, false, false, false, false},
+			metric:    detourMetricDistance,
+			table:     lineTable([]float64{0, 400, 100, 500, 200, 300}),
+		}
+		_, all, err := problem.optimize(context.Background(), 1000)
+		require.NoError(t, err)
+		require.Greater(t, len(all), 3)
+		_, best, err := problem.optimize(context.Background(), 3)
+		require.NoError(t, err)
+		assert.Equal(t, all[:3], best)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		problem := stopOrderProblem{
+			waypoint:  []bool{false, false, false, false},
+			removable: []bool{false, false, false, false},
+			metric:    detourMetricDistance,
+			table:     lineTable([]float64{0, 200, 100, 300}),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := problem.optimize(ctx, 3)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("not connected", func(t *testing.T) {
+		problem := stopOrderProblem{
+			waypoint:  []bool{false, false},
+			removable: []bool{false, false},
+			table:     routing.Table{Distances: [][]*float64{{nil, nil}, {nil, nil}}, Durations: [][]*float64{{nil, nil}, {nil, nil}}},
+		}
+		_, _, err := problem.optimize(context.Background(), 5)
+		assert.EqualError(t, err, "the stations of the line are not connected by routes")
+	})
+}
+
+func TestOsrmHandler_optimizeStopOrder(t *testing.T) {
+	polylineMatcher := regexp.MustCompile("polyline\\(([^)]+)\\)")
+	// the stations are on a meridian, the distance is 100 m per 0.001 degree of latitude
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri, _ := url.QueryUnescape(r.RequestURI)
+		coordinates, _, _ := polyline.DecodeCoords([]byte(polylineMatcher.FindStringSubmatch(uri)[1]))
+		positions := make([]float64, 0, len(coordinates))
+		for _, coordinate := range coordinates {
+			positions = append(positions, math.Round(coordinate[0]*100000))
+		}
+		table := lineTable(positions)
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "Ok", "distances": table.Distances, "durations": table.Durations})
+	}))
+	defer osrmServer.Close()
+	manager := scenario.Empty()
+	keys := make([]string, 0)
+	for _, station := range []scenario.Station{
+		{Name: "A", Lat: 49.000, Lng: 9.9},
+		{Name: "C", Lat: 49.002, Lng: 9.9},
+		{Name: "W", Lat: 49.010, Lng: 9.9, IsWaypoint: true},
+		{Name: "B", Lat: 49.001, Lng: 9.9},
+		{Name: "D", Lat: 49.003, Lng: 9.9},
+	} {
+		keys = append(keys, manager.SaveStation(station).Key)
+	}
+	line := manager.SaveLine(scenario.Line{Name: "1", Stops: keys})
+	handler := newOsrmHandler(manager, routing.NewOSRM(osrmServer.URL))
+
+	t.Run("success", func(t *testing.T) {
+		result, err := handler.optimizeStopOrder(context.Background(), mustMarshal(types.StopOrderRequest{LineKey: line.Key, FixedTerminals: true}))
+		require.NoError(t, err)
+		var response types.StopOrderResponse
+		_ = json.Unmarshal(result, &response)
+		assert.Equal(t, "distance", response.Metric)
+		assert.Equal(t, keys, response.Current.Stations)
+		assert.Empty(t, response.Current.DroppedStations)
+		require.NotEmpty(t, response.Candidates)
+		assert.Equal(t, types.StopOrderCandidate{
+			Stations:              []string{keys[0], keys[3], keys[1], keys[4]},
+			DroppedStations:       []string{keys[2]},
+			AverageDetour:         1,
+			AverageDistanceDetour: 1,
+			AverageDurationDetour: 1,
+			Length:                300,
+			Duration:              30,
+		}, response.Candidates[0])
+		unchanged, _ := manager.Line(line.Key)
+		assert.Equal(t, keys, unchanged.Stops)
+	})
+	t.Run("required waypoint", func(t *testing.T) {
+		result, err := handler.optimizeStopOrder(context.Background(), mustMarshal(types.StopOrderRequest{
+			LineKey:          line.Key,
+			FixedTerminals:   true,
+			RequiredStations: []string{keys[2]},
+			Candidates:       20,
+		}))
+		require.NoError(t, err)
+		var response types.StopOrderResponse
+		_ = json.Unmarshal(result, &response)
+		require.NotEmpty(t, response.Candidates)
+		for _, candidate := range response.Candidates {
+			assert.Empty(t, candidate.DroppedStations)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		_, err := handler.optimizeStopOrder(context.Background(), mustMarshal(types.StopOrderRequest{LineKey: "unknown"}))
+		assert.EqualError(t, err, "could not find line with key \"unknown\"")
+		_, err = handler.optimizeStopOrder(context.Background(), mustMarshal(types.StopOrderRequest{LineKey: line.Key, RequiredStations: []string{"unknown"}}))
+		assert.EqualError(t, err, "the required station \"unknown\" is not served by line \""+line.Key+"\"")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+		_, err = handler.optimizeStopOrder(context.Background(), mustMarshal(types.StopOrderRequest{LineKey: line.Key, Candidates: -1}))
+		assert.EqualError(t, err, "the number of candidates must not be negative")
+		_, err = handler.optimizeStopOrder(context.Background(), mustMarshal(types.StopOrderRequest{LineKey: line.Key, Metric: "comfort"}))
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+}
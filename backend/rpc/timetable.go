@@ -9,6 +9,11 @@ import (
 	"reflect"
 )
 
+const (
+	tourModeInterval = "interval"
+	tourModeExplicit = "explicit"
+)
+
 type timetableHandler struct {
 	manager *scenario.Manager
 }
@@ -28,15 +33,22 @@ func (t *timetableHandler) Methods() map[string]rpcMethod {
 			output: reflect.TypeOf([]types.Timetable{}),
 			method: t.getTimetablesForLine,
 		},
+		"getTimetablesForDate": {
+			description: "Returns a list of all timetables that are operated on the given date in the format yyyy-mm-dd according to their calendar. " +
+				"Timetables without calendar are operated every day. Will not return the tours and the stations of the timetables.",
+			input:  reflect.TypeOf(types.DateRequest{}),
+			output: reflect.TypeOf([]types.Timetable{}),
+			method: t.getTimetablesForDate,
+		},
 		"saveTimetable": {
-			description:    "Saves the given timetable.",
+			description:    "Saves the given timetable. Times must be given in the format h:mm or h:mm:ss, times after midnight as 24:00 and later.",
 			input:          reflect.TypeOf(types.Timetable{}),
 			output:         reflect.TypeOf(types.Timetable{}),
 			method:         t.saveTimetable,
 			persistChanged: true,
 		},
 		"saveTimetableMetadata": {
-			description: "Saves the name and the line of a timetable. Fails if the timetable does not exist yet. Does not touch tours and stations of existing timetable",
+			description: "Saves the name, the line and the calendar of a timetable. Fails if the timetable does not exist yet. Does not touch tours and stations of existing timetable",
 			input:       reflect.TypeOf(types.Timetable{}),
 			output:      reflect.TypeOf(types.Timetable{}),
 			method:      t.saveTimetableMetadata,
@@ -47,6 +59,50 @@ func (t *timetableHandler) Methods() map[string]rpcMethod {
 			method:         t.deleteTimetable,
 			persistChanged: true,
 		},
+		"expandTrips": {
+			description: "Expands the tours of the timetable identified by the given key into concrete trips sorted by their start. " +
+				"Tours with an interval are repeated until their last tour. Times after midnight are given as 24:00 and later.",
+			input:  reflect.TypeOf(types.Timetable{}),
+			output: reflect.TypeOf([]types.Trip{}),
+			method: t.expandTrips,
+		},
+		"generateFromLine": {
+			description: "Generates and saves a timetable for the given line. The times of the stations are derived from the durations " +
+				"of the line's path plus the dwell time in seconds at every intermediate station. Waypoints are included without times. " +
+				"If an interval is given, the tour is repeated until the last departure.",
+			input:          reflect.TypeOf(types.TimetableGenerationRequest{}),
+			output:         reflect.TypeOf(types.Timetable{}),
+			method:         t.generateFromLine,
+			persistChanged: true,
+		},
+		"generateTours": {
+			description: "Generates tours from a template and a list of time bands with headways, e.g. every 20 minutes from 5:00 to 6:00 " +
+				"and every 10 minutes from 6:00 to 9:00. The template contains the events of a single tour, its times are shifted to each " +
+				"departure of the bands, so they can be given as offsets like 0:00, 0:05. In mode \"interval\", the default, a tour with " +
+				"interval is created per band, in mode \"explicit\" a tour per departure. The tours are not saved.",
+			input:  reflect.TypeOf(types.TourGenerationRequest{}),
+			output: reflect.TypeOf([]types.Tour{}),
+			method: t.generateTours,
+		},
+		"compactTours": {
+			description: "Folds explicit tours of the timetable identified by the given key that have the same times relative to their start " +
+				"and depart at a regular interval into a single tour with interval and last tour. At least three tours are folded. " +
+				"Returns the replaced tours and the resulting tours as preview, they are only saved if apply is true.",
+			input:            reflect.TypeOf(types.CompactionRequest{}),
+			output:           reflect.TypeOf(types.CompactionResult{}),
+			method:           t.compactTours,
+			persistRequested: compactionApplied,
+		},
+		"checkRunningTimes": {
+			description: "Compares the time between consecutively served stations of every tour with the durations of the line's path. " +
+				"Reports segments scheduled shorter than minRatio times the routed duration as tight and segments scheduled longer than " +
+				"maxRatio times the routed duration as slack, ignoring deviations up to toleranceSeconds. Missing values default to a " +
+				"minRatio of 1, a maxRatio of 2 and a tolerance of 60 seconds, a maxRatio of 0 disables the slack check. Checks all timetables unless a timetable key is given " +
+				"and only returns timetables with issues or errors.",
+			input:  reflect.TypeOf(types.RunningTimeRequest{}),
+			output: reflect.TypeOf([]types.RunningTimeReport{}),
+			method: t.checkRunningTimes,
+		},
 		"getTimetable": {
 			description: "Retrieves the timetable identified by the given key.",
 			input:       reflect.TypeOf(types.Timetable{}),
@@ -74,10 +130,48 @@ func (t *timetableHandler) getTimetablesForLine(params json.RawMessage) (json.Ra
 	return mustMarshal(result), nil
 }
 
+func (t *timetableHandler) getTimetablesForDate(params json.RawMessage) (json.RawMessage, error) {
+	var request types.DateRequest
+	_ = json.Unmarshal(params, &request)
+	date, err := scenario.ParseDate(request.Date)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	result := make([]types.Timetable, 0)
+	for _, tt := range t.manager.ActiveTimetables(date) {
+		result = append(result, types.Timetable{
+			Key:         tt.Key,
+			Name:        tt.Name,
+			LineKey:     tt.LineKey,
+			LineName:    tt.Line().Name,
+			CalendarKey: tt.CalendarKey,
+		})
+	}
+	return mustMarshal(result), nil
+}
+
+// checkCalendar fails if the calendar key is set, but the calendar does not exist.
+func (t *timetableHandler) checkCalendar(key string) error {
+	if key == "" {
+		return nil
+	}
+	if _, ok := t.manager.Calendar(key); !ok {
+		return invalidParamsError{err: fmt.Errorf("could not find calendar with key \"%s\"", key)}
+	}
+	return nil
+}
+
 func (t *timetableHandler) saveTimetable(params json.RawMessage) (json.RawMessage, error) {
 	var timetable types.Timetable
 	_ = json.Unmarshal(params, &timetable)
-	vo := mapper.ToVoTimetable(timetable)
+	vo, err := mapper.ToVoTimetable(timetable)
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	err = t.checkCalendar(vo.CalendarKey)
+	if err != nil {
+		return nil, err
+	}
 	result := t.manager.SaveTimetable(vo)
 	return mustMarshal(mapper.ToDtoTimetable(result)), nil
 }
@@ -106,8 +200,166 @@ func (t *timetableHandler) saveTimetableMetadata(params json.RawMessage) (json.R
 	if !ok {
 		return nil, fmt.Errorf("could not find timetable with key \"%s\"", timetable.Key)
 	}
+	err := t.checkCalendar(timetable.CalendarKey)
+	if err != nil {
+		return nil, err
+	}
 	result.Name = timetable.Name
 	result.LineKey = timetable.LineKey
+	result.CalendarKey = timetable.CalendarKey
 	t.manager.SaveTimetable(result)
 	return mustMarshal(mapper.ToDtoTimetable(result)), nil
 }
+
+func (t *timetableHandler) expandTrips(params json.RawMessage) (json.RawMessage, error) {
+	var timetable types.Timetable
+	_ = json.Unmarshal(params, &timetable)
+	result, ok := t.manager.Timetable(timetable.Key)
+	if !ok {
+		return nil, fmt.Errorf("could not find timetable with key \"%s\"", timetable.Key)
+	}
+	return mustMarshal(mapper.ToDtoTrips(result.Trips())), nil
+}
+
+func (t *timetableHandler) generateFromLine(params json.RawMessage) (json.RawMessage, error) {
+	var request types.TimetableGenerationRequest
+	_ = json.Unmarshal(params, &request)
+	line, ok := t.manager.Line(request.LineKey)
+	if !ok {
+		return nil, fmt.Errorf("could not find line with key \"%s\"", request.LineKey)
+	}
+	firstDeparture, err := scenario.ParseTime(request.FirstDeparture)
+	if err != nil {
+		return nil, invalidParamsError{err: fmt.Errorf("first departure: %v", err)}
+	}
+	lastDeparture, err := scenario.ParseOptionalTime(request.LastDeparture)
+	if err != nil {
+		return nil, invalidParamsError{err: fmt.Errorf("last departure: %v", err)}
+	}
+	timetable, err := line.GenerateTimetable(scenario.TimetableTemplate{
+		Name:            request.Name,
+		DwellSeconds:    request.DwellSeconds,
+		FirstDeparture:  firstDeparture,
+		IntervalMinutes: request.IntervalMinutes,
+		LastDeparture:   lastDeparture,
+	})
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	result := t.manager.SaveTimetable(timetable)
+	return mustMarshal(mapper.ToDtoTimetable(result)), nil
+}
+
+func (t *timetableHandler) checkRunningTimes(params json.RawMessage) (json.RawMessage, error) {
+	var request types.RunningTimeRequest
+	_ = json.Unmarshal(params, &request)
+	if request.MinRatio < 0 || request.MaxRatio != nil && *request.MaxRatio < 0 || request.ToleranceSeconds != nil && *request.ToleranceSeconds < 0 {
+		return nil, invalidParamsError{err: fmt.Errorf("the ratios and the tolerance must not be negative")}
+	}
+	options := scenario.DefaultRunningTimeOptions()
+	if request.MinRatio > 0 {
+		options.MinRatio = request.MinRatio
+	}
+	if request.MaxRatio != nil {
+		options.MaxRatio = *request.MaxRatio
+	}
+	if request.ToleranceSeconds != nil {
+		options.ToleranceSeconds = *request.ToleranceSeconds
+	}
+	if options.MaxRatio > 0 && options.MaxRatio < options.MinRatio {
+		return nil, invalidParamsError{err: fmt.Errorf("the maximal ratio must not be smaller than the minimal ratio")}
+	}
+	var reports []scenario.RunningTimeReport
+	if request.TimetableKey == "" {
+		reports = t.manager.CheckRunningTimes(options)
+	} else {
+		timetable, ok := t.manager.Timetable(request.TimetableKey)
+		if !ok {
+			return nil, fmt.Errorf("could not find timetable with key \"%s\"", request.TimetableKey)
+		}
+		issues, err := timetable.CheckRunningTimes(options)
+		if err != nil || len(issues) > 0 {
+			reports = append(reports, scenario.RunningTimeReport{Timetable: timetable, Issues: issues, Err: err})
+		}
+	}
+	result := make([]types.RunningTimeReport, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, mapper.ToDtoRunningTimeReport(report))
+	}
+	return mustMarshal(result), nil
+}
+
+func (t *timetableHandler) generateTours(params json.RawMessage) (json.RawMessage, error) {
+	var request types.TourGenerationRequest
+	_ = json.Unmarshal(params, &request)
+	if request.Mode == "" {
+		request.Mode = tourModeInterval
+	}
+	if request.Mode != tourModeInterval && request.Mode != tourModeExplicit {
+		return nil, invalidParamsError{err: fmt.Errorf("the mode \"%s\" is unknown, use \"%s\" or \"%s\"", request.Mode, tourModeInterval, tourModeExplicit)}
+	}
+	if request.TimetableKey != "" {
+		timetable, ok := t.manager.Timetable(request.TimetableKey)
+		if !ok {
+			return nil, fmt.Errorf("could not find timetable with key \"%s\"", request.TimetableKey)
+		}
+		if len(request.Template) != len(timetable.StationKeys) {
+			return nil, invalidParamsError{err: fmt.Errorf("the template has %d events, but the timetable has %d stations", len(request.Template), len(timetable.StationKeys))}
+		}
+	}
+	template, err := mapper.ToVoEvents(request.Template)
+	if err != nil {
+		return nil, invalidParamsError{err: fmt.Errorf("template, %v", err)}
+	}
+	pattern := scenario.TourPattern{Template: template, UseIntervals: request.Mode == tourModeInterval}
+	for index, band := range request.Bands {
+		start, err := scenario.ParseTime(band.Start)
+		if err != nil {
+			return nil, invalidParamsError{err: fmt.Errorf("start of band %d: %v", index, err)}
+		}
+		end, err := scenario.ParseTime(band.End)
+		if err != nil {
+			return nil, invalidParamsError{err: fmt.Errorf("end of band %d: %v", index, err)}
+		}
+		pattern.Bands = append(pattern.Bands, scenario.TimeBand{Start: start, End: end, HeadwayMinutes: band.HeadwayMinutes})
+	}
+	tours, err := pattern.Tours()
+	if err != nil {
+		return nil, invalidParamsError{err: err}
+	}
+	return mustMarshal(mapper.ToDtoTours(tours)), nil
+}
+
+// compactionApplied reports whether the compaction request saves the result, only then the scenario is persisted.
+func compactionApplied(params json.RawMessage) bool {
+	var request types.CompactionRequest
+	_ = json.Unmarshal(params, &request)
+	return request.Apply
+}
+
+func (t *timetableHandler) compactTours(params json.RawMessage) (json.RawMessage, error) {
+	var request types.CompactionRequest
+	_ = json.Unmarshal(params, &request)
+	timetable, ok := t.manager.Timetable(request.Key)
+	if !ok {
+		return nil, fmt.Errorf("could not find timetable with key \"%s\"", request.Key)
+	}
+	compacted, groups := timetable.Compact()
+	result := types.CompactionResult{
+		ToursBefore: len(timetable.Tours),
+		ToursAfter:  len(compacted.Tours),
+		Compacted:   make([]types.CompactedTour, 0, len(groups)),
+		Tours:       mapper.ToDtoTours(compacted.Tours),
+	}
+	for _, group := range groups {
+		result.Compacted = append(result.Compacted, types.CompactedTour{
+			Tour:          mapper.ToDtoTours([]scenario.Tour{group.Tour})[0],
+			ReplacedTours: group.Sources,
+		})
+	}
+	if request.Apply && len(groups) > 0 {
+		t.manager.SaveTimetable(compacted)
+		result.Applied = true
+	}
+	return mustMarshal(result), nil
+}
@@ -18,7 +18,7 @@ func TestTimetableHandler_GetTimetablesForLine(t *testing.T) {
 		Tours: []scenario.Tour{
 			{
 				IntervalMinutes: 10,
-				LastTour:        "16:30",
+				LastTour:        scenario.MustParseTime("16:30"),
 				Events:          []scenario.ArrivalDeparture{},
 			},
 		},
@@ -31,7 +31,7 @@ func TestTimetableHandler_GetTimetablesForLine(t *testing.T) {
 		Tours: []scenario.Tour{
 			{
 				IntervalMinutes: 10,
-				LastTour:        "16:30",
+				LastTour:        scenario.MustParseTime("16:30"),
 				Events:          []scenario.ArrivalDeparture{},
 			},
 		},
@@ -44,7 +44,7 @@ func TestTimetableHandler_GetTimetablesForLine(t *testing.T) {
 		Tours: []scenario.Tour{
 			{
 				IntervalMinutes: 10,
-				LastTour:        "16:30",
+				LastTour:        scenario.MustParseTime("16:30"),
 				Events:          []scenario.ArrivalDeparture{},
 			},
 		},
@@ -121,7 +121,7 @@ func TestTimetableHandler_SaveTimetable(t *testing.T) {
 	assert.Equal(t, []scenario.Tour{
 		{
 			Events: []scenario.ArrivalDeparture{
-				{Departure: "8:00"}, {Departure: "8:05"}, {Arrival: "8:10"},
+				{Departure: scenario.MustParseTime("8:00")}, {Departure: scenario.MustParseTime("8:05")}, {Arrival: scenario.MustParseTime("8:10")},
 			},
 		},
 	}, saved.Tours)
@@ -129,6 +129,20 @@ func TestTimetableHandler_SaveTimetable(t *testing.T) {
 	assert.Equal(t, 3, len(saved.Stations()))
 }
 
+func TestTimetableHandler_SaveTimetable_InvalidTime(t *testing.T) {
+	manager := scenario.Empty()
+	timetable := types.Timetable{
+		Key:   "timetable1",
+		Tours: []types.Tour{{Events: []types.ArrivalDeparture{{Departure: "25:61"}}}},
+	}
+	handler := timetableHandler{manager: manager}
+	_, err := handler.saveTimetable(mustMarshal(timetable))
+	assert.EqualError(t, err, "tour 0, departure of event 0: the minutes and seconds of time \"25:61\" must be smaller than 60")
+	assert.ErrorAs(t, err, &invalidParamsError{})
+	_, ok := manager.Timetable("timetable1")
+	assert.False(t, ok)
+}
+
 func TestTimetableHandler_DeleteTimetable(t *testing.T) {
 	manager := scenario.Empty()
 	manager.SaveTimetable(scenario.Timetable{
@@ -164,11 +178,11 @@ func TestTimetableHandler_GetTimetable(t *testing.T) {
 			Tours: []scenario.Tour{
 				{
 					IntervalMinutes: 10,
-					LastTour:        "16:30",
+					LastTour:        scenario.MustParseTime("16:30"),
 					Events: []scenario.ArrivalDeparture{
-						{Departure: "8:00"},
-						{Departure: "8:05"},
-						{Arrival: "8:10"},
+						{Departure: scenario.MustParseTime("8:00")},
+						{Departure: scenario.MustParseTime("8:05")},
+						{Arrival: scenario.MustParseTime("8:10")},
 					},
 				},
 			},
@@ -236,11 +250,11 @@ func TestTimetableHandler_SaveTimetableMetadata(t *testing.T) {
 			Tours: []scenario.Tour{
 				{
 					IntervalMinutes: 10,
-					LastTour:        "16:30",
+					LastTour:        scenario.MustParseTime("16:30"),
 					Events: []scenario.ArrivalDeparture{
-						{Departure: "8:00"},
-						{Departure: "8:05"},
-						{Arrival: "8:10"},
+						{Departure: scenario.MustParseTime("8:00")},
+						{Departure: scenario.MustParseTime("8:05")},
+						{Arrival: scenario.MustParseTime("8:10")},
 					},
 				},
 			},
@@ -285,3 +299,296 @@ func TestTimetableHandler_SaveTimetableMetadata(t *testing.T) {
 		}, tt)
 	})
 }
+
+func TestTimetableHandler_ExpandTrips(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveTimetable(scenario.Timetable{
+		Key: "timetable1",
+		Tours: []scenario.Tour{
+			{
+				IntervalMinutes: 30,
+				LastTour:        scenario.MustParseTime("9:00"),
+				Events:          []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("8:00")}, {Arrival: scenario.MustParseTime("8:10")}},
+			},
+			{
+				Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("8:15")}, {Arrival: scenario.MustParseTime("8:25")}},
+			},
+		},
+		StationKeys: []string{"station1", "station2"},
+	})
+	handler := timetableHandler{manager: manager}
+	t.Run("success", func(t *testing.T) {
+		result, err := handler.expandTrips(mustMarshal(types.Timetable{Key: "timetable1"}))
+		require.NoError(t, err)
+		var trips []types.Trip
+		_ = json.Unmarshal(result, &trips)
+		assert.Equal(t, []types.Trip{
+			{TourIndex: 0, Start: "8:00", Events: []types.ArrivalDeparture{{Departure: "8:00"}, {Arrival: "8:10"}}},
+			{TourIndex: 1, Start: "8:15", Events: []types.ArrivalDeparture{{Departure: "8:15"}, {Arrival: "8:25"}}},
+			{TourIndex: 0, Start: "8:30", Events: []types.ArrivalDeparture{{Departure: "8:30"}, {Arrival: "8:40"}}},
+			{TourIndex: 0, Start: "9:00", Events: []types.ArrivalDeparture{{Departure: "9:00"}, {Arrival: "9:10"}}},
+		}, trips)
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := handler.expandTrips(mustMarshal(types.Timetable{Key: "unknown"}))
+		assert.EqualError(t, err, "could not find timetable with key \"unknown\"")
+	})
+}
+
+func TestTimetableHandler_GenerateFromLine(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "station1", Name: "Station 1"})
+	manager.SaveStation(scenario.Station{Key: "station2", Name: "Station 2"})
+	manager.SaveLine(scenario.Line{
+		Key:   "line1",
+		Stops: []string{"station1", "station2"},
+		Path:  []scenario.Waypoint{{Dur: 300, Stop: true}, {Stop: true}},
+	})
+	handler := timetableHandler{manager: manager}
+	t.Run("success", func(t *testing.T) {
+		result, err := handler.generateFromLine(mustMarshal(types.TimetableGenerationRequest{
+			LineKey:         "line1",
+			Name:            "Generated",
+			FirstDeparture:  "6:00",
+			IntervalMinutes: 20,
+			LastDeparture:   "7:00",
+		}))
+		require.NoError(t, err)
+		var got types.Timetable
+		_ = json.Unmarshal(result, &got)
+		assert.NotEmpty(t, got.Key)
+		assert.Equal(t, "Generated", got.Name)
+		assert.Equal(t, []types.Tour{
+			{IntervalMinutes: 20, LastTour: "7:00", Events: []types.ArrivalDeparture{{Departure: "6:00"}, {Arrival: "6:05"}}},
+		}, got.Tours)
+		saved, ok := manager.Timetable(got.Key)
+		require.True(t, ok)
+		assert.Equal(t, []string{"station1", "station2"}, saved.StationKeys)
+	})
+	t.Run("line not found", func(t *testing.T) {
+		_, err := handler.generateFromLine(mustMarshal(types.TimetableGenerationRequest{LineKey: "unknown", FirstDeparture: "6:00"}))
+		assert.EqualError(t, err, "could not find line with key \"unknown\"")
+	})
+	t.Run("invalid first departure", func(t *testing.T) {
+		_, err := handler.generateFromLine(mustMarshal(types.TimetableGenerationRequest{LineKey: "line1", FirstDeparture: "6"}))
+		assert.EqualError(t, err, "first departure: the time \"6\" is not in the format h:mm or h:mm:ss")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+}
+
+func TestTimetableHandler_GetTimetablesForDate(t *testing.T) {
+	manager := scenario.Empty()
+	sunday := scenario.Calendar{Key: "sunday"}
+	_ = sunday.SetWeekdayNames([]string{"sunday"})
+	manager.SaveCalendar(sunday)
+	manager.SaveLine(scenario.Line{Key: "line1", Name: "Line 1"})
+	manager.SaveTimetable(scenario.Timetable{Key: "timetable1", LineKey: "line1", Name: "Sunday", CalendarKey: "sunday"})
+	manager.SaveTimetable(scenario.Timetable{Key: "timetable2", LineKey: "line1", Name: "Working Day", CalendarKey: "unknown"})
+	handler := timetableHandler{manager: manager}
+	t.Run("success", func(t *testing.T) {
+		result, err := handler.getTimetablesForDate(mustMarshal(types.DateRequest{Date: "2026-10-19"}))
+		require.NoError(t, err)
+		var got []types.Timetable
+		_ = json.Unmarshal(result, &got)
+		assert.Equal(t, []types.Timetable{
+			{Key: "timetable2", Name: "Working Day", LineKey: "line1", LineName: "Line 1", CalendarKey: "unknown"},
+		}, got)
+	})
+	t.Run("invalid date", func(t *testing.T) {
+		_, err := handler.getTimetablesForDate(mustMarshal(types.DateRequest{Date: "19.10.2026"}))
+		assert.EqualError(t, err, "the date \"19.10.2026\" is not in the format yyyy-mm-dd")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+}
+
+func TestTimetableHandler_SaveTimetable_UnknownCalendar(t *testing.T) {
+	handler := timetableHandler{manager: scenario.Empty()}
+	_, err := handler.saveTimetable(mustMarshal(types.Timetable{Key: "timetable1", CalendarKey: "unknown"}))
+	assert.EqualError(t, err, "could not find calendar with key \"unknown\"")
+	assert.ErrorAs(t, err, &invalidParamsError{})
+}
+
+func TestTimetableHandler_CheckRunningTimes(t *testing.T) {
+	ratio := func(value float64) *float64 { return &value }
+	manager := scenario.Empty()
+	manager.SaveStation(scenario.Station{Key: "station1", Name: "Station 1"})
+	manager.SaveStation(scenario.Station{Key: "station2", Name: "Station 2"})
+	manager.SaveLine(scenario.Line{
+		Key:   "line1",
+		Name:  "Line 1",
+		Stops: []string{"station1", "station2"},
+		Path:  []scenario.Waypoint{{Dur: 600, Stop: true}, {Stop: true}},
+	})
+	tour := func(arrival string) scenario.Tour {
+		return scenario.Tour{Events: []scenario.ArrivalDeparture{{Departure: scenario.MustParseTime("6:00")}, {Arrival: scenario.MustParseTime(arrival)}}}
+	}
+	manager.SaveTimetable(scenario.Timetable{Key: "tt1", Name: "Tight", LineKey: "line1", StationKeys: []string{"station1", "station2"}, Tours: []scenario.Tour{tour("6:02"), tour("6:10")}})
+	manager.SaveTimetable(scenario.Timetable{Key: "tt2", Name: "Slack", LineKey: "line1", StationKeys: []string{"station1", "station2"}, Tours: []scenario.Tour{tour("6:25")}})
+	handler := timetableHandler{manager: manager}
+	t.Run("all timetables", func(t *testing.T) {
+		result, err := handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{}))
+		require.NoError(t, err)
+		var got []types.RunningTimeReport
+		_ = json.Unmarshal(result, &got)
+		require.Equal(t, 2, len(got))
+		assert.Equal(t, types.RunningTimeReport{
+			TimetableKey:  "tt2",
+			TimetableName: "Slack",
+			LineKey:       "line1",
+			LineName:      "Line 1",
+			Issues: []types.RunningTimeIssue{{
+				FromKey:          "station1",
+				FromName:         "Station 1",
+				ToKey:            "station2",
+				ToName:           "Station 2",
+				Departure:        "6:00",
+				Arrival:          "6:25",
+				ScheduledMinutes: 25,
+				RoutedMinutes:    10,
+				Kind:             "slack",
+			}},
+		}, got[0])
+		assert.Equal(t, "tt1", got[1].TimetableKey)
+		require.Equal(t, 1, len(got[1].Issues))
+		assert.Equal(t, "tight", got[1].Issues[0].Kind)
+	})
+	t.Run("single timetable with custom ratio", func(t *testing.T) {
+		result, err := handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{TimetableKey: "tt2", MaxRatio: ratio(3)}))
+		require.NoError(t, err)
+		var got []types.RunningTimeReport
+		_ = json.Unmarshal(result, &got)
+		assert.Empty(t, got)
+	})
+	t.Run("without tolerance", func(t *testing.T) {
+		tolerance := 0
+		result, err := handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{TimetableKey: "tt2", MaxRatio: ratio(2.45), ToleranceSeconds: &tolerance}))
+		require.NoError(t, err)
+		var got []types.RunningTimeReport
+		_ = json.Unmarshal(result, &got)
+		require.Equal(t, 1, len(got))
+		assert.Equal(t, "slack", got[0].Issues[0].Kind)
+
+		result, err = handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{TimetableKey: "tt2", MaxRatio: ratio(2.45)}))
+		require.NoError(t, err)
+		got = nil
+		_ = json.Unmarshal(result, &got)
+		assert.Empty(t, got)
+	})
+	t.Run("slack check disabled", func(t *testing.T) {
+		result, err := handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{MaxRatio: ratio(0)}))
+		require.NoError(t, err)
+		var got []types.RunningTimeReport
+		_ = json.Unmarshal(result, &got)
+		require.Equal(t, 1, len(got))
+		assert.Equal(t, "tt1", got[0].TimetableKey)
+	})
+	t.Run("timetable not found", func(t *testing.T) {
+		_, err := handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{TimetableKey: "unknown"}))
+		assert.EqualError(t, err, "could not find timetable with key \"unknown\"")
+	})
+	t.Run("invalid ratios", func(t *testing.T) {
+		_, err := handler.checkRunningTimes(mustMarshal(types.RunningTimeRequest{MinRatio: 1.5, MaxRatio: ratio(1.2)}))
+		assert.EqualError(t, err, "the maximal ratio must not be smaller than the minimal ratio")
+		assert.ErrorAs(t, err, &invalidParamsError{})
+	})
+}
+
+func TestTimetableHandler_GenerateTours(t *testing.T) {
+	manager := scenario.Empty()
+	manager.SaveTimetable(scenario.Timetable{Key: "tt1", StationKeys: []string{"station1", "station2"}})
+	handler := timetableHandler{manager: manager}
+	template := []types.ArrivalDeparture{{Departure: "0:00"}, {Arrival: "0:07"}}
+	bands := []types.TimeBand{{Start: "5:00", End: "6:00", HeadwayMinutes: 20}, {Start: "6:00", End: "6:20", HeadwayMinutes: 10}}
+	t.Run("interval tours", func(t *testing.T) {
+		result, err := handler.generateTours(mustMarshal(types.TourGenerationRequest{TimetableKey: "tt1", Template: template, Bands: bands}))
+		require.NoError(t, err)
+		var got []types.Tour
+		_ = json.Unmarshal(result, &got)
+		assert.Equal(t, []types.Tour{
+			{IntervalMinutes: 20, LastTour: "5:40", Events: []types.ArrivalDeparture{{Departure: "5:00"}, {Arrival: "5:07"}}},
+			{IntervalMinutes: 10, LastTour: "6:20", Events: []types.ArrivalDeparture{{Departure: "6:00"}, {Arrival: "6:07"}}},
+		}, got)
+		saved, _ := manager.Timetable("tt1")
+		assert.Empty(t, saved.Tours)
+	})
+	t.Run("explicit tours", func(t *testing.T) {
+		result, err := handler.generateTours(mustMarshal(types.TourGenerationRequest{Template: template, Bands: bands[1:], Mode: "explicit"}))
+		require.NoError(t, err)
+		var got []types.Tour
+		_ = json.Unmarshal(result, &got)
+		assert.Equal(t, []types.Tour{
+			{Events: []types.ArrivalDeparture{{Departure: "6:00"}, {Arrival: "6:07"}}},
+			{Events: []types.ArrivalDeparture{{Departure: "6:10"}, {Arrival: "6:17"}}},
+			{Events: []types.ArrivalDeparture{{Departure: "6:20"}, {Arrival: "6:27"}}},
+		}, got)
+	})
+	t.Run("timetable not found", func(t *testing.T) {
+		_, err := handler.generateTours(mustMarshal(types.TourGenerationRequest{TimetableKey: "unknown", Template: template, Bands: bands}))
+		assert.EqualError(t, err, "could not find timetable with key \"unknown\"")
+	})
+	t.Run("invalid params", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			request types.TourGenerationRequest
+			err     string
+		}{
+			"mode":     {types.TourGenerationRequest{Template: template, Bands: bands, Mode: "daily"}, "the mode \"daily\" is unknown, use \"interval\" or \"explicit\""},
+			"stations": {types.TourGenerationRequest{TimetableKey: "tt1", Template: template[:1], Bands: bands}, "the template has 1 events, but the timetable has 2 stations"},
+			"template": {types.TourGenerationRequest{Template: []types.ArrivalDeparture{{Departure: "0"}}, Bands: bands}, "template, departure of event 0: the time \"0\" is not in the format h:mm or h:mm:ss"},
+			"band":     {types.TourGenerationRequest{Template: template, Bands: []types.TimeBand{{Start: "5:00"}}}, "end of band 0: the time \"\" is not in the format h:mm or h:mm:ss"},
+			"headway":  {types.TourGenerationRequest{Template: template, Bands: []types.TimeBand{{Start: "5:00", End: "6:00"}}}, "the headway of band 0 must be positive"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := handler.generateTours(mustMarshal(test.request))
+				assert.EqualError(t, err, test.err)
+				assert.ErrorAs(t, err, &invalidParamsError{})
+			})
+		}
+	})
+}
+
+func TestTimetableHandler_CompactTours(t *testing.T) {
+	manager := scenario.Empty()
+	tours := make([]scenario.Tour, 0)
+	for _, start := range []string{"6:00", "6:30", "7:00", "9:15"} {
+		departure := scenario.MustParseTime(start)
+		tours = append(tours, scenario.Tour{Events: []scenario.ArrivalDeparture{{Departure: departure}, {Arrival: departure.Add(600)}}})
+	}
+	manager.SaveTimetable(scenario.Timetable{Key: "tt1", Tours: tours})
+	handler := timetableHandler{manager: manager}
+	expected := types.CompactionResult{
+		ToursBefore: 4,
+		ToursAfter:  2,
+		Compacted: []types.CompactedTour{{
+			Tour:          types.Tour{IntervalMinutes: 30, LastTour: "7:00", Events: []types.ArrivalDeparture{{Departure: "6:00"}, {Arrival: "6:10"}}},
+			ReplacedTours: []int{0, 1, 2},
+		}},
+		Tours: []types.Tour{
+			{IntervalMinutes: 30, LastTour: "7:00", Events: []types.ArrivalDeparture{{Departure: "6:00"}, {Arrival: "6:10"}}},
+			{Events: []types.ArrivalDeparture{{Departure: "9:15"}, {Arrival: "9:25"}}},
+		},
+	}
+	t.Run("preview", func(t *testing.T) {
+		result, err := handler.compactTours(mustMarshal(types.CompactionRequest{Key: "tt1"}))
+		require.NoError(t, err)
+		var got types.CompactionResult
+		_ = json.Unmarshal(result, &got)
+		assert.Equal(t, expected, got)
+		saved, _ := manager.Timetable("tt1")
+		assert.Equal(t, 4, len(saved.Tours))
+	})
+	t.Run("apply", func(t *testing.T) {
+		result, err := handler.compactTours(mustMarshal(types.CompactionRequest{Key: "tt1", Apply: true}))
+		require.NoError(t, err)
+		var got types.CompactionResult
+		_ = json.Unmarshal(result, &got)
+		expected.Applied = true
+		assert.Equal(t, expected, got)
+		saved, _ := manager.Timetable("tt1")
+		assert.Equal(t, 2, len(saved.Tours))
+		assert.Equal(t, 30, saved.Tours[0].IntervalMinutes)
+	})
+	t.Run("timetable not found", func(t *testing.T) {
+		_, err := handler.compactTours(mustMarshal(types.CompactionRequest{Key: "unknown"}))
+		assert.EqualError(t, err, "could not find timetable with key \"unknown\"")
+	})
+}
@@ -26,6 +26,8 @@ type Waypoint struct {
 	Dist *float64 `json:"dist,omitempty"`
 	Dur  *float64 `json:"dur,omitempty"`
 	Stop bool     `json:"stop,omitempty"`
+	// Manual marks waypoints of path segments between two stops that were drawn by hand. They are not rerouted.
+	Manual bool `json:"manual,omitempty"`
 }
 
 type LineIdentifier struct {
@@ -40,24 +42,49 @@ type Line struct {
 	Key      string     `json:"key"`
 	Name     string     `json:"name"`
 	Color    string     `json:"color"`
+	// Mode is the transport mode, e.g. "bus" (default), "tram", "rail" or "ferry".
+	Mode string `json:"mode,omitempty"`
+	// TimetablePolicy is only used when saving a line, AffectedTimetables is only set in the response.
+	TimetablePolicy    string              `json:"timetablePolicy,omitempty"`
+	AffectedTimetables []AffectedTimetable `json:"affectedTimetables,omitempty"`
+}
+
+type AffectedTimetable struct {
+	Key             string   `json:"key"`
+	Name            string   `json:"name"`
+	AddedStations   []string `json:"addedStations"`
+	RemovedStations []string `json:"removedStations"`
+	Migrated        bool     `json:"migrated"`
+	Error           string   `json:"error,omitempty"`
 }
 
 type AddressResponse struct {
 	Name string `json:"name"`
 }
 
+type RouteCacheStats struct {
+	Enabled    bool  `json:"enabled"`
+	Hits       int   `json:"hits"`
+	Misses     int   `json:"misses"`
+	Entries    int   `json:"entries"`
+	Size       int64 `json:"size"`
+	MaxEntries int   `json:"maxEntries"`
+	MaxSize    int64 `json:"maxSize"`
+}
+
 type StationUpdate struct {
 	ChangedOrAdded []Station `json:"changedOrAdded"`
 	Deleted        []string  `json:"deleted"`
 }
 
 type Timetable struct {
-	Key      string    `json:"key"`
-	Name     string    `json:"name"`
-	LineKey  string    `json:"lineKey,omitempty"`
-	LineName string    `json:"lineName,omitempty"`
-	Tours    []Tour    `json:"tours"`
-	Stations []Station `json:"stations"`
+	Key         string    `json:"key"`
+	Name        string    `json:"name"`
+	LineKey     string    `json:"lineKey,omitempty"`
+	LineName    string    `json:"lineName,omitempty"`
+	CalendarKey string    `json:"calendarKey,omitempty"`
+	Tours       []Tour    `json:"tours"`
+	Stations    []Station `json:"stations"`
 }
 
 type Tour struct {
@@ -71,25 +98,309 @@ type ArrivalDeparture struct {
 	Departure string `json:"departure,omitempty"`
 }
 
+type Trip struct {
+	TourIndex int                `json:"tourIndex"`
+	Start     string             `json:"start"`
+	Events    []ArrivalDeparture `json:"events"`
+}
+
+type Calendar struct {
+	Key          string   `json:"key"`
+	Name         string   `json:"name"`
+	Weekdays     []string `json:"weekdays"`
+	StartDate    string   `json:"startDate,omitempty"`
+	EndDate      string   `json:"endDate,omitempty"`
+	AddedDates   []string `json:"addedDates,omitempty"`
+	RemovedDates []string `json:"removedDates,omitempty"`
+}
+
+type DateRequest struct {
+	Date string `json:"date"`
+}
+
+type DepartureRequest struct {
+	StationKey string `json:"stationKey"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Date       string `json:"date,omitempty"`
+}
+
+type Departure struct {
+	Time            string `json:"time"`
+	LineKey         string `json:"lineKey"`
+	LineName        string `json:"lineName"`
+	LineColor       string `json:"lineColor"`
+	TimetableKey    string `json:"timetableKey"`
+	TimetableName   string `json:"timetableName"`
+	TourIndex       int    `json:"tourIndex"`
+	DestinationKey  string `json:"destinationKey"`
+	DestinationName string `json:"destinationName"`
+}
+
+type TransferRequest struct {
+	MinTransferMinutes int    `json:"minTransferMinutes"`
+	MaxWaitMinutes     int    `json:"maxWaitMinutes"`
+	Date               string `json:"date,omitempty"`
+}
+
+type StationTransfers struct {
+	StationKey         string          `json:"stationKey"`
+	StationName        string          `json:"stationName"`
+	Score              float64         `json:"score"`
+	Arrivals           int             `json:"arrivals"`
+	Connections        int             `json:"connections"`
+	MissedConnections  int             `json:"missedConnections"`
+	AverageWaitMinutes float64         `json:"averageWaitMinutes"`
+	Pairs              []LineTransfers `json:"pairs"`
+}
+
+type LineTransfers struct {
+	FromLineKey        string             `json:"fromLineKey"`
+	FromLineName       string             `json:"fromLineName"`
+	ToLineKey          string             `json:"toLineKey"`
+	ToLineName         string             `json:"toLineName"`
+	Arrivals           int                `json:"arrivals"`
+	Connections        int                `json:"connections"`
+	AverageWaitMinutes float64            `json:"averageWaitMinutes"`
+	MaxWaitMinutes     float64            `json:"maxWaitMinutes"`
+	MissedConnections  []MissedConnection `json:"missedConnections"`
+}
+
+type MissedConnection struct {
+	Arrival   string `json:"arrival"`
+	Departure string `json:"departure"`
+}
+
+type JourneyRequest struct {
+	OriginKey          string  `json:"originKey"`
+	DestinationKey     string  `json:"destinationKey"`
+	Departure          string  `json:"departure"`
+	Date               string  `json:"date,omitempty"`
+	MinTransferMinutes int     `json:"minTransferMinutes"`
+	MaxWalkMeters      float64 `json:"maxWalkMeters"`
+	Count              int     `json:"count"`
+}
+
+type Journey struct {
+	Departure       string  `json:"departure"`
+	Arrival         string  `json:"arrival"`
+	DurationMinutes float64 `json:"durationMinutes"`
+	Transfers       int     `json:"transfers"`
+	Legs            []Leg   `json:"legs"`
+}
+
+type Leg struct {
+	Type         string `json:"type"`
+	LineKey      string `json:"lineKey,omitempty"`
+	LineName     string `json:"lineName,omitempty"`
+	LineColor    string `json:"lineColor,omitempty"`
+	TimetableKey string `json:"timetableKey,omitempty"`
+	TourIndex    int    `json:"tourIndex"`
+	FromKey      string `json:"fromKey"`
+	FromName     string `json:"fromName"`
+	ToKey        string `json:"toKey"`
+	ToName       string `json:"toName"`
+	Departure    string `json:"departure"`
+	Arrival      string `json:"arrival"`
+}
+
+type TimetableGenerationRequest struct {
+	LineKey         string `json:"lineKey"`
+	Name            string `json:"name"`
+	DwellSeconds    int    `json:"dwellSeconds"`
+	FirstDeparture  string `json:"firstDeparture"`
+	IntervalMinutes int    `json:"intervalMinutes"`
+	LastDeparture   string `json:"lastDeparture"`
+}
+
+type TourGenerationRequest struct {
+	// TimetableKey is optional. If given, the template must have one event per station of the timetable.
+	TimetableKey string             `json:"timetableKey,omitempty"`
+	Template     []ArrivalDeparture `json:"template"`
+	Bands        []TimeBand         `json:"bands"`
+	// Mode is "interval" or "explicit".
+	Mode string `json:"mode,omitempty"`
+}
+
+type TimeBand struct {
+	Start          string `json:"start"`
+	End            string `json:"end"`
+	HeadwayMinutes int    `json:"headwayMinutes"`
+}
+
+type CompactionRequest struct {
+	Key string `json:"key"`
+	// Apply saves the compacted tours. Otherwise, the result is only a preview.
+	Apply bool `json:"apply"`
+}
+
+type CompactionResult struct {
+	ToursBefore int             `json:"toursBefore"`
+	ToursAfter  int             `json:"toursAfter"`
+	Applied     bool            `json:"applied"`
+	Compacted   []CompactedTour `json:"compacted"`
+	Tours       []Tour          `json:"tours"`
+}
+
+type CompactedTour struct {
+	Tour Tour `json:"tour"`
+	// ReplacedTours contains the indices of the replaced tours in chronological order.
+	ReplacedTours []int `json:"replacedTours"`
+}
+
+type RunningTimeRequest struct {
+	TimetableKey string  `json:"timetableKey,omitempty"`
+	MinRatio     float64 `json:"minRatio"`
+	// MaxRatio defaults to 2 if it is missing, 0 disables the slack check.
+	MaxRatio *float64 `json:"maxRatio,omitempty"`
+	// ToleranceSeconds defaults to 60 if it is missing.
+	ToleranceSeconds *int `json:"toleranceSeconds,omitempty"`
+}
+
+type RunningTimeReport struct {
+	TimetableKey  string             `json:"timetableKey"`
+	TimetableName string             `json:"timetableName"`
+	LineKey       string             `json:"lineKey"`
+	LineName      string             `json:"lineName"`
+	Error         string             `json:"error,omitempty"`
+	Issues        []RunningTimeIssue `json:"issues"`
+}
+
+type RunningTimeIssue struct {
+	TourIndex        int     `json:"tourIndex"`
+	FromKey          string  `json:"fromKey"`
+	FromName         string  `json:"fromName"`
+	ToKey            string  `json:"toKey"`
+	ToName           string  `json:"toName"`
+	Departure        string  `json:"departure"`
+	Arrival          string  `json:"arrival"`
+	ScheduledMinutes float64 `json:"scheduledMinutes"`
+	RoutedMinutes    float64 `json:"routedMinutes"`
+	// Kind is "tight" or "slack".
+	Kind string `json:"kind"`
+}
+
 type DetourRequest struct {
 	Stations []Station  `json:"stations"`
 	Path     []Waypoint `json:"path"`
 	Cap      int        `json:"cap"`
+	// Metric is "distance" or "duration" and selects the relative detour that ranks the detours.
+	Metric string `json:"metric,omitempty"`
+	// Mode is the transport mode of the line that selects the router of the direct routes, bus by default.
+	Mode string `json:"mode,omitempty"`
 }
 
 type DetourResponse struct {
-	EmptyResult    bool    `json:"emptyResult"`
-	AverageDetour  float64 `json:"averageDetour"`
-	BiggestDetour  Detour  `json:"biggestDetour"`
-	MedianDetour   Detour  `json:"medianDetour"`
-	SmallestDetour Detour  `json:"smallestDetour"`
+	EmptyResult           bool    `json:"emptyResult"`
+	Metric                string  `json:"metric"`
+	AverageDetour         float64 `json:"averageDetour"`
+	AverageDurationDetour float64 `json:"averageDurationDetour"`
+	BiggestDetour         Detour  `json:"biggestDetour"`
+	MedianDetour          Detour  `json:"medianDetour"`
+	SmallestDetour        Detour  `json:"smallestDetour"`
+	// DemandDetour weights the detours with the imported demand between the stations.
+	DemandDetour DemandDetour `json:"demandDetour"`
+}
+
+type DemandDetour struct {
+	Trips                         float64 `json:"trips"`
+	WeightedAverageDetour         float64 `json:"weightedAverageDetour"`
+	WeightedAverageDurationDetour float64 `json:"weightedAverageDurationDetour"`
+	PassengerMinutesLost          float64 `json:"passengerMinutesLost"`
+}
+
+type LineDemandDetour struct {
+	LineKey  string `json:"lineKey"`
+	LineName string `json:"lineName"`
+	DemandDetour
 }
 
 type Detour struct {
 	Absolute float64 `json:"absolute"`
 	Relative float64 `json:"relative"`
-	Source   int     `json:"source"`
-	Target   int     `json:"target"`
+	// AbsoluteDuration and RelativeDuration compare the durations instead of the distances.
+	AbsoluteDuration float64 `json:"absoluteDuration"`
+	RelativeDuration float64 `json:"relativeDuration"`
+	Source           int     `json:"source"`
+	Target           int     `json:"target"`
+}
+
+type Demand struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Trips       float64 `json:"trips"`
+}
+
+type DemandImportRequest struct {
+	// Csv contains the lines origin station key, destination station key and trips, optionally after a header.
+	Csv string `json:"csv"`
+}
+
+type DetourMatrixRequest struct {
+	DetourRequest
+	HistogramBinWidth float64 `json:"histogramBinWidth,omitempty"`
+}
+
+type DetourMatrixResponse struct {
+	Metric      string             `json:"metric"`
+	Detours     []Detour           `json:"detours"`
+	Percentiles []DetourPercentile `json:"percentiles"`
+	Histogram   []HistogramBin     `json:"histogram"`
+}
+
+type DetourSegmentsResponse struct {
+	Metric   string          `json:"metric"`
+	Segments []DetourSegment `json:"segments"`
+}
+
+type DetourSegment struct {
+	// Source and Target are the indices of the stations at the ends of the segment.
+	Source int `json:"source"`
+	Target int `json:"target"`
+	// FromWaypoint and ToWaypoint are the indices of the waypoints of the path at the ends of the segment.
+	FromWaypoint     int     `json:"fromWaypoint"`
+	ToWaypoint       int     `json:"toWaypoint"`
+	Pairs            int     `json:"pairs"`
+	AttributedDetour float64 `json:"attributedDetour"`
+	Score            float64 `json:"score"`
+}
+
+type StopOrderRequest struct {
+	LineKey string `json:"lineKey"`
+	// FixedTerminals keeps the first and the last station in place.
+	FixedTerminals bool `json:"fixedTerminals,omitempty"`
+	// RequiredStations contains the keys of waypoint stations that must not be dropped. Stops are never dropped.
+	RequiredStations []string `json:"requiredStations,omitempty"`
+	Metric           string   `json:"metric,omitempty"`
+	Candidates       int      `json:"candidates,omitempty"`
+}
+
+type StopOrderResponse struct {
+	Metric     string               `json:"metric"`
+	Current    StopOrderCandidate   `json:"current"`
+	Candidates []StopOrderCandidate `json:"candidates"`
+}
+
+type StopOrderCandidate struct {
+	Stations        []string `json:"stations"`
+	DroppedStations []string `json:"droppedStations"`
+	// AverageDetour is the average relative detour of the requested metric.
+	AverageDetour         float64 `json:"averageDetour"`
+	AverageDistanceDetour float64 `json:"averageDistanceDetour"`
+	AverageDurationDetour float64 `json:"averageDurationDetour"`
+	Length                float64 `json:"length"`
+	Duration              float64 `json:"duration"`
+}
+
+type DetourPercentile struct {
+	Percentile int     `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
 }
 
 type Vehicle struct {
@@ -0,0 +1,215 @@
+package scenario
+
+import (
+	"fmt"
+	gonanoid "github.com/matoous/go-nanoid"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateFormat is the format of dates in persistence and the RPC interface.
+const DateFormat = "2006-01-02"
+
+// Calendar defines the days on which the timetables assigned to it are operated.
+type Calendar struct {
+	Key  string
+	Name string
+	// Weekdays is indexed by time.Weekday, i.e. it starts with Sunday.
+	Weekdays [7]bool
+	// StartDate and EndDate limit the validity of the calendar including both dates. A zero date is unbounded.
+	StartDate time.Time
+	EndDate   time.Time
+	// AddedDates are operated regardless of weekday and validity, RemovedDates are never operated.
+	AddedDates   []time.Time
+	RemovedDates []time.Time
+}
+
+// ParseDate parses a date in the format yyyy-mm-dd.
+func ParseDate(text string) (time.Time, error) {
+	date, err := time.Parse(DateFormat, strings.TrimSpace(text))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the date \"%s\" is not in the format yyyy-mm-dd", text)
+	}
+	return date, nil
+}
+
+// ParseOptionalDate works like ParseDate, but returns the zero date for the empty string.
+func ParseOptionalDate(text string) (time.Time, error) {
+	if strings.TrimSpace(text) == "" {
+		return time.Time{}, nil
+	}
+	return ParseDate(text)
+}
+
+// FormatOptionalDate formats the date as yyyy-mm-dd, or as empty string for the zero date.
+func FormatOptionalDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(DateFormat)
+}
+
+// ParseDates parses all dates in the format yyyy-mm-dd.
+func ParseDates(texts []string) ([]time.Time, error) {
+	result := make([]time.Time, 0, len(texts))
+	for _, text := range texts {
+		date, err := ParseDate(text)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, date)
+	}
+	return result, nil
+}
+
+// FormatDates formats all dates as yyyy-mm-dd.
+func FormatDates(dates []time.Time) []string {
+	result := make([]string, 0, len(dates))
+	for _, date := range dates {
+		result = append(result, date.Format(DateFormat))
+	}
+	return result
+}
+
+// ParseWeekday parses the english name of a weekday, e.g. "monday". The case is ignored.
+func ParseWeekday(text string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), strings.TrimSpace(text)) {
+			return weekday, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("there is no weekday \"%s\", use one of \"monday\" to \"sunday\"", text)
+}
+
+// WeekdayNames returns the lowercase names of the weekdays of the calendar starting with Monday.
+func (c *Calendar) WeekdayNames() []string {
+	result := make([]string, 0, 7)
+	for _, weekday := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if c.Weekdays[weekday] {
+			result = append(result, strings.ToLower(weekday.String()))
+		}
+	}
+	return result
+}
+
+// SetWeekdayNames replaces the weekdays of the calendar by the given names, see ParseWeekday.
+func (c *Calendar) SetWeekdayNames(names []string) error {
+	c.Weekdays = [7]bool{}
+	for _, name := range names {
+		weekday, err := ParseWeekday(name)
+		if err != nil {
+			return err
+		}
+		c.Weekdays[weekday] = true
+	}
+	return nil
+}
+
+// Validate checks that the validity range of the calendar is not reversed.
+func (c *Calendar) Validate() error {
+	if !c.StartDate.IsZero() && !c.EndDate.IsZero() && c.EndDate.Before(c.StartDate) {
+		return fmt.Errorf("the end date %s must not be before the start date %s", c.EndDate.Format(DateFormat), c.StartDate.Format(DateFormat))
+	}
+	return nil
+}
+
+// IsActive returns true if the calendar is operated on the given date. Only the date part is considered.
+func (c *Calendar) IsActive(date time.Time) bool {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if containsDate(c.RemovedDates, date) {
+		return false
+	}
+	if containsDate(c.AddedDates, date) {
+		return true
+	}
+	if !c.StartDate.IsZero() && date.Before(c.StartDate) {
+		return false
+	}
+	if !c.EndDate.IsZero() && date.After(c.EndDate) {
+		return false
+	}
+	return c.Weekdays[date.Weekday()]
+}
+
+func containsDate(dates []time.Time, date time.Time) bool {
+	for _, current := range dates {
+		if current.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// Calendar returns the calendar of the timetable. It returns false if the timetable has no calendar.
+func (t *Timetable) Calendar() (Calendar, bool) {
+	calendar, ok := t.manager.calendars[t.CalendarKey]
+	return calendar, ok
+}
+
+// IsActive returns true if the timetable is operated on the given date. Timetables without
+// calendar, or with a calendar that does not exist, are considered to be operated every day.
+func (t *Timetable) IsActive(date time.Time) bool {
+	calendar, ok := t.Calendar()
+	if !ok {
+		return true
+	}
+	return calendar.IsActive(date)
+}
+
+// ActiveTimetables returns all timetables that are operated on the given date.
+func (m *Manager) ActiveTimetables(date time.Time) []Timetable {
+	result := make([]Timetable, 0)
+	for _, timetable := range m.Timetables() {
+		if timetable.IsActive(date) {
+			result = append(result, timetable)
+		}
+	}
+	return result
+}
+
+func (m *Manager) Calendars() []Calendar {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	result := make([]Calendar, 0, len(m.calendars))
+	for _, calendar := range m.calendars {
+		result = append(result, calendar)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func (m *Manager) Calendar(key string) (Calendar, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	calendar, ok := m.calendars[key]
+	return calendar, ok
+}
+
+func (m *Manager) SaveCalendar(calendar Calendar) Calendar {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if calendar.Key == "" {
+		calendar.Key = gonanoid.MustID(10)
+	}
+	m.calendars[calendar.Key] = calendar
+	return calendar
+}
+
+// DeleteCalendar deletes the calendar and removes it from all timetables that use it.
+func (m *Manager) DeleteCalendar(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.calendars, key)
+	for timetableKey, timetable := range m.timetables {
+		if timetable.CalendarKey == key {
+			timetable.CalendarKey = ""
+			m.timetables[timetableKey] = timetable
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package scenario
+
+import (
+	"backend/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func date(text string) time.Time {
+	result, err := ParseDate(text)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestCalendar_IsActive(t *testing.T) {
+	calendar := Calendar{
+		StartDate:    date("2026-01-01"),
+		EndDate:      date("2026-12-31"),
+		AddedDates:   []time.Time{date("2026-12-26")},
+		RemovedDates: []time.Time{date("2026-12-24")},
+	}
+	_ = calendar.SetWeekdayNames([]string{"monday", "tuesday", "wednesday", "thursday", "Friday"})
+	tests := []struct {
+		date   string
+		active bool
+	}{
+		{"2026-10-19", true},
+		{"2026-10-18", false},
+		{"2026-12-24", false},
+		{"2026-12-26", true},
+		{"2025-12-31", false},
+		{"2027-01-01", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			assert.Equal(t, tt.active, calendar.IsActive(date(tt.date)))
+		})
+	}
+	t.Run("time of day is ignored", func(t *testing.T) {
+		assert.True(t, calendar.IsActive(time.Date(2026, 10, 19, 23, 59, 0, 0, time.Local)))
+	})
+}
+
+func TestCalendar_Validate(t *testing.T) {
+	calendar := Calendar{StartDate: date("2026-02-01"), EndDate: date("2026-01-01")}
+	assert.EqualError(t, calendar.Validate(), "the end date 2026-01-01 must not be before the start date 2026-02-01")
+}
+
+func TestParseWeekday(t *testing.T) {
+	weekday, err := ParseWeekday("Sunday")
+	require.NoError(t, err)
+	assert.Equal(t, time.Sunday, weekday)
+	_, err = ParseWeekday("someday")
+	assert.EqualError(t, err, "there is no weekday \"someday\", use one of \"monday\" to \"sunday\"")
+}
+
+func TestManager_ActiveTimetables(t *testing.T) {
+	manager := Empty()
+	weekend := Calendar{Key: "weekend"}
+	weekend.Weekdays[time.Saturday] = true
+	weekend.Weekdays[time.Sunday] = true
+	manager.SaveCalendar(weekend)
+	manager.SaveTimetable(Timetable{Key: "saturday", Name: "Saturday", CalendarKey: "weekend"})
+	manager.SaveTimetable(Timetable{Key: "always", Name: "Always"})
+
+	active := manager.ActiveTimetables(date("2026-10-18"))
+	assert.Equal(t, 2, len(active))
+	active = manager.ActiveTimetables(date("2026-10-19"))
+	require.Equal(t, 1, len(active))
+	assert.Equal(t, "always", active[0].Key)
+}
+
+func TestManager_DeleteCalendar(t *testing.T) {
+	manager := Empty()
+	manager.SaveCalendar(Calendar{Key: "c1"})
+	manager.SaveTimetable(Timetable{Key: "tt1", CalendarKey: "c1"})
+	manager.DeleteCalendar("c1")
+	_, ok := manager.Calendar("c1")
+	assert.False(t, ok)
+	timetable, _ := manager.Timetable("tt1")
+	assert.Equal(t, "", timetable.CalendarKey)
+}
+
+func TestCalendar_Persistence(t *testing.T) {
+	persisted := persistence.Calendar{
+		Key:          "c1",
+		Name:         "Working Day",
+		Weekdays:     []string{"monday", "friday"},
+		StartDate:    "2026-01-01",
+		AddedDates:   []string{"2026-12-26"},
+		RemovedDates: []string{},
+	}
+	converted, err := convertCalendarFromPersistence(persisted)
+	require.NoError(t, err)
+	assert.True(t, converted.Weekdays[time.Monday])
+	assert.True(t, converted.EndDate.IsZero())
+	assert.Equal(t, persisted, convertCalendarToPersistence(converted))
+
+	_, err = convertCalendarFromPersistence(persistence.Calendar{AddedDates: []string{"26.12.2026"}})
+	assert.EqualError(t, err, "added dates: the date \"26.12.2026\" is not in the format yyyy-mm-dd")
+
+	dir, _ := os.MkdirTemp(os.TempDir(), "*")
+	defer func() { _ = os.RemoveAll(dir) }()
+	manager, err := LoadScenario(dir)
+	require.NoError(t, err)
+	manager.SaveCalendar(converted)
+	manager.SaveTimetable(Timetable{Key: "tt1", CalendarKey: "c1"})
+	require.NoError(t, manager.Persist())
+	reloaded, err := LoadScenario(dir)
+	require.NoError(t, err)
+	calendar, ok := reloaded.Calendar("c1")
+	require.True(t, ok)
+	assert.Equal(t, converted, calendar)
+	timetable, _ := reloaded.Timetable("tt1")
+	assert.Equal(t, "c1", timetable.CalendarKey)
+}
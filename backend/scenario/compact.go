@@ -0,0 +1,109 @@
+package scenario
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// minCompactedTours is the number of regularly spaced tours that is at least folded into a tour with interval.
+const minCompactedTours = 3
+
+// CompactedTour is a tour with interval that replaces several explicit tours of a timetable.
+type CompactedTour struct {
+	Tour Tour
+	// Sources contains the indices of the replaced tours in the original timetable in chronological order.
+	Sources []int
+}
+
+// Compact detects explicit tours that have the same times relative to their start and depart at a regular
+// interval of whole minutes, and folds each run of at least three such tours into one tour with interval and
+// last tour. Tours that already have an interval are kept as they are. The compacted tour takes the place of
+// its earliest source, all other tours keep their order. The timetable is not saved, so the result can be
+// shown as a preview before it is applied.
+func (t *Timetable) Compact() (Timetable, []CompactedTour) {
+	groups := make(map[string][]int)
+	signatures := make([]string, 0)
+	for index, tour := range t.Tours {
+		if tour.IntervalMinutes > 0 || !tour.Start().IsSet() {
+			continue
+		}
+		signature := tourSignature(tour)
+		if _, ok := groups[signature]; !ok {
+			signatures = append(signatures, signature)
+		}
+		groups[signature] = append(groups[signature], index)
+	}
+	compacted := make([]CompactedTour, 0)
+	for _, signature := range signatures {
+		compacted = append(compacted, t.compactGroup(groups[signature])...)
+	}
+	sort.Slice(compacted, func(i, j int) bool {
+		return compacted[i].Sources[0] < compacted[j].Sources[0]
+	})
+	replacements := make(map[int]int)
+	for index, tour := range compacted {
+		for _, source := range tour.Sources {
+			replacements[source] = -1
+		}
+		replacements[tour.Sources[0]] = index
+	}
+	result := *t
+	result.Tours = make([]Tour, 0, len(t.Tours))
+	for index, tour := range t.Tours {
+		replacement, ok := replacements[index]
+		if !ok {
+			result.Tours = append(result.Tours, tour)
+		} else if replacement >= 0 {
+			result.Tours = append(result.Tours, compacted[replacement].Tour)
+		}
+	}
+	return result, compacted
+}
+
+// compactGroup finds runs of regularly spaced tours among the given tours, which have the same signature.
+func (t *Timetable) compactGroup(indices []int) []CompactedTour {
+	sort.SliceStable(indices, func(i, j int) bool {
+		return t.Tours[indices[i]].Start().Before(t.Tours[indices[j]].Start())
+	})
+	result := make([]CompactedTour, 0)
+	for first := 0; first+minCompactedTours <= len(indices); {
+		interval := t.Tours[indices[first+1]].Start().Sub(t.Tours[indices[first]].Start())
+		last := first + 1
+		for last+1 < len(indices) && t.Tours[indices[last+1]].Start().Sub(t.Tours[indices[last]].Start()) == interval {
+			last++
+		}
+		if interval <= 0 || interval%60 != 0 || last-first+1 < minCompactedTours {
+			first++
+			continue
+		}
+		tour := t.Tours[indices[first]]
+		result = append(result, CompactedTour{
+			Tour: Tour{
+				IntervalMinutes: interval / 60,
+				LastTour:        t.Tours[indices[last]].Start(),
+				Events:          tour.Events,
+			},
+			Sources: append([]int{}, indices[first:last+1]...),
+		})
+		first = last + 1
+	}
+	return result
+}
+
+// tourSignature describes the times of the tour relative to its start. Tours with the same signature only
+// differ in their start.
+func tourSignature(tour Tour) string {
+	start := tour.Start()
+	var builder strings.Builder
+	for _, event := range tour.normalizedEvents() {
+		for _, current := range []Time{event.Arrival, event.Departure} {
+			if current.IsSet() {
+				builder.WriteString(fmt.Sprintf("%d,", current.Sub(start)))
+			} else {
+				builder.WriteString("-,")
+			}
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,71 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTimetable_Compact(t *testing.T) {
+	tour := func(departure string, arrival string) Tour {
+		return Tour{Events: []ArrivalDeparture{{Departure: MustParseTime(departure)}, {}, {Arrival: MustParseTime(arrival)}}}
+	}
+	interval := Tour{IntervalMinutes: 60, LastTour: MustParseTime("12:00"), Events: tour("10:00", "10:10").Events}
+	timetable := Timetable{
+		Key: "tt1",
+		Tours: []Tour{
+			tour("6:40", "6:50"),
+			tour("6:00", "6:10"),
+			tour("6:30", "6:45"),
+			tour("6:20", "6:30"),
+			interval,
+			tour("7:00", "7:10"),
+			tour("8:00", "8:10"),
+			tour("8:30", "8:40"),
+			tour("23:30", "23:40"),
+			tour("24:00", "24:10"),
+			tour("23:45", "23:55"),
+		},
+	}
+
+	compacted, groups := timetable.Compact()
+	require.Equal(t, 2, len(groups))
+	assert.Equal(t, CompactedTour{
+		Tour:    Tour{IntervalMinutes: 20, LastTour: MustParseTime("7:00"), Events: tour("6:00", "6:10").Events},
+		Sources: []int{1, 3, 0, 5},
+	}, groups[0])
+	assert.Equal(t, CompactedTour{
+		Tour:    Tour{IntervalMinutes: 15, LastTour: MustParseTime("24:00"), Events: tour("23:30", "23:40").Events},
+		Sources: []int{8, 10, 9},
+	}, groups[1])
+	assert.Equal(t, []Tour{
+		groups[0].Tour,
+		tour("6:30", "6:45"),
+		interval,
+		tour("8:00", "8:10"),
+		tour("8:30", "8:40"),
+		groups[1].Tour,
+	}, compacted.Tours)
+	assert.Equal(t, 11, len(timetable.Tours))
+
+	starts := func(timetable Timetable) []Time {
+		result := make([]Time, 0)
+		for _, trip := range timetable.Trips() {
+			result = append(result, trip.Start)
+		}
+		return result
+	}
+	assert.Equal(t, starts(timetable), starts(compacted))
+}
+
+func TestTimetable_Compact_Nothing(t *testing.T) {
+	timetable := Timetable{Tours: []Tour{
+		{Events: []ArrivalDeparture{{Departure: MustParseTime("6:00")}, {Arrival: MustParseTime("6:10")}}},
+		{Events: []ArrivalDeparture{{Departure: MustParseTime("6:00")}, {Arrival: MustParseTime("6:10")}}},
+		{Events: []ArrivalDeparture{{Departure: MustParseTime("6:00")}, {Arrival: MustParseTime("6:10")}}},
+		{Events: []ArrivalDeparture{{}, {}}},
+	}}
+	compacted, groups := timetable.Compact()
+	assert.Empty(t, groups)
+	assert.Equal(t, timetable.Tours, compacted.Tours)
+}
@@ -0,0 +1,101 @@
+package scenario
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Demand is the number of passenger trips from one station to another, an entry of an origin–destination matrix.
+type Demand struct {
+	Origin      string
+	Destination string
+	Trips       float64
+}
+
+type demandPair struct {
+	origin      string
+	destination string
+}
+
+// Demand returns the origin–destination matrix sorted by origin and destination.
+func (m *Manager) Demand() []Demand {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.sortedDemand()
+}
+
+// sortedDemand returns the origin–destination matrix sorted by origin and destination. The mutex must be held.
+func (m *Manager) sortedDemand() []Demand {
+	result := make([]Demand, 0, len(m.demand))
+	for pair, trips := range m.demand {
+		result = append(result, Demand{Origin: pair.origin, Destination: pair.destination, Trips: trips})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Origin != result[j].Origin {
+			return result[i].Origin < result[j].Origin
+		}
+		return result[i].Destination < result[j].Destination
+	})
+	return result
+}
+
+// Trips returns the number of trips from the origin to the destination, 0 if there is no demand.
+func (m *Manager) Trips(origin string, destination string) float64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.demand[demandPair{origin: origin, destination: destination}]
+}
+
+// SetDemand replaces the origin–destination matrix. Trips of duplicate pairs are summed up.
+func (m *Manager) SetDemand(demand []Demand) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.demand = make(map[demandPair]float64)
+	for _, entry := range demand {
+		pair := demandPair{origin: entry.Origin, destination: entry.Destination}
+		m.demand[pair] = m.demand[pair] + entry.Trips
+	}
+}
+
+// ParseDemand reads an origin–destination matrix from CSV with the columns origin station key, destination
+// station key and number of trips. A header line is skipped. All stations must exist in the scenario.
+func (m *Manager) ParseDemand(reader io.Reader) ([]Demand, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	result := make([]Demand, 0)
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read the demand: %v", err)
+		}
+		if len(record) != 3 {
+			return nil, fmt.Errorf("line %d: expected the 3 columns origin, destination and trips, but got %d", line, len(record))
+		}
+		trips, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil && line == 1 {
+			continue
+		}
+		if err != nil || trips < 0 || math.IsNaN(trips) || math.IsInf(trips, 0) {
+			return nil, fmt.Errorf("line %d: the trips \"%s\" are not a non-negative number", line, record[2])
+		}
+		entry := Demand{Origin: strings.TrimSpace(record[0]), Destination: strings.TrimSpace(record[1]), Trips: trips}
+		for _, key := range []string{entry.Origin, entry.Destination} {
+			if _, ok := m.Station(key); !ok {
+				return nil, fmt.Errorf("line %d: could not find station with key \"%s\"", line, key)
+			}
+		}
+		if entry.Origin == entry.Destination {
+			return nil, fmt.Errorf("line %d: the origin and the destination must differ", line)
+		}
+		result = append(result, entry)
+	}
+}
@@ -0,0 +1,76 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManager_ParseDemand(t *testing.T) {
+	manager := Empty()
+	for _, key := range []string{"a", "b", "c"} {
+		manager.stations[key] = Station{Key: key}
+	}
+	t.Run("with header", func(t *testing.T) {
+		demand, err := manager.ParseDemand(strings.NewReader("origin,destination,trips\na,b,12\nb, a, 3.5\na,b,1\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []Demand{{"a", "b", 12}, {"b", "a", 3.5}, {"a", "b", 1}}, demand)
+		manager.SetDemand(demand)
+		assert.Equal(t, []Demand{{"a", "b", 13}, {"b", "a", 3.5}}, manager.Demand())
+		assert.Equal(t, 13.0, manager.Trips("a", "b"))
+		assert.Equal(t, 0.0, manager.Trips("a", "c"))
+	})
+	t.Run("without header", func(t *testing.T) {
+		demand, err := manager.ParseDemand(strings.NewReader("c,a,4"))
+		require.NoError(t, err)
+		assert.Equal(t, []Demand{{"c", "a", 4}}, demand)
+	})
+	t.Run("errors", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			csv string
+			err string
+		}{
+			"columns":          {"a,b\n", "line 1: expected the 3 columns origin, destination and trips, but got 2"},
+			"trips":            {"a,b,1\na,c,many\n", "line 2: the trips \"many\" are not a non-negative number"},
+			"negative trips":   {"a,b,-1\n", "line 1: the trips \"-1\" are not a non-negative number"},
+			"not a number":     {"a,b,NaN\n", "line 1: the trips \"NaN\" are not a non-negative number"},
+			"infinite trips":   {"a,b,1\na,c,Inf\n", "line 2: the trips \"Inf\" are not a non-negative number"},
+			"unknown station":  {"origin,destination,trips\na,x,1\n", "line 2: could not find station with key \"x\""},
+			"same station":     {"a,a,1\n", "line 1: the origin and the destination must differ"},
+			"header not first": {"a,b,1\norigin,destination,trips\n", "line 2: the trips \"trips\" are not a non-negative number"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := manager.ParseDemand(strings.NewReader(test.csv))
+				assert.EqualError(t, err, test.err)
+			})
+		}
+	})
+}
+
+func TestManager_Demand_Persistence(t *testing.T) {
+	directory := t.TempDir()
+	manager := Empty()
+	manager.filePath = directory
+	manager.SetDemand([]Demand{{"b", "a", 2}, {"a", "b", 1}})
+	require.NoError(t, manager.Persist())
+	assert.FileExists(t, filepath.Join(directory, "demand.json"))
+
+	loaded, err := LoadScenario(directory)
+	require.NoError(t, err)
+	assert.Equal(t, []Demand{{"a", "b", 1}, {"b", "a", 2}}, loaded.Demand())
+
+	require.NoError(t, os.Remove(filepath.Join(directory, "demand.json")))
+	loaded, err = LoadScenario(directory)
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Demand())
+}
+
+func TestManager_DeleteStation_Demand(t *testing.T) {
+	manager := Empty()
+	manager.SetDemand([]Demand{{"a", "b", 1}, {"b", "c", 2}, {"c", "a", 3}})
+	manager.DeleteStation("a")
+	assert.Equal(t, []Demand{{"b", "c", 2}}, manager.Demand())
+}
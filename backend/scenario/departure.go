@@ -0,0 +1,129 @@
+package scenario
+
+import (
+	"sort"
+	"time"
+)
+
+// Departure is the departure of a single trip at a station.
+type Departure struct {
+	Time        Time
+	Timetable   Timetable
+	TourIndex   int
+	Destination Station
+}
+
+// stop is a call of a trip at a station. Index is the position of the station in the timetable,
+// first and last are the positions of the first and the last served station of the trip.
+type stop struct {
+	timetable Timetable
+	stations  []Station
+	trip      Trip
+	index     int
+	first     int
+	last      int
+}
+
+// arrival returns the time the trip arrives at the station. It is unset at the first station of the trip.
+func (s stop) arrival() Time {
+	if s.index <= s.first {
+		return Time{}
+	}
+	return arrivalOf(s.trip.Events[s.index])
+}
+
+// departure returns the time the trip leaves the station. It is unset at the last station of the trip.
+func (s stop) departure() Time {
+	if s.index >= s.last {
+		return Time{}
+	}
+	return departureOf(s.trip.Events[s.index])
+}
+
+// arrivalOf returns the arrival of the event, or its departure if only that is set.
+func arrivalOf(event ArrivalDeparture) Time {
+	if event.Arrival.IsSet() {
+		return event.Arrival
+	}
+	return event.Departure
+}
+
+// departureOf returns the departure of the event, or its arrival if only that is set.
+func departureOf(event ArrivalDeparture) Time {
+	if event.Departure.IsSet() {
+		return event.Departure
+	}
+	return event.Arrival
+}
+
+// visitStops calls the visitor for every trip of every timetable that serves the station. If the date is
+// not zero, only timetables that are operated on that date are considered.
+func (m *Manager) visitStops(stationKey string, date time.Time, visitor func(s stop)) {
+	timetables := m.Timetables()
+	if !date.IsZero() {
+		timetables = m.ActiveTimetables(date)
+	}
+	for _, timetable := range timetables {
+		indices := make([]int, 0, 1)
+		for index, key := range timetable.StationKeys {
+			if key == stationKey {
+				indices = append(indices, index)
+			}
+		}
+		if len(indices) == 0 {
+			continue
+		}
+		stations := timetable.Stations()
+		for _, trip := range timetable.Trips() {
+			first, last := servedRange(trip.Events)
+			if last < 0 || last >= len(stations) {
+				continue
+			}
+			for _, index := range indices {
+				if index < len(trip.Events) {
+					visitor(stop{timetable: timetable, stations: stations, trip: trip, index: index, first: first, last: last})
+				}
+			}
+		}
+	}
+}
+
+// Departures returns the departures at the station between from and to, both inclusive, sorted by time.
+// Departures at the same time keep the order of the timetables.
+// Times after midnight belong to the service day before and are expressed as 24:00 and later.
+// If the date is not zero, only timetables that are operated on that date are considered.
+// Trips that end at the station do not depart there and are omitted.
+func (m *Manager) Departures(stationKey string, from Time, to Time, date time.Time) []Departure {
+	result := make([]Departure, 0)
+	m.visitStops(stationKey, date, func(s stop) {
+		departure := s.departure()
+		if !departure.IsSet() || departure.Before(from) || to.Before(departure) {
+			return
+		}
+		result = append(result, Departure{
+			Time:        departure,
+			Timetable:   s.timetable,
+			TourIndex:   s.trip.TourIndex,
+			Destination: s.stations[s.last],
+		})
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
+
+// servedRange returns the indices of the first and the last event with an arrival or departure, or -1 if there is none.
+func servedRange(events []ArrivalDeparture) (int, int) {
+	first := -1
+	last := -1
+	for index, event := range events {
+		if event.Arrival.IsSet() || event.Departure.IsSet() {
+			if first < 0 {
+				first = index
+			}
+			last = index
+		}
+	}
+	return first, last
+}
@@ -0,0 +1,65 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestManager_Departures(t *testing.T) {
+	manager := Empty()
+	manager.SaveStation(Station{Key: "a", Name: "A"})
+	manager.SaveStation(Station{Key: "b", Name: "B"})
+	manager.SaveStation(Station{Key: "c", Name: "C"})
+	manager.SaveLine(Line{Key: "line1", Name: "Line 1", Stops: []string{"a", "b", "c"}})
+	manager.SaveLine(Line{Key: "line2", Name: "Line 2", Stops: []string{"c", "b"}})
+	sunday := Calendar{Key: "sunday"}
+	sunday.Weekdays[time.Sunday] = true
+	manager.SaveCalendar(sunday)
+	manager.SaveTimetable(Timetable{
+		Key:         "tt1",
+		LineKey:     "line1",
+		StationKeys: []string{"a", "b", "c"},
+		Tours: []Tour{
+			{
+				IntervalMinutes: 30,
+				LastTour:        MustParseTime("8:30"),
+				Events:          []ArrivalDeparture{{Departure: MustParseTime("7:55")}, {Arrival: MustParseTime("8:00"), Departure: MustParseTime("8:01")}, {Arrival: MustParseTime("8:05")}},
+			},
+		},
+	})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt2",
+		LineKey:     "line2",
+		CalendarKey: "sunday",
+		StationKeys: []string{"c", "b"},
+		Tours: []Tour{
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:25")}, {Arrival: MustParseTime("8:31")}}},
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:20")}, {Arrival: MustParseTime("8:26")}}},
+		},
+	})
+
+	t.Run("intermediate station", func(t *testing.T) {
+		departures := manager.Departures("b", MustParseTime("8:00"), MustParseTime("9:00"), time.Time{})
+		require.Equal(t, 2, len(departures))
+		assert.Equal(t, MustParseTime("8:01"), departures[0].Time)
+		assert.Equal(t, "tt1", departures[0].Timetable.Key)
+		assert.Equal(t, 0, departures[0].TourIndex)
+		assert.Equal(t, "C", departures[0].Destination.Name)
+		assert.Equal(t, MustParseTime("8:31"), departures[1].Time)
+	})
+	t.Run("sorted across timetables", func(t *testing.T) {
+		departures := manager.Departures("c", MustParseTime("8:00"), MustParseTime("9:00"), time.Time{})
+		require.Equal(t, 2, len(departures))
+		assert.Equal(t, MustParseTime("8:20"), departures[0].Time)
+		assert.Equal(t, 1, departures[0].TourIndex)
+		assert.Equal(t, "B", departures[0].Destination.Name)
+		assert.Equal(t, MustParseTime("8:25"), departures[1].Time)
+	})
+	t.Run("filtered by date", func(t *testing.T) {
+		monday, _ := ParseDate("2026-10-19")
+		departures := manager.Departures("c", MustParseTime("0:00"), MustParseTime("47:59"), monday)
+		assert.Empty(t, departures)
+	})
+}
@@ -0,0 +1,84 @@
+package scenario
+
+import (
+	"fmt"
+	"math"
+)
+
+// TimetableTemplate describes how a timetable is generated from the travel times of a line.
+type TimetableTemplate struct {
+	Name string
+	// DwellSeconds is the time a vehicle waits at each intermediate stop.
+	DwellSeconds    int
+	FirstDeparture  Time
+	IntervalMinutes int
+	LastDeparture   Time
+}
+
+// GenerateTimetable creates a timetable for the line with a single tour starting at the first departure.
+// The times of the events are derived from the durations of the line's path plus the dwell time
+// at every intermediate stop and are rounded to whole minutes. Like in the other timetables of the line, waypoint
+// stations are part of the timetable, but are not served.
+// The timetable is not saved.
+func (l *Line) GenerateTimetable(template TimetableTemplate) (Timetable, error) {
+	if !template.FirstDeparture.IsSet() {
+		return Timetable{}, fmt.Errorf("the first departure must be set")
+	}
+	if template.DwellSeconds < 0 {
+		return Timetable{}, fmt.Errorf("the dwell time must not be negative")
+	}
+	travelTimes, err := l.StopTravelTimes()
+	if err != nil {
+		return Timetable{}, err
+	}
+	stations := l.Stations()
+	served := make([]int, 0, len(stations))
+	for index, station := range stations {
+		if !station.IsWaypoint {
+			served = append(served, index)
+		}
+	}
+	if len(served) < 2 {
+		return Timetable{}, fmt.Errorf("the line \"%s\" must have at least two stations that are not waypoints", l.Key)
+	}
+	stationKeys := append([]string{}, l.Stops...)
+	events := make([]ArrivalDeparture, len(stations))
+	dwell := 0
+	for position, index := range served {
+		offset := travelTimes[index] - travelTimes[served[0]] + float64(dwell)
+		arrival := template.FirstDeparture.Add(roundToMinute(offset))
+		switch {
+		case position == 0:
+			events[index] = ArrivalDeparture{Departure: template.FirstDeparture}
+		case position == len(served)-1:
+			events[index] = ArrivalDeparture{Arrival: arrival}
+		case template.DwellSeconds == 0:
+			events[index] = ArrivalDeparture{Departure: arrival}
+		default:
+			departure := template.FirstDeparture.Add(roundToMinute(offset + float64(template.DwellSeconds)))
+			events[index] = ArrivalDeparture{Arrival: arrival, Departure: departure}
+		}
+		if position > 0 {
+			dwell = dwell + template.DwellSeconds
+		}
+	}
+	tour := Tour{Events: events}
+	if template.IntervalMinutes > 0 {
+		tour.IntervalMinutes = template.IntervalMinutes
+		tour.LastTour = template.LastDeparture
+	}
+	err = tour.Validate()
+	if err != nil {
+		return Timetable{}, err
+	}
+	return Timetable{
+		LineKey:     l.Key,
+		Name:        template.Name,
+		Tours:       []Tour{tour},
+		StationKeys: stationKeys,
+	}, nil
+}
+
+func roundToMinute(seconds float64) int {
+	return int(math.Round(seconds/60)) * 60
+}
@@ -0,0 +1,98 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func generationManager() *Manager {
+	manager := Empty()
+	manager.SaveStation(Station{Key: "a"})
+	manager.SaveStation(Station{Key: "w", IsWaypoint: true})
+	manager.SaveStation(Station{Key: "b"})
+	manager.SaveStation(Station{Key: "c"})
+	manager.SaveLine(Line{
+		Key:   "line1",
+		Stops: []string{"a", "w", "b", "c"},
+		Path: []Waypoint{
+			{Dur: 100, Stop: true},
+			{Dur: 50},
+			{Dur: 90, Stop: true},
+			{Dur: 170, Stop: true},
+			{Dur: 0, Stop: true},
+		},
+	})
+	return manager
+}
+
+func TestLine_GenerateTimetable(t *testing.T) {
+	manager := generationManager()
+	line, _ := manager.Line("line1")
+	t.Run("with dwell time and interval", func(t *testing.T) {
+		timetable, err := line.GenerateTimetable(TimetableTemplate{
+			Name:            "Generated",
+			DwellSeconds:    30,
+			FirstDeparture:  MustParseTime("6:00"),
+			IntervalMinutes: 15,
+			LastDeparture:   MustParseTime("8:00"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Timetable{
+			LineKey:     "line1",
+			Name:        "Generated",
+			StationKeys: []string{"a", "w", "b", "c"},
+			Tours: []Tour{
+				{
+					IntervalMinutes: 15,
+					LastTour:        MustParseTime("8:00"),
+					Events: []ArrivalDeparture{
+						{Departure: MustParseTime("6:00")},
+						{},
+						{Arrival: MustParseTime("6:04"), Departure: MustParseTime("6:05")},
+						{Arrival: MustParseTime("6:07")},
+					},
+				},
+			},
+		}, timetable)
+	})
+	t.Run("without dwell time", func(t *testing.T) {
+		timetable, err := line.GenerateTimetable(TimetableTemplate{FirstDeparture: MustParseTime("23:59")})
+		require.NoError(t, err)
+		assert.Equal(t, []Tour{
+			{
+				Events: []ArrivalDeparture{
+					{Departure: MustParseTime("23:59")},
+					{},
+					{Departure: MustParseTime("24:03")},
+					{Arrival: MustParseTime("24:06")},
+				},
+			},
+		}, timetable.Tours)
+	})
+}
+
+func TestLine_GenerateTimetable_Errors(t *testing.T) {
+	manager := generationManager()
+	manager.SaveLine(Line{Key: "unrouted", Stops: []string{"a", "b"}})
+	manager.SaveLine(Line{Key: "short", Stops: []string{"a", "w"}, Path: []Waypoint{{Stop: true}, {Stop: true}}})
+	tests := []struct {
+		name     string
+		lineKey  string
+		template TimetableTemplate
+		err      string
+	}{
+		{"missing first departure", "line1", TimetableTemplate{}, "the first departure must be set"},
+		{"negative dwell time", "line1", TimetableTemplate{FirstDeparture: MustParseTime("6:00"), DwellSeconds: -1}, "the dwell time must not be negative"},
+		{"missing last departure", "line1", TimetableTemplate{FirstDeparture: MustParseTime("6:00"), IntervalMinutes: 10}, "the last tour must be set if an interval is given"},
+		{"unrouted line", "unrouted", TimetableTemplate{FirstDeparture: MustParseTime("6:00")}, "the path of line \"unrouted\" has 0 stops, but the line has 2 stops"},
+		{"too few stations", "short", TimetableTemplate{FirstDeparture: MustParseTime("6:00")}, "the line \"short\" must have at least two stations that are not waypoints"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, _ := manager.Line(tt.lineKey)
+			_, err := line.GenerateTimetable(tt.template)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
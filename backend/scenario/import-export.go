@@ -18,8 +18,11 @@ func LoadScenario(path string) (*Manager, error) {
 	timetables := make(map[string]Timetable)
 	vehicles := make(map[string]Vehicle)
 	stations := make(map[string]Station)
+	calendars := make(map[string]Calendar)
 	manager := Manager{
 		vehicles:   vehicles,
+		calendars:  calendars,
+		demand:     make(map[demandPair]float64),
 		timetables: timetables,
 		lines:      lines,
 		stations:   stations,
@@ -51,6 +54,17 @@ func LoadScenario(path string) (*Manager, error) {
 			}
 			manager.stations, err = convertStationsFromPersistence(&manager, stations)
 			return err
+		} else if strings.HasSuffix(path, "demand.json") {
+			var demand []persistence.Demand
+			err = json.NewDecoder(file).Decode(&demand)
+			if err != nil {
+				return fmt.Errorf("could not read demand.json: %v", err)
+			}
+			for _, entry := range demand {
+				pair := demandPair{origin: entry.Origin, destination: entry.Destination}
+				manager.demand[pair] = manager.demand[pair] + entry.Trips
+			}
+			return nil
 		} else if strings.HasSuffix(path, "scenario.json") {
 			var scenario persistence.Scenario
 			err = json.NewDecoder(file).Decode(&scenario)
@@ -75,7 +89,10 @@ func LoadScenario(path string) (*Manager, error) {
 			if err != nil {
 				return fmt.Errorf("could not read timetable file \"%s\": %v", path, err)
 			}
-			timetables[timetable.Key] = convertTimetableFromPersistence(&manager, timetable)
+			timetables[timetable.Key], err = convertTimetableFromPersistence(&manager, timetable)
+			if err != nil {
+				return fmt.Errorf("could not read timetable file \"%s\": %v", path, err)
+			}
 		} else if topic == "lines" {
 			var line persistence.Line
 			err = json.NewDecoder(file).Decode(&line)
@@ -92,6 +109,7 @@ func LoadScenario(path string) (*Manager, error) {
 				Name:    line.Name,
 				Color:   line.Color,
 				Key:     line.Key,
+				Mode:    line.Mode,
 				manager: &manager,
 			}
 		} else if topic == "vehicles" {
@@ -104,6 +122,16 @@ func LoadScenario(path string) (*Manager, error) {
 			if err != nil {
 				return err
 			}
+		} else if topic == "calendars" {
+			var calendar persistence.Calendar
+			err = json.NewDecoder(file).Decode(&calendar)
+			if err != nil {
+				return fmt.Errorf("could not read calendar file \"%s\": %v", path, err)
+			}
+			calendars[calendar.Key], err = convertCalendarFromPersistence(calendar)
+			if err != nil {
+				return fmt.Errorf("could not read calendar file \"%s\": %v", path, err)
+			}
 		}
 		return nil
 	})
@@ -132,19 +160,31 @@ func convertStationsFromPersistence(manager *Manager, stations []persistence.Sta
 	return result, nil
 }
 
-func convertTimetableFromPersistence(manager *Manager, timetable persistence.Timetable) Timetable {
+func convertTimetableFromPersistence(manager *Manager, timetable persistence.Timetable) (Timetable, error) {
 	tours := make([]Tour, 0, len(timetable.Tours))
-	for _, tour := range timetable.Tours {
+	for tourIndex, tour := range timetable.Tours {
 		events := make([]ArrivalDeparture, 0, len(tour.Events))
-		for _, event := range tour.Events {
+		for eventIndex, event := range tour.Events {
+			arrival, err := ParseOptionalTime(event.Arrival)
+			if err != nil {
+				return Timetable{}, fmt.Errorf("could not read arrival of event %d of tour %d: %v", eventIndex, tourIndex, err)
+			}
+			departure, err := ParseOptionalTime(event.Departure)
+			if err != nil {
+				return Timetable{}, fmt.Errorf("could not read departure of event %d of tour %d: %v", eventIndex, tourIndex, err)
+			}
 			events = append(events, ArrivalDeparture{
-				Arrival:   event.Arrival,
-				Departure: event.Departure,
+				Arrival:   arrival,
+				Departure: departure,
 			})
 		}
+		lastTour, err := ParseOptionalTime(tour.LastTour)
+		if err != nil {
+			return Timetable{}, fmt.Errorf("could not read last tour of tour %d: %v", tourIndex, err)
+		}
 		tours = append(tours, Tour{
 			IntervalMinutes: tour.IntervalMinutes,
-			LastTour:        tour.LastTour,
+			LastTour:        lastTour,
 			Events:          events,
 		})
 	}
@@ -152,9 +192,49 @@ func convertTimetableFromPersistence(manager *Manager, timetable persistence.Tim
 		Key:         timetable.Key,
 		LineKey:     timetable.Line,
 		Name:        timetable.Name,
+		CalendarKey: timetable.Calendar,
 		Tours:       tours,
 		StationKeys: timetable.Stations,
 		manager:     manager,
+	}, nil
+}
+
+// convertCalendarFromPersistence parses the weekdays and dates of the calendar.
+func convertCalendarFromPersistence(calendar persistence.Calendar) (Calendar, error) {
+	result := Calendar{Key: calendar.Key, Name: calendar.Name}
+	err := result.SetWeekdayNames(calendar.Weekdays)
+	if err != nil {
+		return Calendar{}, err
+	}
+	result.StartDate, err = ParseOptionalDate(calendar.StartDate)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("start date: %v", err)
+	}
+	result.EndDate, err = ParseOptionalDate(calendar.EndDate)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("end date: %v", err)
+	}
+	result.AddedDates, err = ParseDates(calendar.AddedDates)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("added dates: %v", err)
+	}
+	result.RemovedDates, err = ParseDates(calendar.RemovedDates)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("removed dates: %v", err)
+	}
+	return result, result.Validate()
+}
+
+// convertCalendarToPersistence is the inverse of convertCalendarFromPersistence.
+func convertCalendarToPersistence(calendar Calendar) persistence.Calendar {
+	return persistence.Calendar{
+		Key:          calendar.Key,
+		Name:         calendar.Name,
+		Weekdays:     calendar.WeekdayNames(),
+		StartDate:    FormatOptionalDate(calendar.StartDate),
+		EndDate:      FormatOptionalDate(calendar.EndDate),
+		AddedDates:   FormatDates(calendar.AddedDates),
+		RemovedDates: FormatDates(calendar.RemovedDates),
 	}
 }
 
@@ -225,8 +305,13 @@ func (m *Manager) Export() map[string]any {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 	result := make(map[string]any)
+	sortedStations := make([]Station, 0, len(m.stations))
+	for _, station := range m.stations {
+		sortedStations = append(sortedStations, station)
+	}
+	sort.Slice(sortedStations, sortStations(sortedStations))
 	stations := make([]persistence.Station, 0, len(m.stations))
-	for _, station := range m.Stations() {
+	for _, station := range sortedStations {
 		stations = append(stations, persistence.Station{
 			Key:  station.Key,
 			Name: station.Name,
@@ -238,13 +323,23 @@ func (m *Manager) Export() map[string]any {
 		})
 	}
 	result["stations.json"] = stations
-	for _, line := range m.Lines() {
+	demand := make([]persistence.Demand, 0, len(m.demand))
+	for _, entry := range m.sortedDemand() {
+		demand = append(demand, persistence.Demand{
+			Origin:      entry.Origin,
+			Destination: entry.Destination,
+			Trips:       entry.Trips,
+		})
+	}
+	result["demand.json"] = demand
+	for _, line := range m.lines {
 		persistedLine := persistence.Line{
 			Stops: line.Stops,
 			Path:  convertWaypointsToPersistence(line.Path),
 			Name:  line.Name,
 			Color: line.Color,
 			Key:   line.Key,
+			Mode:  line.Mode,
 		}
 		result["lines/"+persistedLine.Key+".json"] = persistedLine
 	}
@@ -254,6 +349,9 @@ func (m *Manager) Export() map[string]any {
 	for _, vehicle := range m.convertVehiclesToPersistence() {
 		result["vehicles/"+vehicle.Key+".json"] = vehicle
 	}
+	for _, calendar := range m.calendars {
+		result["calendars/"+calendar.Key+".json"] = convertCalendarToPersistence(calendar)
+	}
 	scenario := persistence.Scenario{
 		Center: persistence.Center{
 			Lat:  m.Center.Lat,
@@ -265,21 +363,22 @@ func (m *Manager) Export() map[string]any {
 	return result
 }
 
+// convertTimetablesToPersistence returns the timetables sorted by key. The mutex must be held.
 func (m *Manager) convertTimetablesToPersistence() []persistence.Timetable {
 	timetables := make([]persistence.Timetable, 0, len(m.timetables))
-	for _, timetable := range m.Timetables() {
+	for _, timetable := range m.timetables {
 		tours := make([]persistence.Tour, 0, len(timetable.Tours))
 		for _, tour := range timetable.Tours {
 			events := make([]persistence.ArrivalDeparture, 0, len(tour.Events))
 			for _, event := range tour.Events {
 				events = append(events, persistence.ArrivalDeparture{
-					Arrival:   event.Arrival,
-					Departure: event.Departure,
+					Arrival:   event.Arrival.String(),
+					Departure: event.Departure.String(),
 				})
 			}
 			tours = append(tours, persistence.Tour{
 				IntervalMinutes: tour.IntervalMinutes,
-				LastTour:        tour.LastTour,
+				LastTour:        tour.LastTour.String(),
 				Events:          events,
 			})
 		}
@@ -287,14 +386,18 @@ func (m *Manager) convertTimetablesToPersistence() []persistence.Timetable {
 			Key:      timetable.Key,
 			Line:     timetable.LineKey,
 			Name:     timetable.Name,
+			Calendar: timetable.CalendarKey,
 			Stations: timetable.StationKeys,
 			Tours:    tours,
 		})
-
 	}
+	sort.Slice(timetables, func(i, j int) bool {
+		return timetables[i].Key < timetables[j].Key
+	})
 	return timetables
 }
 
+// convertVehiclesToPersistence returns the vehicles sorted by key. The mutex must be held.
 func (m *Manager) convertVehiclesToPersistence() []persistence.Vehicle {
 	vehicles := make([]persistence.Vehicle, 0, len(m.vehicles))
 	for _, vehicle := range m.vehicles {
@@ -339,11 +442,12 @@ func convertWaypointsFromPersistence(path persistence.Path) ([]Waypoint, error)
 	waypoints := make([]Waypoint, 0, len(coords))
 	for index, coord := range coords {
 		waypoints = append(waypoints, Waypoint{
-			Lat:  coord[0],
-			Lng:  coord[1],
-			Dist: path.Meta[index].Dist,
-			Dur:  path.Meta[index].Dur,
-			Stop: path.Meta[index].Stop,
+			Lat:    coord[0],
+			Lng:    coord[1],
+			Dist:   path.Meta[index].Dist,
+			Dur:    path.Meta[index].Dur,
+			Stop:   path.Meta[index].Stop,
+			Manual: path.Meta[index].Manual,
 		})
 	}
 	return waypoints, nil
@@ -355,9 +459,10 @@ func convertWaypointsToPersistence(waypoints []Waypoint) persistence.Path {
 	for _, wp := range waypoints {
 		coords = append(coords, []float64{wp.Lat, wp.Lng})
 		meta = append(meta, persistence.MetaCoord{
-			Dist: wp.Dist,
-			Dur:  wp.Dur,
-			Stop: wp.Stop,
+			Dist:   wp.Dist,
+			Dur:    wp.Dur,
+			Stop:   wp.Stop,
+			Manual: wp.Manual,
 		})
 	}
 	return persistence.Path{
@@ -38,9 +38,9 @@ func TestLoadFile(t *testing.T) {
 		tour := timetable.Tours[11]
 		assert.Equal(t, 16, len(tour.Events))
 		assert.Equal(t, ArrivalDeparture{
-			Departure: "8:44",
+			Departure: MustParseTime("8:44"),
 		}, tour.Events[10])
-		assert.Equal(t, "19:11", tour.LastTour)
+		assert.Equal(t, MustParseTime("19:11"), tour.LastTour)
 		assert.Equal(t, 8, tour.IntervalMinutes)
 
 		assert.Equal(t, 313, len(manager.stations))
@@ -246,7 +246,6 @@ func TestManager_Export1(t *testing.T) {
 		stations   map[string]Station
 		timetables map[string]Timetable
 		vehicles   map[string]Vehicle
-		mutex      sync.RWMutex
 		Center     Center
 	}
 	tests := []struct {
@@ -256,7 +255,8 @@ func TestManager_Export1(t *testing.T) {
 	}{
 		// TODO: Add test cases.
 	}
-	for _, tt := range tests {
+	for i := range tests {
+		tt := &tests[i]
 		t.Run(tt.name, func(t *testing.T) {
 			m := &Manager{
 				filePath:   tt.fields.filePath,
@@ -264,7 +264,7 @@ func TestManager_Export1(t *testing.T) {
 				stations:   tt.fields.stations,
 				timetables: tt.fields.timetables,
 				vehicles:   tt.fields.vehicles,
-				mutex:      tt.fields.mutex,
+				mutex:      sync.RWMutex{},
 				Center:     tt.fields.Center,
 			}
 			assert.Equalf(t, tt.want, m.Export(), "Export()")
@@ -0,0 +1,275 @@
+package scenario
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// JourneyOptions configure the journey planner.
+type JourneyOptions struct {
+	Departure Time
+	// Date restricts the planner to timetables operated on that date if it is not zero.
+	Date time.Time
+	// MinTransferSeconds is the minimal time needed to change between two vehicles at the same station.
+	MinTransferSeconds int
+	// MaxWalkMeters is the maximal beeline distance of a walk between two stations. Walking is disabled if it is not positive.
+	MaxWalkMeters float64
+	// WalkSpeed is the walking speed in meters per second.
+	WalkSpeed float64
+	// Count is the number of journeys to plan. Each journey departs later than the one before.
+	Count int
+}
+
+// Leg is a part of a journey, either a ride with a single trip or a walk between two stations.
+type Leg struct {
+	// Timetable is the timetable of the ride. Its key is empty for walks.
+	Timetable Timetable
+	TourIndex int
+	From      Station
+	To        Station
+	Departure Time
+	Arrival   Time
+}
+
+// IsWalk returns true if the leg is a walk between two stations.
+func (l *Leg) IsWalk() bool {
+	return l.Timetable.Key == ""
+}
+
+// Journey is a sequence of legs leading from the origin to the destination.
+type Journey struct {
+	Legs []Leg
+}
+
+// Departure returns the departure of the first leg.
+func (j *Journey) Departure() Time {
+	return j.Legs[0].Departure
+}
+
+// Arrival returns the arrival of the last leg.
+func (j *Journey) Arrival() Time {
+	return j.Legs[len(j.Legs)-1].Arrival
+}
+
+// Transfers returns the number of changes between vehicles.
+func (j *Journey) Transfers() int {
+	rides := 0
+	for _, leg := range j.Legs {
+		if !leg.IsWalk() {
+			rides++
+		}
+	}
+	if rides == 0 {
+		return 0
+	}
+	return rides - 1
+}
+
+// connection is the ride of a trip between two consecutive served stations.
+type connection struct {
+	trip      int
+	timetable Timetable
+	tourIndex int
+	from      string
+	to        string
+	departure Time
+	arrival   Time
+}
+
+// footpath is a walk to a nearby station.
+type footpath struct {
+	to      string
+	seconds int
+}
+
+// journeyStep remembers how a station was reached in the connection scan.
+type journeyStep struct {
+	// enter and exit are the indices of the connections used to ride to the station, or -1 for walks.
+	enter int
+	exit  int
+	// from is the station the walk to the station started at.
+	from string
+	walk int
+}
+
+// PlanJourneys searches the journeys with the earliest arrival from the origin to the destination station with the
+// connection scan algorithm. Trips of all timetables are expanded, walks are possible between stations within the
+// maximal walking distance. Waypoint stations are never used for walks. The next journey is searched with a departure
+// after the first departure of the journey before. The result is empty if the destination cannot be reached.
+func (m *Manager) PlanJourneys(originKey string, destinationKey string, options JourneyOptions) []Journey {
+	connections := m.connections(options.Date)
+	footpaths := m.footpaths(options.MaxWalkMeters, options.WalkSpeed)
+	result := make([]Journey, 0, options.Count)
+	departure := options.Departure
+	for len(result) < options.Count {
+		journey, ok := m.planJourney(connections, footpaths, originKey, destinationKey, departure, options.MinTransferSeconds)
+		if !ok {
+			break
+		}
+		result = append(result, journey)
+		if len(journey.Legs) == 1 && journey.Legs[0].IsWalk() {
+			break
+		}
+		departure = journey.Departure().Add(1)
+	}
+	return result
+}
+
+func (m *Manager) planJourney(connections []connection, footpaths map[string][]footpath, originKey string, destinationKey string,
+	departure Time, minTransferSeconds int) (Journey, bool) {
+	if originKey == destinationKey {
+		return Journey{}, false
+	}
+	arrival := map[string]int{originKey: departure.Seconds()}
+	// ready is the earliest time a vehicle can be boarded at the station, including the transfer time.
+	ready := map[string]int{originKey: departure.Seconds()}
+	steps := make(map[string]journeyStep)
+	for _, path := range footpaths[originKey] {
+		walkArrival := departure.Seconds() + path.seconds
+		arrival[path.to] = walkArrival
+		ready[path.to] = walkArrival
+		steps[path.to] = journeyStep{enter: -1, exit: -1, from: originKey, walk: path.seconds}
+	}
+	boarded := make(map[int]int)
+	first := sort.Search(len(connections), func(i int) bool {
+		return !connections[i].departure.Before(departure)
+	})
+	for index := first; index < len(connections); index++ {
+		c := connections[index]
+		if best, ok := arrival[destinationKey]; ok && best <= c.departure.Seconds() {
+			break
+		}
+		if _, ok := boarded[c.trip]; !ok {
+			readyTime, reached := ready[c.from]
+			if !reached || readyTime > c.departure.Seconds() {
+				continue
+			}
+			boarded[c.trip] = index
+		}
+		if best, ok := arrival[c.to]; ok && best <= c.arrival.Seconds() {
+			continue
+		}
+		arrival[c.to] = c.arrival.Seconds()
+		ready[c.to] = c.arrival.Seconds() + minTransferSeconds
+		steps[c.to] = journeyStep{enter: boarded[c.trip], exit: index}
+		for _, path := range footpaths[c.to] {
+			walkArrival := c.arrival.Seconds() + path.seconds
+			if best, ok := arrival[path.to]; ok && best <= walkArrival {
+				continue
+			}
+			arrival[path.to] = walkArrival
+			ready[path.to] = walkArrival
+			steps[path.to] = journeyStep{enter: -1, exit: -1, from: c.to, walk: path.seconds}
+		}
+	}
+	if _, ok := arrival[destinationKey]; !ok {
+		return Journey{}, false
+	}
+	legs := make([]Leg, 0)
+	current := destinationKey
+	for current != originKey {
+		step := steps[current]
+		to, _ := m.Station(current)
+		if step.exit < 0 {
+			from, _ := m.Station(step.from)
+			legs = append(legs, Leg{
+				From:      from,
+				To:        to,
+				Departure: NewTime(0, 0, arrival[current]-step.walk),
+				Arrival:   NewTime(0, 0, arrival[current]),
+			})
+			current = step.from
+			continue
+		}
+		enter := connections[step.enter]
+		exit := connections[step.exit]
+		from, _ := m.Station(enter.from)
+		legs = append(legs, Leg{
+			Timetable: exit.timetable,
+			TourIndex: exit.tourIndex,
+			From:      from,
+			To:        to,
+			Departure: enter.departure,
+			Arrival:   exit.arrival,
+		})
+		current = enter.from
+	}
+	for i, j := 0, len(legs)-1; i < j; i, j = i+1, j-1 {
+		legs[i], legs[j] = legs[j], legs[i]
+	}
+	if legs[0].IsWalk() && len(legs) > 1 {
+		// walk to the first vehicle as late as possible
+		walk := legs[0].Arrival.Sub(legs[0].Departure)
+		legs[0].Departure = legs[1].Departure.Add(-walk)
+		legs[0].Arrival = legs[1].Departure
+	}
+	return Journey{Legs: legs}, true
+}
+
+// connections expands the trips of all timetables into connections sorted by departure.
+func (m *Manager) connections(date time.Time) []connection {
+	timetables := m.Timetables()
+	if !date.IsZero() {
+		timetables = m.ActiveTimetables(date)
+	}
+	result := make([]connection, 0)
+	trip := 0
+	for _, timetable := range timetables {
+		stations := timetable.Stations()
+		for _, t := range timetable.Trips() {
+			previous := -1
+			for index, event := range t.Events {
+				if index >= len(stations) || stations[index].Key == "" {
+					break
+				}
+				if !arrivalOf(event).IsSet() {
+					continue
+				}
+				if previous >= 0 {
+					result = append(result, connection{
+						trip:      trip,
+						timetable: timetable,
+						tourIndex: t.TourIndex,
+						from:      stations[previous].Key,
+						to:        stations[index].Key,
+						departure: departureOf(t.Events[previous]),
+						arrival:   arrivalOf(event),
+					})
+				}
+				previous = index
+			}
+			trip++
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].departure.Before(result[j].departure)
+	})
+	return result
+}
+
+// footpaths returns the walks between all stations that are not waypoints within the maximal distance.
+func (m *Manager) footpaths(maxWalkMeters float64, walkSpeed float64) map[string][]footpath {
+	result := make(map[string][]footpath)
+	if maxWalkMeters <= 0 || walkSpeed <= 0 {
+		return result
+	}
+	stations := make([]Station, 0)
+	for _, station := range m.Stations() {
+		if !station.IsWaypoint {
+			stations = append(stations, station)
+		}
+	}
+	for i, a := range stations {
+		for _, b := range stations[i+1:] {
+			distance := HaversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+			if distance > maxWalkMeters {
+				continue
+			}
+			seconds := int(math.Ceil(distance / walkSpeed))
+			result[a.Key] = append(result[a.Key], footpath{to: b.Key, seconds: seconds})
+			result[b.Key] = append(result[b.Key], footpath{to: a.Key, seconds: seconds})
+		}
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func journeyManager() *Manager {
+	manager := Empty()
+	manager.SaveStation(Station{Key: "a", Name: "A", Lat: 49.80, Lng: 9.90})
+	manager.SaveStation(Station{Key: "b", Name: "B", Lat: 49.81, Lng: 9.90})
+	manager.SaveStation(Station{Key: "c", Name: "C", Lat: 49.81, Lng: 9.902})
+	manager.SaveStation(Station{Key: "d", Name: "D", Lat: 49.82, Lng: 9.90})
+	manager.SaveLine(Line{Key: "line1", Name: "Line 1", Stops: []string{"a", "b"}})
+	manager.SaveLine(Line{Key: "line2", Name: "Line 2", Stops: []string{"c", "d"}})
+	manager.SaveLine(Line{Key: "line3", Name: "Line 3", Stops: []string{"b", "d"}})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt1",
+		LineKey:     "line1",
+		StationKeys: []string{"a", "b"},
+		Tours: []Tour{
+			{
+				IntervalMinutes: 30,
+				LastTour:        MustParseTime("9:00"),
+				Events:          []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:10")}},
+			},
+		},
+	})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt2",
+		LineKey:     "line2",
+		StationKeys: []string{"c", "d"},
+		Tours: []Tour{
+			{
+				IntervalMinutes: 30,
+				LastTour:        MustParseTime("8:45"),
+				Events:          []ArrivalDeparture{{Departure: MustParseTime("8:15")}, {Arrival: MustParseTime("8:25")}},
+			},
+		},
+	})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt3",
+		LineKey:     "line3",
+		StationKeys: []string{"b", "d"},
+		Tours:       []Tour{{Events: []ArrivalDeparture{{Departure: MustParseTime("8:20")}, {Arrival: MustParseTime("9:00")}}}},
+	})
+	return manager
+}
+
+type legSummary struct {
+	timetable string
+	from      string
+	to        string
+	departure string
+	arrival   string
+}
+
+func summarizeLegs(journey Journey) []legSummary {
+	result := make([]legSummary, 0, len(journey.Legs))
+	for _, leg := range journey.Legs {
+		result = append(result, legSummary{leg.Timetable.Key, leg.From.Key, leg.To.Key, leg.Departure.String(), leg.Arrival.String()})
+	}
+	return result
+}
+
+func TestManager_PlanJourneys(t *testing.T) {
+	manager := journeyManager()
+	options := JourneyOptions{
+		Departure:          MustParseTime("7:50"),
+		MinTransferSeconds: 120,
+		MaxWalkMeters:      300,
+		WalkSpeed:          1,
+		Count:              3,
+	}
+	t.Run("with walking transfer", func(t *testing.T) {
+		journeys := manager.PlanJourneys("a", "d", options)
+		require.Equal(t, 2, len(journeys))
+		assert.Equal(t, []legSummary{
+			{"tt1", "a", "b", "8:00", "8:10"},
+			{"", "b", "c", "8:10", "8:12:24"},
+			{"tt2", "c", "d", "8:15", "8:25"},
+		}, summarizeLegs(journeys[0]))
+		assert.Equal(t, 1, journeys[0].Transfers())
+		assert.Equal(t, MustParseTime("8:00"), journeys[0].Departure())
+		assert.Equal(t, MustParseTime("8:25"), journeys[0].Arrival())
+		assert.Equal(t, MustParseTime("8:55"), journeys[1].Arrival())
+	})
+	t.Run("without walking", func(t *testing.T) {
+		noWalk := options
+		noWalk.MaxWalkMeters = 0
+		journeys := manager.PlanJourneys("a", "d", noWalk)
+		require.Equal(t, 1, len(journeys))
+		assert.Equal(t, []legSummary{
+			{"tt1", "a", "b", "8:00", "8:10"},
+			{"tt3", "b", "d", "8:20", "9:00"},
+		}, summarizeLegs(journeys[0]))
+	})
+	t.Run("minimal transfer time", func(t *testing.T) {
+		slow := options
+		slow.MaxWalkMeters = 0
+		slow.MinTransferSeconds = 15 * 60
+		assert.Empty(t, manager.PlanJourneys("a", "d", slow))
+	})
+	t.Run("walk only", func(t *testing.T) {
+		journeys := manager.PlanJourneys("b", "c", options)
+		require.Equal(t, 1, len(journeys))
+		assert.Equal(t, []legSummary{{"", "b", "c", "7:50", "7:52:24"}}, summarizeLegs(journeys[0]))
+		assert.Equal(t, 0, journeys[0].Transfers())
+	})
+	t.Run("walk to first vehicle", func(t *testing.T) {
+		journeys := manager.PlanJourneys("b", "d", options)
+		require.NotEmpty(t, journeys)
+		assert.Equal(t, []legSummary{
+			{"", "b", "c", "8:12:36", "8:15"},
+			{"tt2", "c", "d", "8:15", "8:25"},
+		}, summarizeLegs(journeys[0]))
+	})
+	t.Run("unreachable", func(t *testing.T) {
+		assert.Empty(t, manager.PlanJourneys("d", "a", options))
+	})
+}
@@ -38,11 +38,13 @@ func (s *Station) Lines() []Line {
 }
 
 type Line struct {
-	Stops   []string
-	Path    []Waypoint
-	Name    string
-	Color   string
-	Key     string
+	Stops []string
+	Path  []Waypoint
+	Name  string
+	Color string
+	Key   string
+	// Mode is the transport mode of the line, one of Modes. Lines without mode are buses.
+	Mode    string
 	manager *Manager
 }
 
@@ -52,6 +54,9 @@ type Waypoint struct {
 	Dist float64
 	Dur  float64
 	Stop bool
+	// Manual is set if the segment to the next waypoint was drawn by hand instead of being routed. Its distance
+	// and duration are computed when the line is saved.
+	Manual bool
 }
 
 func (l *Line) Stations() []Station {
@@ -64,15 +69,32 @@ func (l *Line) Stations() []Station {
 	return result
 }
 
+// StopTravelTimes returns the accumulated travel time in seconds from the first stop to each stop of
+// the line, based on the durations of the line's path. Fails if the path does not match the stops.
+func (l *Line) StopTravelTimes() ([]float64, error) {
+	result := make([]float64, 0, len(l.Stops))
+	current := 0.0
+	for _, waypoint := range l.Path {
+		if waypoint.Stop {
+			result = append(result, current)
+		}
+		current = current + waypoint.Dur
+	}
+	if len(result) != len(l.Stops) {
+		return nil, fmt.Errorf("the path of line \"%s\" has %d stops, but the line has %d stops", l.Key, len(result), len(l.Stops))
+	}
+	return result, nil
+}
+
 type Tour struct {
 	IntervalMinutes int
-	LastTour        string
+	LastTour        Time
 	Events          []ArrivalDeparture
 }
 
 type ArrivalDeparture struct {
-	Arrival   string
-	Departure string
+	Arrival   Time
+	Departure Time
 }
 
 type Manager struct {
@@ -81,6 +103,8 @@ type Manager struct {
 	stations   map[string]Station
 	timetables map[string]Timetable
 	vehicles   map[string]Vehicle
+	calendars  map[string]Calendar
+	demand     map[demandPair]float64
 	mutex      sync.RWMutex
 	Center     Center
 }
@@ -93,6 +117,8 @@ func Empty() *Manager {
 		stations:   make(map[string]Station),
 		timetables: make(map[string]Timetable),
 		vehicles:   make(map[string]Vehicle),
+		calendars:  make(map[string]Calendar),
+		demand:     make(map[demandPair]float64),
 		mutex:      sync.RWMutex{},
 	}
 }
@@ -126,6 +152,7 @@ func (m *Manager) SaveLine(line Line) Line {
 	if line.Key == "" {
 		line.Key = gonanoid.MustID(10)
 	}
+	line.measureManualSegments()
 	line.manager = m
 	m.lines[line.Key] = line
 	return line
@@ -170,4 +197,9 @@ func (m *Manager) DeleteStation(key string) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	delete(m.stations, key)
+	for pair := range m.demand {
+		if pair.origin == key || pair.destination == key {
+			delete(m.demand, pair)
+		}
+	}
 }
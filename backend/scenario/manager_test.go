@@ -190,6 +190,8 @@ func TestEmpty(t *testing.T) {
 		stations:   map[string]Station{},
 		timetables: map[string]Timetable{},
 		vehicles:   map[string]Vehicle{},
+		calendars:  map[string]Calendar{},
+		demand:     map[demandPair]float64{},
 		mutex:      sync.RWMutex{},
 		Center:     Center{Lat: 0, Lng: 0, Zoom: 0},
 	}, manager)
@@ -0,0 +1,226 @@
+package scenario
+
+import "fmt"
+
+// StationChanges lists the stations that were added to or removed from a timetable.
+type StationChanges struct {
+	Added   []string
+	Removed []string
+}
+
+// IsEmpty returns true if no station was added or removed.
+func (s *StationChanges) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0
+}
+
+// timetableStationKeys returns the stations a timetable of the line must have. Waypoints are only included
+// if the timetable already includes waypoints.
+func (t *Timetable) timetableStationKeys(line Line) []string {
+	includeWaypoints := false
+	for _, station := range t.Stations() {
+		if station.IsWaypoint {
+			includeWaypoints = true
+		}
+	}
+	result := make([]string, 0, len(line.Stops))
+	for _, station := range line.Stations() {
+		if includeWaypoints || !station.IsWaypoint {
+			result = append(result, station.Key)
+		}
+	}
+	return result
+}
+
+// StationChanges compares the stations of the timetable with the stops of the line.
+func (t *Timetable) StationChanges(line Line) StationChanges {
+	keys := t.timetableStationKeys(line)
+	matches := alignStations(t.StationKeys, keys)
+	return stationChanges(t.StationKeys, keys, matches)
+}
+
+// Migrate adapts the timetable to the stops of the line. Events of removed stations are dropped. Events of added
+// stations are interpolated between their neighbours in proportion to the durations of the line's path, and
+// extrapolated from the path durations if they are added before the first or after the last station. Stations
+// outside the served part of a tour stay unserved. Interpolated times are rounded to whole minutes.
+func (t *Timetable) Migrate(line Line) (Timetable, StationChanges, error) {
+	keys := t.timetableStationKeys(line)
+	matches := alignStations(t.StationKeys, keys)
+	changes := stationChanges(t.StationKeys, keys, matches)
+	if changes.IsEmpty() {
+		return *t, changes, nil
+	}
+	travelTimes, err := line.StopTravelTimes()
+	if err != nil {
+		return Timetable{}, changes, err
+	}
+	offsets, _ := keyTravelTimes(line, keys, travelTimes)
+	result := *t
+	result.StationKeys = keys
+	result.Tours = make([]Tour, 0, len(t.Tours))
+	for _, tour := range t.Tours {
+		migrated, err := migrateTour(tour, matches, offsets)
+		if err != nil {
+			return Timetable{}, changes, err
+		}
+		err = migrated.Validate()
+		if err != nil {
+			return Timetable{}, changes, err
+		}
+		result.Tours = append(result.Tours, migrated)
+	}
+	return result, changes, nil
+}
+
+// migrateTour creates the events of the tour for the new stations. matches contains the index of the old
+// station for each new station, or -1 for added stations. Fails if an added station before the first served
+// station would be served before midnight of the service day.
+func migrateTour(tour Tour, matches []int, offsets []float64) (Tour, error) {
+	old := tour.normalizedEvents()
+	events := make([]ArrivalDeparture, len(matches))
+	firstMatch, lastMatch := -1, -1
+	for index, match := range matches {
+		if match >= 0 && match < len(old) {
+			events[index] = old[match]
+		}
+		if match >= 0 {
+			if firstMatch < 0 {
+				firstMatch = index
+			}
+			lastMatch = index
+		}
+	}
+	for index, match := range matches {
+		if match >= 0 {
+			continue
+		}
+		previous := -1
+		for i := index - 1; i >= 0; i-- {
+			if matches[i] >= 0 && departureOf(events[i]).IsSet() {
+				previous = i
+				break
+			}
+		}
+		next := -1
+		for i := index + 1; i < len(matches); i++ {
+			if matches[i] >= 0 && arrivalOf(events[i]).IsSet() {
+				next = i
+				break
+			}
+		}
+		switch {
+		case previous >= 0 && next >= 0:
+			from := departureOf(events[previous])
+			to := arrivalOf(events[next])
+			share := 0.0
+			if offsets[next] > offsets[previous] {
+				share = (offsets[index] - offsets[previous]) / (offsets[next] - offsets[previous])
+			}
+			seconds := roundToMinute(float64(from.Seconds()) + share*float64(to.Sub(from)))
+			if seconds < from.Seconds() {
+				seconds = from.Seconds()
+			}
+			if seconds > to.Seconds() {
+				seconds = to.Seconds()
+			}
+			events[index] = ArrivalDeparture{Departure: NewTime(0, 0, seconds)}
+		case previous >= 0 && previous == lastMatch && index > lastMatch:
+			from := departureOf(events[previous])
+			seconds := from.Seconds() + roundToMinute(offsets[index]-offsets[previous])
+			if index == len(matches)-1 {
+				events[index] = ArrivalDeparture{Arrival: NewTime(0, 0, seconds)}
+			} else {
+				events[index] = ArrivalDeparture{Departure: NewTime(0, 0, seconds)}
+			}
+		case next >= 0 && next == firstMatch && index < firstMatch:
+			to := arrivalOf(events[next])
+			seconds := to.Seconds() - roundToMinute(offsets[next]-offsets[index])
+			if seconds < 0 {
+				return Tour{}, fmt.Errorf("the added station %d would be served before midnight of the service day", index)
+			}
+			events[index] = ArrivalDeparture{Departure: NewTime(0, 0, seconds)}
+		}
+	}
+	result := Tour{IntervalMinutes: tour.IntervalMinutes, LastTour: tour.LastTour, Events: events}
+	oldStart := tour.Start()
+	newStart := result.Start()
+	if tour.LastTour.IsSet() && oldStart.IsSet() && newStart.IsSet() {
+		result.LastTour = tour.LastTour.Add(newStart.Sub(oldStart))
+	}
+	return result, nil
+}
+
+// keyTravelTimes returns the travel time from the first stop of the line for each of the keys,
+// which must be the stops of the line in the same order, optionally without waypoints. Returns false if a key
+// cannot be matched, its travel time is 0 then.
+func keyTravelTimes(line Line, keys []string, travelTimes []float64) ([]float64, bool) {
+	result := make([]float64, 0, len(keys))
+	matched := true
+	stop := 0
+	for _, key := range keys {
+		for stop < len(line.Stops) && line.Stops[stop] != key {
+			stop++
+		}
+		if stop < len(line.Stops) {
+			result = append(result, travelTimes[stop])
+		} else {
+			result = append(result, 0)
+			matched = false
+		}
+	}
+	return result, matched
+}
+
+// alignStations matches the new stations with the old stations by their longest common subsequence.
+// It returns the index of the old station for each new station, or -1 if the station was added.
+func alignStations(old []string, new []string) []int {
+	lengths := make([][]int, len(old)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	result := make([]int, len(new))
+	for j := range result {
+		result[j] = -1
+	}
+	i, j := 0, 0
+	for i < len(old) && j < len(new) {
+		if old[i] == new[j] {
+			result[j] = i
+			i++
+			j++
+		} else if lengths[i+1][j] >= lengths[i][j+1] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func stationChanges(old []string, new []string, matches []int) StationChanges {
+	matched := make(map[int]bool)
+	result := StationChanges{Added: make([]string, 0), Removed: make([]string, 0)}
+	for index, match := range matches {
+		if match < 0 {
+			result.Added = append(result.Added, new[index])
+		} else {
+			matched[match] = true
+		}
+	}
+	for index, key := range old {
+		if !matched[index] {
+			result.Removed = append(result.Removed, key)
+		}
+	}
+	return result
+}
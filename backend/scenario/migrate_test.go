@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func migrationManager() *Manager {
+	manager := Empty()
+	for _, key := range []string{"a", "b", "c", "d", "n", "z"} {
+		manager.SaveStation(Station{Key: key})
+	}
+	manager.SaveStation(Station{Key: "w", IsWaypoint: true})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt1",
+		LineKey:     "line1",
+		StationKeys: []string{"a", "b", "c"},
+		Tours: []Tour{
+			{
+				IntervalMinutes: 30,
+				LastTour:        MustParseTime("9:00"),
+				Events:          []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:09"), Departure: MustParseTime("8:10")}, {Arrival: MustParseTime("8:20")}},
+			},
+			{
+				Events: []ArrivalDeparture{{Departure: MustParseTime("10:00")}, {Arrival: MustParseTime("10:10")}, {}},
+			},
+		},
+	})
+	return manager
+}
+
+func routedLine(manager *Manager, stops []string, durations []float64) Line {
+	path := make([]Waypoint, 0, len(stops))
+	for index := range stops {
+		waypoint := Waypoint{Stop: true}
+		if index < len(durations) {
+			waypoint.Dur = durations[index]
+		}
+		path = append(path, waypoint)
+	}
+	return manager.SaveLine(Line{Key: "line1", Stops: stops, Path: path})
+}
+
+func TestTimetable_Migrate(t *testing.T) {
+	t.Run("insert and append", func(t *testing.T) {
+		manager := migrationManager()
+		line := routedLine(manager, []string{"a", "b", "n", "c", "d"}, []float64{600, 200, 400, 300})
+		timetable, _ := manager.Timetable("tt1")
+		migrated, changes, err := timetable.Migrate(line)
+		require.NoError(t, err)
+		assert.Equal(t, StationChanges{Added: []string{"n", "d"}, Removed: []string{}}, changes)
+		assert.Equal(t, []string{"a", "b", "n", "c", "d"}, migrated.StationKeys)
+		assert.Equal(t, []Tour{
+			{
+				IntervalMinutes: 30,
+				LastTour:        MustParseTime("9:00"),
+				Events: []ArrivalDeparture{
+					{Departure: MustParseTime("8:00")},
+					{Arrival: MustParseTime("8:09"), Departure: MustParseTime("8:10")},
+					{Departure: MustParseTime("8:13")},
+					{Arrival: MustParseTime("8:20")},
+					{Arrival: MustParseTime("8:25")},
+				},
+			},
+			{
+				Events: []ArrivalDeparture{{Departure: MustParseTime("10:00")}, {Arrival: MustParseTime("10:10")}, {}, {}, {}},
+			},
+		}, migrated.Tours)
+	})
+	t.Run("remove", func(t *testing.T) {
+		manager := migrationManager()
+		line := routedLine(manager, []string{"a", "c"}, []float64{600})
+		timetable, _ := manager.Timetable("tt1")
+		migrated, changes, err := timetable.Migrate(line)
+		require.NoError(t, err)
+		assert.Equal(t, StationChanges{Added: []string{}, Removed: []string{"b"}}, changes)
+		assert.Equal(t, []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:20")}}, migrated.Tours[0].Events)
+	})
+	t.Run("prepend", func(t *testing.T) {
+		manager := migrationManager()
+		line := routedLine(manager, []string{"z", "a", "b", "c"}, []float64{120, 600, 600})
+		timetable, _ := manager.Timetable("tt1")
+		migrated, _, err := timetable.Migrate(line)
+		require.NoError(t, err)
+		assert.Equal(t, ArrivalDeparture{Departure: MustParseTime("7:58")}, migrated.Tours[0].Events[0])
+		assert.Equal(t, MustParseTime("8:58"), migrated.Tours[0].LastTour)
+	})
+	t.Run("prepend before midnight", func(t *testing.T) {
+		manager := migrationManager()
+		manager.SaveTimetable(Timetable{
+			Key:         "tt1",
+			LineKey:     "line1",
+			StationKeys: []string{"a", "b", "c"},
+			Tours: []Tour{
+				{Events: []ArrivalDeparture{{Departure: MustParseTime("0:02")}, {Arrival: MustParseTime("0:09"), Departure: MustParseTime("0:10")}, {Arrival: MustParseTime("0:20")}}},
+			},
+		})
+		line := routedLine(manager, []string{"z", "a", "b", "c"}, []float64{300, 600, 600})
+		timetable, _ := manager.Timetable("tt1")
+		_, changes, err := timetable.Migrate(line)
+		assert.EqualError(t, err, "the added station 0 would be served before midnight of the service day")
+		assert.Equal(t, []string{"z"}, changes.Added)
+	})
+	t.Run("waypoints are ignored", func(t *testing.T) {
+		manager := migrationManager()
+		line := routedLine(manager, []string{"a", "w", "b", "c"}, nil)
+		timetable, _ := manager.Timetable("tt1")
+		migrated, changes, err := timetable.Migrate(line)
+		require.NoError(t, err)
+		assert.True(t, changes.IsEmpty())
+		assert.Equal(t, timetable, migrated)
+	})
+	t.Run("unrouted line", func(t *testing.T) {
+		manager := migrationManager()
+		line := manager.SaveLine(Line{Key: "line1", Stops: []string{"a", "c"}})
+		timetable, _ := manager.Timetable("tt1")
+		_, changes, err := timetable.Migrate(line)
+		assert.EqualError(t, err, "the path of line \"line1\" has 0 stops, but the line has 2 stops")
+		assert.Equal(t, []string{"b"}, changes.Removed)
+	})
+}
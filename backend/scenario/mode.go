@@ -0,0 +1,61 @@
+package scenario
+
+import "fmt"
+
+// The transport modes of lines. They follow the basic route types of GTFS.
+const (
+	ModeBus        = "bus"
+	ModeTrolleybus = "trolleybus"
+	ModeTram       = "tram"
+	ModeSubway     = "subway"
+	ModeRail       = "rail"
+	ModeMonorail   = "monorail"
+	ModeFerry      = "ferry"
+	ModeCableTram  = "cable_tram"
+	ModeAerialLift = "aerial_lift"
+	ModeFunicular  = "funicular"
+)
+
+// Modes contains all transport modes of lines.
+var Modes = []string{ModeBus, ModeTrolleybus, ModeTram, ModeSubway, ModeRail, ModeMonorail, ModeFerry, ModeCableTram, ModeAerialLift, ModeFunicular}
+
+// ModeSpeeds are the average speeds in km/h of the transport modes. The durations of manually drawn path
+// segments are computed from them.
+var ModeSpeeds = map[string]float64{
+	ModeBus:        25,
+	ModeTrolleybus: 25,
+	ModeTram:       25,
+	ModeSubway:     40,
+	ModeRail:       60,
+	ModeMonorail:   35,
+	ModeFerry:      20,
+	ModeCableTram:  10,
+	ModeAerialLift: 15,
+	ModeFunicular:  10,
+}
+
+// ValidateMode fails if the transport mode is unknown. The empty mode is valid and means bus.
+func ValidateMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, known := range Modes {
+		if mode == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("the transport mode \"%s\" is unknown, use one of %v", mode, Modes)
+}
+
+// TransportMode returns the transport mode of the line, bus if it has none.
+func (l Line) TransportMode() string {
+	if l.Mode == "" {
+		return ModeBus
+	}
+	return l.Mode
+}
+
+// Speed returns the average speed of the line in meters per second.
+func (l Line) Speed() float64 {
+	return ModeSpeeds[l.TransportMode()] / 3.6
+}
@@ -0,0 +1,37 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateMode(t *testing.T) {
+	for _, mode := range append([]string{""}, Modes...) {
+		assert.NoError(t, ValidateMode(mode), mode)
+	}
+	assert.EqualError(t, ValidateMode("Tram"), "the transport mode \"Tram\" is unknown, use one of "+
+		"[bus trolleybus tram subway rail monorail ferry cable_tram aerial_lift funicular]")
+}
+
+func TestLine_TransportMode(t *testing.T) {
+	assert.Equal(t, ModeBus, Line{}.TransportMode())
+	assert.Equal(t, ModeFerry, Line{Mode: ModeFerry}.TransportMode())
+}
+
+func TestManager_Mode_Persistence(t *testing.T) {
+	directory := t.TempDir()
+	manager := Empty()
+	manager.filePath = directory
+	manager.SaveStation(Station{Key: "a"})
+	manager.SaveLine(Line{Key: "tram", Stops: []string{"a"}, Mode: ModeTram})
+	manager.SaveLine(Line{Key: "bus", Stops: []string{"a"}})
+	require.NoError(t, manager.Persist())
+
+	loaded, err := LoadScenario(directory)
+	require.NoError(t, err)
+	tram, _ := loaded.Line("tram")
+	assert.Equal(t, ModeTram, tram.Mode)
+	bus, _ := loaded.Line("bus")
+	assert.Equal(t, "", bus.Mode)
+}
@@ -0,0 +1,92 @@
+package scenario
+
+import "fmt"
+
+// StopSegments splits the path of the line into the segments between consecutive stops. Each segment contains
+// the waypoints from the waypoint of a stop up to and including the waypoint of the next stop. Returns false
+// if the number of stop waypoints differs from the number of stops.
+func (l Line) StopSegments() ([][]Waypoint, bool) {
+	stopWaypoints := make([]int, 0, len(l.Stops))
+	for index, waypoint := range l.Path {
+		if waypoint.Stop {
+			stopWaypoints = append(stopWaypoints, index)
+		}
+	}
+	if len(stopWaypoints) != len(l.Stops) {
+		return nil, false
+	}
+	result := make([][]Waypoint, 0, len(stopWaypoints))
+	for index := 0; index+1 < len(stopWaypoints); index++ {
+		result = append(result, l.Path[stopWaypoints[index]:stopWaypoints[index+1]+1])
+	}
+	return result, true
+}
+
+// HasManualSegments reports whether a segment of the path was drawn by hand.
+func (l Line) HasManualSegments() bool {
+	for _, waypoint := range l.Path {
+		if waypoint.Manual {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateManualSegments checks that manually drawn waypoints form whole segments between two stops: either all
+// waypoints of a segment except the one of the next stop are marked as manual or none of them.
+func (l Line) ValidateManualSegments() error {
+	if !l.HasManualSegments() {
+		return nil
+	}
+	segments, ok := l.StopSegments()
+	if !ok || len(segments) == 0 {
+		return fmt.Errorf("the path of a line with manual segments must have a stop waypoint for each of its %d stops", len(l.Stops))
+	}
+	for index, segment := range segments {
+		for _, waypoint := range segment[1 : len(segment)-1] {
+			if waypoint.Manual != segment[0].Manual {
+				return fmt.Errorf("the segment from stop %d to stop %d must be marked as manual at all of its waypoints or at none", index, index+1)
+			}
+		}
+	}
+	for index, waypoint := range l.Path {
+		if waypoint.Stop {
+			break
+		}
+		if waypoint.Manual {
+			return fmt.Errorf("waypoint %d before the first stop must not be marked as manual", index)
+		}
+	}
+	for index := len(l.Path) - 1; index >= 0; index-- {
+		if l.Path[index].Manual {
+			return fmt.Errorf("waypoint %d at or after the last stop must not be marked as manual", index)
+		}
+		if l.Path[index].Stop {
+			break
+		}
+	}
+	return nil
+}
+
+// measureManualSegments sets the distances of manually drawn waypoints to the haversine distance to the next
+// waypoint and their durations to the time needed at the speed of the line. The path is copied before it
+// is changed.
+func (l *Line) measureManualSegments() {
+	if !l.HasManualSegments() {
+		return
+	}
+	path := make([]Waypoint, len(l.Path))
+	copy(path, l.Path)
+	for index := range path {
+		if !path[index].Manual {
+			continue
+		}
+		if index+1 == len(path) {
+			path[index].Dist, path[index].Dur = 0, 0
+			continue
+		}
+		path[index].Dist = HaversineDistance(path[index].Lat, path[index].Lng, path[index+1].Lat, path[index+1].Lng)
+		path[index].Dur = path[index].Dist / l.Speed()
+	}
+	l.Path = path
+}
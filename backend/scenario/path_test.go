@@ -0,0 +1,81 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func manualTestLine() Line {
+	return Line{Key: "ferry", Stops: []string{"a", "b", "c"}, Mode: ModeFerry, Path: []Waypoint{
+		{Lat: 49.79745, Lng: 9.93503, Dist: 1000, Dur: 100, Stop: true},
+		{Lat: 49.80182, Lng: 9.92265, Stop: true, Manual: true},
+		{Lat: 49.80400, Lng: 9.92000, Manual: true},
+		{Lat: 49.80500, Lng: 9.91000, Stop: true},
+	}}
+}
+
+func TestLine_StopSegments(t *testing.T) {
+	line := manualTestLine()
+	segments, ok := line.StopSegments()
+	require.True(t, ok)
+	require.Equal(t, 2, len(segments))
+	assert.Equal(t, line.Path[0:2], segments[0])
+	assert.Equal(t, line.Path[1:4], segments[1])
+
+	line.Stops = append(line.Stops, "d")
+	_, ok = line.StopSegments()
+	assert.False(t, ok)
+}
+
+func TestLine_ValidateManualSegments(t *testing.T) {
+	assert.NoError(t, manualTestLine().ValidateManualSegments())
+	assert.NoError(t, Line{Stops: []string{"a", "b"}, Path: []Waypoint{{Stop: true}, {}, {Stop: true}}}.ValidateManualSegments())
+	tests := map[string]struct {
+		change func(line *Line)
+		want   string
+	}{
+		"only an inner waypoint": {func(line *Line) { line.Path[1].Manual = false },
+			"the segment from stop 1 to stop 2 must be marked as manual at all of its waypoints or at none"},
+		"missing stop": {func(line *Line) { line.Stops = append(line.Stops, "d") },
+			"the path of a line with manual segments must have a stop waypoint for each of its 4 stops"},
+		"last stop": {func(line *Line) { line.Path[3].Manual = true },
+			"waypoint 3 at or after the last stop must not be marked as manual"},
+		"before the first stop": {func(line *Line) { line.Path = append([]Waypoint{{Manual: true}}, line.Path...) },
+			"waypoint 0 before the first stop must not be marked as manual"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			line := manualTestLine()
+			test.change(&line)
+			assert.EqualError(t, line.ValidateManualSegments(), test.want)
+		})
+	}
+}
+
+func TestManager_SaveLine_manual(t *testing.T) {
+	manager := Empty()
+	for _, key := range []string{"a", "b", "c"} {
+		manager.SaveStation(Station{Key: key})
+	}
+	line := manualTestLine()
+	saved := manager.SaveLine(line)
+	assert.Equal(t, 1000.0, saved.Path[0].Dist)
+	assert.Equal(t, 100.0, saved.Path[0].Dur)
+	for index := 1; index < 3; index++ {
+		distance := HaversineDistance(line.Path[index].Lat, line.Path[index].Lng, line.Path[index+1].Lat, line.Path[index+1].Lng)
+		assert.InDelta(t, distance, saved.Path[index].Dist, 0.001)
+		assert.InDelta(t, distance/(20/3.6), saved.Path[index].Dur, 0.001)
+	}
+	assert.Equal(t, 0.0, saved.Path[3].Dist)
+	// the path of the caller is not changed
+	assert.Equal(t, 0.0, line.Path[1].Dist)
+
+	directory := t.TempDir()
+	manager.filePath = directory
+	require.NoError(t, manager.Persist())
+	loaded, err := LoadScenario(directory)
+	require.NoError(t, err)
+	ferry, _ := loaded.Line("ferry")
+	assert.Equal(t, []bool{false, true, true, false}, []bool{ferry.Path[0].Manual, ferry.Path[1].Manual, ferry.Path[2].Manual, ferry.Path[3].Manual})
+}
@@ -0,0 +1,104 @@
+package scenario
+
+import "fmt"
+
+// TimeBand is a period of the day in which tours depart at a fixed headway. Start and End are both
+// departures of the band if the headway fits.
+type TimeBand struct {
+	Start          Time
+	End            Time
+	HeadwayMinutes int
+}
+
+// TourPattern describes tours that follow the same template in several time bands,
+// e.g. every 20 minutes from 5:00 to 6:00 and every 10 minutes from 6:00 to 9:00.
+type TourPattern struct {
+	// Template contains the events of a single tour. Only the differences between its times matter,
+	// the template is shifted so that its first time matches each departure of the bands.
+	Template []ArrivalDeparture
+	// Bands must be in chronological order and must not overlap. A band may start at the end of the band before.
+	Bands []TimeBand
+	// UseIntervals creates a single tour with interval per band instead of a tour per departure.
+	UseIntervals bool
+}
+
+// Tours creates the tours of the pattern. A departure at the end of a band that is also the start of the
+// next band belongs to the next band.
+func (p *TourPattern) Tours() ([]Tour, error) {
+	template := Tour{Events: p.Template}
+	start := template.Start()
+	if !start.IsSet() {
+		return nil, fmt.Errorf("the template must contain at least one time")
+	}
+	err := template.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("template: %v", err)
+	}
+	departures, err := p.departures()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Tour, 0)
+	for index, band := range departures {
+		if len(band) == 0 {
+			continue
+		}
+		if p.UseIntervals {
+			tour := shiftTour(template, band[0].Sub(start))
+			if len(band) > 1 {
+				tour.IntervalMinutes = p.Bands[index].HeadwayMinutes
+				tour.LastTour = band[len(band)-1]
+			}
+			result = append(result, tour)
+			continue
+		}
+		for _, departure := range band {
+			result = append(result, shiftTour(template, departure.Sub(start)))
+		}
+	}
+	return result, nil
+}
+
+// departures returns the departures of each band.
+func (p *TourPattern) departures() ([][]Time, error) {
+	if len(p.Bands) == 0 {
+		return nil, fmt.Errorf("at least one time band is required")
+	}
+	result := make([][]Time, 0, len(p.Bands))
+	for index, band := range p.Bands {
+		if !band.Start.IsSet() || !band.End.IsSet() {
+			return nil, fmt.Errorf("the start and the end of band %d must be set", index)
+		}
+		if band.HeadwayMinutes <= 0 {
+			return nil, fmt.Errorf("the headway of band %d must be positive", index)
+		}
+		if band.End.Before(band.Start) {
+			return nil, fmt.Errorf("the end %s of band %d must not be before its start %s", band.End, index, band.Start)
+		}
+		if index > 0 {
+			previous := p.Bands[index-1]
+			if band.Start.Before(previous.End) {
+				return nil, fmt.Errorf("band %d must not start before the end %s of the band before", index, previous.End)
+			}
+			last := len(result[index-1]) - 1
+			if result[index-1][last] == band.Start {
+				result[index-1] = result[index-1][:last]
+			}
+		}
+		departures := make([]Time, 0)
+		for departure := band.Start; !band.End.Before(departure); departure = departure.Add(band.HeadwayMinutes * 60) {
+			departures = append(departures, departure)
+		}
+		result = append(result, departures)
+	}
+	return result, nil
+}
+
+// shiftTour returns a copy of the tour with all times shifted by the given seconds.
+func shiftTour(tour Tour, seconds int) Tour {
+	events := make([]ArrivalDeparture, 0, len(tour.Events))
+	for _, event := range tour.Events {
+		events = append(events, ArrivalDeparture{Arrival: event.Arrival.Add(seconds), Departure: event.Departure.Add(seconds)})
+	}
+	return Tour{Events: events}
+}
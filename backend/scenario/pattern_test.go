@@ -0,0 +1,82 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTourPattern_Tours(t *testing.T) {
+	template := []ArrivalDeparture{{Departure: MustParseTime("0:00")}, {Arrival: MustParseTime("0:04"), Departure: MustParseTime("0:05")}, {}, {Arrival: MustParseTime("0:12")}}
+	bands := []TimeBand{
+		{Start: MustParseTime("5:00"), End: MustParseTime("6:00"), HeadwayMinutes: 20},
+		{Start: MustParseTime("6:00"), End: MustParseTime("6:30"), HeadwayMinutes: 10},
+		{Start: MustParseTime("22:00"), End: MustParseTime("24:10"), HeadwayMinutes: 60},
+	}
+	tour := func(departure string, arrival string, departure2 string, arrival2 string) Tour {
+		return Tour{Events: []ArrivalDeparture{{Departure: MustParseTime(departure)}, {Arrival: MustParseTime(arrival), Departure: MustParseTime(departure2)}, {}, {Arrival: MustParseTime(arrival2)}}}
+	}
+	t.Run("explicit tours", func(t *testing.T) {
+		pattern := TourPattern{Template: template, Bands: bands}
+		tours, err := pattern.Tours()
+		require.NoError(t, err)
+		starts := make([]string, 0, len(tours))
+		for _, tour := range tours {
+			assert.Equal(t, 0, tour.IntervalMinutes)
+			starts = append(starts, tour.Start().String())
+		}
+		assert.Equal(t, []string{"5:00", "5:20", "5:40", "6:00", "6:10", "6:20", "6:30", "22:00", "23:00", "24:00"}, starts)
+		assert.Equal(t, tour("5:20", "5:24", "5:25", "5:32"), tours[1])
+		assert.Equal(t, tour("24:00", "24:04", "24:05", "24:12"), tours[9])
+	})
+	t.Run("interval tours", func(t *testing.T) {
+		pattern := TourPattern{Template: template, Bands: bands, UseIntervals: true}
+		tours, err := pattern.Tours()
+		require.NoError(t, err)
+		first := tour("5:00", "5:04", "5:05", "5:12")
+		first.IntervalMinutes = 20
+		first.LastTour = MustParseTime("5:40")
+		second := tour("6:00", "6:04", "6:05", "6:12")
+		second.IntervalMinutes = 10
+		second.LastTour = MustParseTime("6:30")
+		third := tour("22:00", "22:04", "22:05", "22:12")
+		third.IntervalMinutes = 60
+		third.LastTour = MustParseTime("24:00")
+		assert.Equal(t, []Tour{first, second, third}, tours)
+	})
+	t.Run("template with absolute times", func(t *testing.T) {
+		pattern := TourPattern{
+			Template:     []ArrivalDeparture{{}, {Departure: MustParseTime("8:03")}, {Arrival: MustParseTime("8:10")}},
+			Bands:        []TimeBand{{Start: MustParseTime("7:00"), End: MustParseTime("7:00"), HeadwayMinutes: 15}},
+			UseIntervals: true,
+		}
+		tours, err := pattern.Tours()
+		require.NoError(t, err)
+		assert.Equal(t, []Tour{{Events: []ArrivalDeparture{{}, {Departure: MustParseTime("7:00")}, {Arrival: MustParseTime("7:07")}}}}, tours)
+	})
+	t.Run("errors", func(t *testing.T) {
+		for name, test := range map[string]struct {
+			pattern TourPattern
+			err     string
+		}{
+			"empty template": {TourPattern{Template: []ArrivalDeparture{{}}, Bands: bands}, "the template must contain at least one time"},
+			"invalid template": {
+				TourPattern{Template: []ArrivalDeparture{{Departure: MustParseTime("0:05")}, {Arrival: MustParseTime("0:07"), Departure: MustParseTime("0:06")}}, Bands: bands},
+				"template: the time 0:06 of event 1 is earlier than the time 0:07 before",
+			},
+			"no bands":         {TourPattern{Template: template}, "at least one time band is required"},
+			"missing end":      {TourPattern{Template: template, Bands: []TimeBand{{Start: MustParseTime("5:00"), HeadwayMinutes: 10}}}, "the start and the end of band 0 must be set"},
+			"no headway":       {TourPattern{Template: template, Bands: []TimeBand{{Start: MustParseTime("5:00"), End: MustParseTime("6:00")}}}, "the headway of band 0 must be positive"},
+			"end before start": {TourPattern{Template: template, Bands: []TimeBand{{Start: MustParseTime("6:00"), End: MustParseTime("5:00"), HeadwayMinutes: 10}}}, "the end 5:00 of band 0 must not be before its start 6:00"},
+			"overlapping": {
+				TourPattern{Template: template, Bands: []TimeBand{bands[1], bands[0]}},
+				"band 1 must not start before the end 6:30 of the band before",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := test.pattern.Tours()
+				assert.EqualError(t, err, test.err)
+			})
+		}
+	})
+}
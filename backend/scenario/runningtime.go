@@ -0,0 +1,112 @@
+package scenario
+
+import "fmt"
+
+// RunningTimeOptions configure the comparison of scheduled and routed running times.
+type RunningTimeOptions struct {
+	// MinRatio is the share of the routed travel time a segment must at least be scheduled with.
+	MinRatio float64
+	// MaxRatio is the multiple of the routed travel time a segment may at most be scheduled with. Slack segments
+	// are not reported if it is 0.
+	MaxRatio float64
+	// ToleranceSeconds is the deviation that is always accepted, as timetables are usually planned in whole minutes.
+	ToleranceSeconds int
+}
+
+// DefaultRunningTimeOptions reports segments that are scheduled faster than the routed travel time or more
+// than twice as long, ignoring deviations of up to a minute.
+func DefaultRunningTimeOptions() RunningTimeOptions {
+	return RunningTimeOptions{MinRatio: 1, MaxRatio: 2, ToleranceSeconds: 60}
+}
+
+// RunningTimeIssue is a segment between two consecutively served stations of a tour whose scheduled
+// running time does not fit the travel time of the line's path.
+type RunningTimeIssue struct {
+	TourIndex int
+	From      Station
+	To        Station
+	Departure Time
+	Arrival   Time
+	// ScheduledSeconds is the time between the departure at From and the arrival at To.
+	ScheduledSeconds int
+	// RoutedSeconds is the sum of the path durations between From and To.
+	RoutedSeconds float64
+	// Tight is true if the segment is scheduled too short, false if it is suspiciously slack.
+	Tight bool
+}
+
+// RunningTimeReport contains the issues of a timetable, or the error why it could not be checked.
+type RunningTimeReport struct {
+	Timetable Timetable
+	Issues    []RunningTimeIssue
+	Err       error
+}
+
+// CheckRunningTimes compares the running time of every segment between two consecutively served stations of
+// every tour against the durations of the line's path between these stations. Tours with an interval are
+// checked once, as all their trips share the same running times. Segments without routed duration are skipped.
+func (t *Timetable) CheckRunningTimes(options RunningTimeOptions) ([]RunningTimeIssue, error) {
+	line := t.Line()
+	if line.Key == "" {
+		return nil, fmt.Errorf("could not find line with key \"%s\"", t.LineKey)
+	}
+	travelTimes, err := line.StopTravelTimes()
+	if err != nil {
+		return nil, err
+	}
+	offsets, ok := keyTravelTimes(line, t.StationKeys, travelTimes)
+	if !ok {
+		return nil, fmt.Errorf("the stations of timetable \"%s\" do not match the stops of line \"%s\"", t.Key, line.Key)
+	}
+	stations := t.Stations()
+	result := make([]RunningTimeIssue, 0)
+	for tourIndex, tour := range t.Tours {
+		events := tour.normalizedEvents()
+		previous := -1
+		for index, event := range events {
+			if index >= len(offsets) || (!event.Arrival.IsSet() && !event.Departure.IsSet()) {
+				continue
+			}
+			if previous >= 0 {
+				issue := RunningTimeIssue{
+					TourIndex:     tourIndex,
+					From:          stations[previous],
+					To:            stations[index],
+					Departure:     departureOf(events[previous]),
+					Arrival:       arrivalOf(event),
+					RoutedSeconds: offsets[index] - offsets[previous],
+				}
+				issue.ScheduledSeconds = issue.Arrival.Sub(issue.Departure)
+				if issue.RoutedSeconds > 0 && checkRunningTime(&issue, options) {
+					result = append(result, issue)
+				}
+			}
+			previous = index
+		}
+	}
+	return result, nil
+}
+
+// checkRunningTime sets whether the segment is too tight and returns true if the segment is an issue at all.
+func checkRunningTime(issue *RunningTimeIssue, options RunningTimeOptions) bool {
+	scheduled := float64(issue.ScheduledSeconds)
+	tolerance := float64(options.ToleranceSeconds)
+	if scheduled+tolerance < issue.RoutedSeconds*options.MinRatio {
+		issue.Tight = true
+		return true
+	}
+	return options.MaxRatio > 0 && scheduled-tolerance > issue.RoutedSeconds*options.MaxRatio
+}
+
+// CheckRunningTimes checks the running times of all timetables and returns a report for every timetable
+// that has issues or could not be checked.
+func (m *Manager) CheckRunningTimes(options RunningTimeOptions) []RunningTimeReport {
+	result := make([]RunningTimeReport, 0)
+	for _, timetable := range m.Timetables() {
+		issues, err := timetable.CheckRunningTimes(options)
+		if err != nil || len(issues) > 0 {
+			result = append(result, RunningTimeReport{Timetable: timetable, Issues: issues, Err: err})
+		}
+	}
+	return result
+}
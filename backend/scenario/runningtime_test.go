@@ -0,0 +1,90 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTimetable_CheckRunningTimes(t *testing.T) {
+	manager := Empty()
+	for _, key := range []string{"a", "b", "c"} {
+		manager.SaveStation(Station{Key: key, Name: key})
+	}
+	routedLine(manager, []string{"a", "b", "c"}, []float64{600, 300})
+	timetable := manager.SaveTimetable(Timetable{
+		Key:         "tt1",
+		LineKey:     "line1",
+		StationKeys: []string{"a", "b", "c"},
+		Tours: []Tour{
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:02"), Departure: MustParseTime("8:03")}, {Arrival: MustParseTime("8:20")}}},
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:09"), Departure: MustParseTime("8:11")}, {Arrival: MustParseTime("8:16")}}},
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("23:55")}, {Departure: MustParseTime("0:05")}, {Arrival: MustParseTime("0:10")}}},
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("9:00")}, {}, {Arrival: MustParseTime("9:10")}}},
+		},
+	})
+
+	t.Run("default options", func(t *testing.T) {
+		issues, err := timetable.CheckRunningTimes(DefaultRunningTimeOptions())
+		require.NoError(t, err)
+		require.Equal(t, 3, len(issues))
+		assert.Equal(t, 0, issues[0].TourIndex)
+		assert.Equal(t, []string{"a", "b"}, []string{issues[0].From.Key, issues[0].To.Key})
+		assert.Equal(t, []Time{MustParseTime("8:00"), MustParseTime("8:02")}, []Time{issues[0].Departure, issues[0].Arrival})
+		assert.Equal(t, 120, issues[0].ScheduledSeconds)
+		assert.Equal(t, 600.0, issues[0].RoutedSeconds)
+		assert.True(t, issues[0].Tight)
+		assert.Equal(t, []int{0, 3}, []int{issues[1].TourIndex, issues[2].TourIndex})
+		assert.False(t, issues[1].Tight)
+		assert.Equal(t, 1020, issues[1].ScheduledSeconds)
+		assert.True(t, issues[2].Tight)
+		assert.Equal(t, "a", issues[2].From.Key)
+		assert.Equal(t, "c", issues[2].To.Key)
+		assert.Equal(t, 900.0, issues[2].RoutedSeconds)
+	})
+	t.Run("tolerance and no slack check", func(t *testing.T) {
+		issues, err := timetable.CheckRunningTimes(RunningTimeOptions{MinRatio: 1, ToleranceSeconds: 300})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(issues))
+		assert.Equal(t, 120, issues[0].ScheduledSeconds)
+	})
+	t.Run("unrouted segments", func(t *testing.T) {
+		routedLine(manager, []string{"a", "b", "c"}, []float64{})
+		issues, err := timetable.CheckRunningTimes(DefaultRunningTimeOptions())
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+	t.Run("stations do not match", func(t *testing.T) {
+		routedLine(manager, []string{"a", "c"}, []float64{600})
+		_, err := timetable.CheckRunningTimes(DefaultRunningTimeOptions())
+		assert.EqualError(t, err, "the stations of timetable \"tt1\" do not match the stops of line \"line1\"")
+	})
+	t.Run("missing line", func(t *testing.T) {
+		orphan := manager.SaveTimetable(Timetable{Key: "orphan", LineKey: "unknown"})
+		_, err := orphan.CheckRunningTimes(DefaultRunningTimeOptions())
+		assert.EqualError(t, err, "could not find line with key \"unknown\"")
+	})
+}
+
+func TestManager_CheckRunningTimes(t *testing.T) {
+	manager := Empty()
+	for _, key := range []string{"a", "b"} {
+		manager.SaveStation(Station{Key: key})
+	}
+	routedLine(manager, []string{"a", "b"}, []float64{600})
+	tour := func(arrival string) Tour {
+		return Tour{Events: []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime(arrival)}}}
+	}
+	manager.SaveTimetable(Timetable{Key: "fine", Name: "A", LineKey: "line1", StationKeys: []string{"a", "b"}, Tours: []Tour{tour("8:10")}})
+	manager.SaveTimetable(Timetable{Key: "tight", Name: "B", LineKey: "line1", StationKeys: []string{"a", "b"}, Tours: []Tour{tour("8:01")}})
+	manager.SaveTimetable(Timetable{Key: "orphan", Name: "C", LineKey: "unknown"})
+
+	reports := make(map[string]RunningTimeReport)
+	for _, report := range manager.CheckRunningTimes(DefaultRunningTimeOptions()) {
+		reports[report.Timetable.Key] = report
+	}
+	require.Equal(t, 2, len(reports))
+	assert.NoError(t, reports["tight"].Err)
+	assert.Equal(t, 1, len(reports["tight"].Issues))
+	assert.Error(t, reports["orphan"].Err)
+}
@@ -0,0 +1,107 @@
+package scenario
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxTimeHours is the exclusive upper bound for the hours of a time. Times from 24:00 on
+// belong to the service day before, e.g. 25:10 is 1:10 in the night after the service day.
+const maxTimeHours = 48
+
+// Time is a time of day with a precision of seconds. The zero value is a time that is not set,
+// e.g. the missing arrival of the first event of a tour.
+type Time struct {
+	seconds int
+	valid   bool
+}
+
+// NewTime creates a time from the given components. Overflowing minutes and seconds are carried over.
+func NewTime(hours int, minutes int, seconds int) Time {
+	return Time{seconds: hours*3600 + minutes*60 + seconds, valid: true}
+}
+
+// ParseTime parses a time in the format h:mm or h:mm:ss. Hours from 24 to 47 are allowed to express
+// times after midnight.
+func ParseTime(text string) (Time, error) {
+	parts := strings.Split(strings.TrimSpace(text), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Time{}, fmt.Errorf("the time \"%s\" is not in the format h:mm or h:mm:ss", text)
+	}
+	values := make([]int, 0, 3)
+	for index, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 || len(part) == 0 || (index > 0 && len(part) != 2) {
+			return Time{}, fmt.Errorf("the time \"%s\" is not in the format h:mm or h:mm:ss", text)
+		}
+		values = append(values, value)
+	}
+	if len(values) == 2 {
+		values = append(values, 0)
+	}
+	if values[0] >= maxTimeHours {
+		return Time{}, fmt.Errorf("the hours of time \"%s\" must be smaller than %d", text, maxTimeHours)
+	}
+	if values[1] > 59 || values[2] > 59 {
+		return Time{}, fmt.Errorf("the minutes and seconds of time \"%s\" must be smaller than 60", text)
+	}
+	return NewTime(values[0], values[1], values[2]), nil
+}
+
+// ParseOptionalTime works like ParseTime, but returns a time that is not set for the empty string.
+func ParseOptionalTime(text string) (Time, error) {
+	if strings.TrimSpace(text) == "" {
+		return Time{}, nil
+	}
+	return ParseTime(text)
+}
+
+// MustParseTime works like ParseTime, but panics if the time cannot be parsed.
+func MustParseTime(text string) Time {
+	result, err := ParseTime(text)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// IsSet returns false for the zero value of a time.
+func (t Time) IsSet() bool {
+	return t.valid
+}
+
+// Seconds returns the seconds since midnight of the service day.
+func (t Time) Seconds() int {
+	return t.seconds
+}
+
+// Add returns the time shifted by the given number of seconds. A time that is not set stays unset.
+func (t Time) Add(seconds int) Time {
+	if !t.valid {
+		return t
+	}
+	return Time{seconds: t.seconds + seconds, valid: true}
+}
+
+// Before returns true if the time is strictly earlier than the other time.
+func (t Time) Before(other Time) bool {
+	return t.seconds < other.seconds
+}
+
+// Sub returns the difference between the two times in seconds.
+func (t Time) Sub(other Time) int {
+	return t.seconds - other.seconds
+}
+
+// String formats the time as h:mm, or as h:mm:ss if the seconds are not zero.
+// A time that is not set is formatted as empty string.
+func (t Time) String() string {
+	if !t.valid {
+		return ""
+	}
+	if t.seconds%60 != 0 {
+		return fmt.Sprintf("%d:%02d:%02d", t.seconds/3600, t.seconds/60%60, t.seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", t.seconds/3600, t.seconds/60%60)
+}
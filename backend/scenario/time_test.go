@@ -0,0 +1,115 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int
+		wantErr string
+	}{
+		{text: "4:45", want: 4*3600 + 45*60},
+		{text: "04:45", want: 4*3600 + 45*60},
+		{text: "25:00", want: 25 * 3600},
+		{text: "4:45:10", want: 4*3600 + 45*60 + 10},
+		{text: "4:7x", wantErr: "the time \"4:7x\" is not in the format h:mm or h:mm:ss"},
+		{text: "4:7", wantErr: "the time \"4:7\" is not in the format h:mm or h:mm:ss"},
+		{text: ":07", wantErr: "the time \":07\" is not in the format h:mm or h:mm:ss"},
+		{text: "445", wantErr: "the time \"445\" is not in the format h:mm or h:mm:ss"},
+		{text: "", wantErr: "the time \"\" is not in the format h:mm or h:mm:ss"},
+		{text: "4:60", wantErr: "the minutes and seconds of time \"4:60\" must be smaller than 60"},
+		{text: "48:00", wantErr: "the hours of time \"48:00\" must be smaller than 48"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseTime(tt.text)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, got.IsSet())
+			assert.Equal(t, tt.want, got.Seconds())
+		})
+	}
+}
+
+func TestParseOptionalTime(t *testing.T) {
+	got, err := ParseOptionalTime(" ")
+	require.NoError(t, err)
+	assert.False(t, got.IsSet())
+	assert.Equal(t, "", got.String())
+	got, err = ParseOptionalTime("7:05")
+	require.NoError(t, err)
+	assert.Equal(t, NewTime(7, 5, 0), got)
+}
+
+func TestTime_String(t *testing.T) {
+	assert.Equal(t, "4:05", NewTime(4, 5, 0).String())
+	assert.Equal(t, "24:05", NewTime(23, 58, 0).Add(7*60).String())
+	assert.Equal(t, "0:00:30", NewTime(0, 0, 30).String())
+	assert.Equal(t, "", Time{}.String())
+	assert.True(t, NewTime(8, 0, 0).Before(NewTime(8, 1, 0)))
+	assert.Equal(t, 90, NewTime(8, 1, 30).Sub(NewTime(8, 0, 0)))
+}
+
+func TestTour_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tour    Tour
+		wantErr string
+	}{
+		{
+			name: "valid tour after midnight",
+			tour: Tour{Events: []ArrivalDeparture{{Departure: MustParseTime("23:58")}, {}, {Arrival: MustParseTime("24:05")}}},
+		},
+		{
+			name: "valid tour with wrapped times",
+			tour: Tour{Events: []ArrivalDeparture{{Departure: MustParseTime("23:58")}, {Arrival: MustParseTime("0:02"), Departure: MustParseTime("0:03")}, {Arrival: MustParseTime("0:05")}}},
+		},
+		{
+			name:    "not in order",
+			tour:    Tour{Events: []ArrivalDeparture{{Departure: MustParseTime("8:10")}, {Arrival: MustParseTime("8:12"), Departure: MustParseTime("8:11")}}},
+			wantErr: "the time 8:11 of event 1 is earlier than the time 8:12 before",
+		},
+		{
+			name:    "a day or longer",
+			tour:    Tour{Events: []ArrivalDeparture{{Departure: MustParseTime("0:00")}, {Arrival: MustParseTime("24:30")}}},
+			wantErr: "the time 24:30 of event 1 is a day or more after the start 0:00 of the tour",
+		},
+		{
+			name:    "negative time",
+			tour:    Tour{Events: []ArrivalDeparture{{Departure: NewTime(0, -5, 0)}, {Arrival: MustParseTime("0:10")}}},
+			wantErr: "the time of event 0 must not be negative",
+		},
+		{
+			name:    "negative last tour",
+			tour:    Tour{IntervalMinutes: 10, LastTour: NewTime(0, -10, 0), Events: []ArrivalDeparture{{Departure: MustParseTime("0:00")}}},
+			wantErr: "the last tour must not be negative",
+		},
+		{
+			name:    "negative interval",
+			tour:    Tour{IntervalMinutes: -5},
+			wantErr: "the interval must not be negative",
+		},
+		{
+			name:    "interval without last tour",
+			tour:    Tour{IntervalMinutes: 5},
+			wantErr: "the last tour must be set if an interval is given",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tour.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package scenario
 
 import (
+	"fmt"
 	gonanoid "github.com/matoous/go-nanoid"
 	"sort"
 )
@@ -9,6 +10,7 @@ type Timetable struct {
 	Key         string
 	LineKey     string
 	Name        string
+	CalendarKey string
 	Tours       []Tour
 	manager     *Manager
 	StationKeys []string
@@ -26,6 +28,47 @@ func (t *Timetable) Line() Line {
 	return t.manager.lines[t.LineKey]
 }
 
+// Validate checks that the times of the tour are not negative, that the events are in chronological order and
+// that an interval is not negative and comes with a last tour. Like in normalizedEvents, a time earlier than its
+// predecessor is after midnight, but the tour must not last a day or longer.
+func (t *Tour) Validate() error {
+	var start, previous, previousNormalized Time
+	for index, event := range t.Events {
+		for _, current := range []Time{event.Arrival, event.Departure} {
+			if !current.IsSet() {
+				continue
+			}
+			if current.Seconds() < 0 {
+				return fmt.Errorf("the time of event %d must not be negative", index)
+			}
+			normalized := current
+			for previousNormalized.IsSet() && normalized.Before(previousNormalized) {
+				normalized = normalized.Add(day)
+			}
+			if !start.IsSet() {
+				start = normalized
+			}
+			if normalized.Sub(start) >= day {
+				if normalized != current {
+					return fmt.Errorf("the time %s of event %d is earlier than the time %s before", current, index, previous)
+				}
+				return fmt.Errorf("the time %s of event %d is a day or more after the start %s of the tour", current, index, start)
+			}
+			previous, previousNormalized = current, normalized
+		}
+	}
+	if t.LastTour.IsSet() && t.LastTour.Seconds() < 0 {
+		return fmt.Errorf("the last tour must not be negative")
+	}
+	if t.IntervalMinutes < 0 {
+		return fmt.Errorf("the interval must not be negative")
+	}
+	if t.IntervalMinutes > 0 && !t.LastTour.IsSet() {
+		return fmt.Errorf("the last tour must be set if an interval is given")
+	}
+	return nil
+}
+
 func (m *Manager) Timetables() []Timetable {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
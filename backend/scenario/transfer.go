@@ -0,0 +1,188 @@
+package scenario
+
+import (
+	"sort"
+	"time"
+)
+
+// TransferOptions configure the transfer analysis.
+type TransferOptions struct {
+	// MinTransferSeconds is the minimal time passengers need to change between two vehicles.
+	MinTransferSeconds int
+	// MaxWaitSeconds is the longest acceptable wait. Arrivals without a departure within this time are unconnected.
+	MaxWaitSeconds int
+	// Date restricts the analysis to timetables operated on that date if it is not zero.
+	Date time.Time
+}
+
+// MissedConnection is a departure that leaves less than the minimal transfer time after an arrival.
+type MissedConnection struct {
+	Arrival   Time
+	Departure Time
+}
+
+// LineTransfers summarizes the transfers from arrivals of one line to departures of another line at a station.
+type LineTransfers struct {
+	From        Line
+	To          Line
+	Arrivals    int
+	Connections int
+	// TotalWaitSeconds and MaxWaitSeconds only consider arrivals with connection.
+	TotalWaitSeconds int
+	MaxWaitSeconds   int
+	Missed           []MissedConnection
+	// quality is the sum of the connection qualities of all arrivals, see StationTransfers.Score.
+	quality float64
+}
+
+// AverageWaitSeconds returns the average wait of all arrivals with connection, or 0 if there is none.
+func (l *LineTransfers) AverageWaitSeconds() float64 {
+	if l.Connections == 0 {
+		return 0
+	}
+	return float64(l.TotalWaitSeconds) / float64(l.Connections)
+}
+
+// StationTransfers is the result of the transfer analysis of a station served by multiple lines.
+type StationTransfers struct {
+	Station Station
+	Lines   []Line
+	Pairs   []LineTransfers
+	// Score rates the connections of the station from 0 to 100. Each arrival rates its connection with 1 if the
+	// departure leaves exactly after the minimal transfer time, decreasing linearly to 0 for the maximal wait.
+	// Unconnected arrivals are rated 0. The score is the average rating of all arrivals.
+	Score float64
+}
+
+// transferEvent is an arrival or departure at a station together with the station the trip came from and goes to.
+type transferEvent struct {
+	time     Time
+	previous string
+	next     string
+}
+
+// AnalyzeTransfers analyzes the transfers between different lines at all stations served by more than one line.
+// Each arrival is connected to the first departure of the other line that leaves at least the minimal transfer
+// time later. Departures returning to the station the arriving trip came from are ignored, e.g. changing between
+// both directions of a line. The result is sorted by score, worst stations first.
+func (m *Manager) AnalyzeTransfers(options TransferOptions) []StationTransfers {
+	result := make([]StationTransfers, 0)
+	for _, station := range m.Stations() {
+		lines := distinctLines(station.Lines())
+		if len(lines) < 2 {
+			continue
+		}
+		arrivals := make(map[string][]transferEvent)
+		departures := make(map[string][]transferEvent)
+		m.visitStops(station.Key, options.Date, func(s stop) {
+			previous, next := "", ""
+			if s.index > 0 {
+				previous = s.timetable.StationKeys[s.index-1]
+			}
+			if s.index < len(s.timetable.StationKeys)-1 {
+				next = s.timetable.StationKeys[s.index+1]
+			}
+			lineKey := s.timetable.LineKey
+			if arrival := s.arrival(); arrival.IsSet() {
+				arrivals[lineKey] = append(arrivals[lineKey], transferEvent{time: arrival, previous: previous, next: next})
+			}
+			if departure := s.departure(); departure.IsSet() {
+				departures[lineKey] = append(departures[lineKey], transferEvent{time: departure, previous: previous, next: next})
+			}
+		})
+		for _, d := range departures {
+			sort.Slice(d, func(i, j int) bool {
+				return d[i].time.Before(d[j].time)
+			})
+		}
+		transfers := StationTransfers{Station: station, Lines: lines, Pairs: make([]LineTransfers, 0)}
+		totalArrivals := 0
+		totalQuality := 0.0
+		for _, from := range lines {
+			for _, to := range lines {
+				if from.Key == to.Key || len(arrivals[from.Key]) == 0 || len(departures[to.Key]) == 0 {
+					continue
+				}
+				pair := analyzeLineTransfers(from, to, arrivals[from.Key], departures[to.Key], options)
+				if pair.Arrivals == 0 {
+					continue
+				}
+				totalArrivals = totalArrivals + pair.Arrivals
+				totalQuality = totalQuality + pair.quality
+				transfers.Pairs = append(transfers.Pairs, pair)
+			}
+		}
+		if totalArrivals == 0 {
+			continue
+		}
+		transfers.Score = 100 * totalQuality / float64(totalArrivals)
+		result = append(result, transfers)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Score < result[j].Score
+	})
+	return result
+}
+
+func analyzeLineTransfers(from Line, to Line, arrivals []transferEvent, departures []transferEvent, options TransferOptions) LineTransfers {
+	result := LineTransfers{From: from, To: to, Missed: make([]MissedConnection, 0)}
+	for _, arrival := range arrivals {
+		candidates := make([]transferEvent, 0, len(departures))
+		for _, departure := range departures {
+			if departure.next == "" || departure.next != arrival.previous {
+				candidates = append(candidates, departure)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		result.Arrivals++
+		missed := Time{}
+		for _, departure := range candidates {
+			wait := departure.time.Sub(arrival.time)
+			if wait < 0 {
+				continue
+			}
+			if wait < options.MinTransferSeconds {
+				missed = departure.time
+				continue
+			}
+			if wait > options.MaxWaitSeconds {
+				break
+			}
+			result.Connections++
+			result.TotalWaitSeconds = result.TotalWaitSeconds + wait
+			if wait > result.MaxWaitSeconds {
+				result.MaxWaitSeconds = wait
+			}
+			result.quality = result.quality + connectionQuality(wait, options)
+			break
+		}
+		if missed.IsSet() {
+			result.Missed = append(result.Missed, MissedConnection{Arrival: arrival.time, Departure: missed})
+		}
+	}
+	return result
+}
+
+// connectionQuality rates a wait from 1 for the minimal transfer time to 0 for the maximal wait.
+func connectionQuality(wait int, options TransferOptions) float64 {
+	span := options.MaxWaitSeconds - options.MinTransferSeconds
+	if span <= 0 {
+		return 1
+	}
+	return 1 - float64(wait-options.MinTransferSeconds)/float64(span)
+}
+
+// distinctLines removes duplicates of lines that serve a station more than once.
+func distinctLines(lines []Line) []Line {
+	result := make([]Line, 0, len(lines))
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if !seen[line.Key] {
+			seen[line.Key] = true
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func transferManager() *Manager {
+	manager := Empty()
+	for _, key := range []string{"a", "b", "c", "d", "x"} {
+		manager.SaveStation(Station{Key: key, Name: key})
+	}
+	manager.SaveLine(Line{Key: "line1", Name: "Line 1", Stops: []string{"a", "x", "b"}})
+	manager.SaveLine(Line{Key: "line2", Name: "Line 2", Stops: []string{"c", "x", "d"}})
+	manager.SaveLine(Line{Key: "line3", Name: "Line 3", Stops: []string{"b", "x", "a"}})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt1",
+		LineKey:     "line1",
+		StationKeys: []string{"a", "x", "b"},
+		Tours: []Tour{
+			{
+				IntervalMinutes: 20,
+				LastTour:        MustParseTime("8:20"),
+				Events:          []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Departure: MustParseTime("8:05")}, {Arrival: MustParseTime("8:10")}},
+			},
+		},
+	})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt2",
+		LineKey:     "line2",
+		StationKeys: []string{"c", "x", "d"},
+		Tours: []Tour{
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Departure: MustParseTime("8:07")}, {Arrival: MustParseTime("8:12")}}},
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:23")}, {Departure: MustParseTime("8:30")}, {Arrival: MustParseTime("8:35")}}},
+		},
+	})
+	manager.SaveTimetable(Timetable{
+		Key:         "tt3",
+		LineKey:     "line3",
+		StationKeys: []string{"b", "x", "a"},
+		Tours: []Tour{
+			{Events: []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Departure: MustParseTime("8:06")}, {Arrival: MustParseTime("8:11")}}},
+		},
+	})
+	return manager
+}
+
+func TestManager_AnalyzeTransfers(t *testing.T) {
+	manager := transferManager()
+	result := manager.AnalyzeTransfers(TransferOptions{MinTransferSeconds: 180, MaxWaitSeconds: 1200})
+	require.Equal(t, 1, len(result))
+	station := result[0]
+	assert.Equal(t, "x", station.Station.Key)
+	assert.Equal(t, 3, len(station.Lines))
+	assert.InDelta(t, 100.0/7, station.Score, 0.001)
+
+	type summary struct {
+		from, to          string
+		arrivals, connect int
+		totalWait         int
+		missed            []MissedConnection
+	}
+	got := make([]summary, 0, len(station.Pairs))
+	for _, pair := range station.Pairs {
+		got = append(got, summary{pair.From.Key, pair.To.Key, pair.Arrivals, pair.Connections, pair.TotalWaitSeconds, pair.Missed})
+	}
+	assert.Equal(t, []summary{
+		{"line1", "line2", 2, 1, 300, []MissedConnection{{Arrival: MustParseTime("8:05"), Departure: MustParseTime("8:07")}}},
+		{"line2", "line1", 2, 1, 1080, []MissedConnection{}},
+		{"line2", "line3", 2, 0, 0, []MissedConnection{}},
+		{"line3", "line2", 1, 0, 0, []MissedConnection{{Arrival: MustParseTime("8:06"), Departure: MustParseTime("8:07")}}},
+	}, got)
+	assert.Equal(t, 300.0, station.Pairs[0].AverageWaitSeconds())
+	assert.Equal(t, 0.0, station.Pairs[2].AverageWaitSeconds())
+}
+
+func TestManager_AnalyzeTransfers_NoWaitRange(t *testing.T) {
+	manager := transferManager()
+	result := manager.AnalyzeTransfers(TransferOptions{MinTransferSeconds: 0, MaxWaitSeconds: 0})
+	require.Equal(t, 1, len(result))
+	assert.Equal(t, 0.0, result[0].Score)
+}
@@ -0,0 +1,91 @@
+package scenario
+
+import "sort"
+
+const day = 24 * 3600
+
+// Trip is a single run of a vehicle along the stations of a timetable.
+type Trip struct {
+	TourIndex int
+	Start     Time
+	// Events contains one entry per station of the timetable. Stations that are not served are unset.
+	Events []ArrivalDeparture
+}
+
+// Start returns the first time of the tour. It is unset if the tour has no times at all.
+func (t *Tour) Start() Time {
+	for _, event := range t.Events {
+		if event.Departure.IsSet() {
+			return event.Departure
+		}
+		if event.Arrival.IsSet() {
+			return event.Arrival
+		}
+	}
+	return Time{}
+}
+
+// normalizedEvents returns the events of the tour where times that are earlier than
+// their predecessor are moved to the next day.
+func (t *Tour) normalizedEvents() []ArrivalDeparture {
+	result := make([]ArrivalDeparture, 0, len(t.Events))
+	var previous Time
+	shift := func(current Time) Time {
+		if !current.IsSet() {
+			return current
+		}
+		for previous.IsSet() && current.Before(previous) {
+			current = current.Add(day)
+		}
+		previous = current
+		return current
+	}
+	for _, event := range t.Events {
+		arrival := shift(event.Arrival)
+		departure := shift(event.Departure)
+		result = append(result, ArrivalDeparture{Arrival: arrival, Departure: departure})
+	}
+	return result
+}
+
+// Trips expands the tour into concrete trips. A tour without interval describes exactly one trip.
+// A tour with interval is repeated until the start of the last tour. If the last tour is earlier
+// than the start of the tour, it is considered to be after midnight.
+func (t *Tour) Trips() []Trip {
+	start := t.Start()
+	if !start.IsSet() {
+		return []Trip{}
+	}
+	events := t.normalizedEvents()
+	if t.IntervalMinutes <= 0 || !t.LastTour.IsSet() {
+		return []Trip{{Start: start, Events: events}}
+	}
+	lastTour := t.LastTour
+	for lastTour.Before(start) {
+		lastTour = lastTour.Add(day)
+	}
+	result := make([]Trip, 0, lastTour.Sub(start)/(t.IntervalMinutes*60)+1)
+	for offset := 0; !lastTour.Before(start.Add(offset)); offset = offset + t.IntervalMinutes*60 {
+		shifted := make([]ArrivalDeparture, 0, len(events))
+		for _, event := range events {
+			shifted = append(shifted, ArrivalDeparture{Arrival: event.Arrival.Add(offset), Departure: event.Departure.Add(offset)})
+		}
+		result = append(result, Trip{Start: start.Add(offset), Events: shifted})
+	}
+	return result
+}
+
+// Trips expands all tours of the timetable into concrete trips sorted by their start.
+func (t *Timetable) Trips() []Trip {
+	result := make([]Trip, 0, len(t.Tours))
+	for index, tour := range t.Tours {
+		for _, trip := range tour.Trips() {
+			trip.TourIndex = index
+			result = append(result, trip)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
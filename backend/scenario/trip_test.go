@@ -0,0 +1,63 @@
+package scenario
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestTour_Trips(t *testing.T) {
+	t.Run("without interval", func(t *testing.T) {
+		tour := Tour{Events: []ArrivalDeparture{{}, {Departure: MustParseTime("23:58")}, {Arrival: MustParseTime("0:05")}}}
+		assert.Equal(t, []Trip{
+			{
+				Start:  MustParseTime("23:58"),
+				Events: []ArrivalDeparture{{}, {Departure: MustParseTime("23:58")}, {Arrival: MustParseTime("24:05")}},
+			},
+		}, tour.Trips())
+	})
+	t.Run("with interval", func(t *testing.T) {
+		tour := Tour{
+			IntervalMinutes: 20,
+			LastTour:        MustParseTime("8:50"),
+			Events:          []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:10")}},
+		}
+		assert.Equal(t, []Trip{
+			{Start: MustParseTime("8:00"), Events: []ArrivalDeparture{{Departure: MustParseTime("8:00")}, {Arrival: MustParseTime("8:10")}}},
+			{Start: MustParseTime("8:20"), Events: []ArrivalDeparture{{Departure: MustParseTime("8:20")}, {Arrival: MustParseTime("8:30")}}},
+			{Start: MustParseTime("8:40"), Events: []ArrivalDeparture{{Departure: MustParseTime("8:40")}, {Arrival: MustParseTime("8:50")}}},
+		}, tour.Trips())
+	})
+	t.Run("last tour after midnight", func(t *testing.T) {
+		tour := Tour{
+			IntervalMinutes: 30,
+			LastTour:        MustParseTime("0:30"),
+			Events:          []ArrivalDeparture{{Departure: MustParseTime("23:30")}, {Arrival: MustParseTime("23:45")}},
+		}
+		trips := tour.Trips()
+		assert.Equal(t, 3, len(trips))
+		assert.Equal(t, "24:30", trips[2].Start.String())
+		assert.Equal(t, "24:45", trips[2].Events[1].Arrival.String())
+	})
+	t.Run("without events", func(t *testing.T) {
+		tour := Tour{IntervalMinutes: 10, LastTour: MustParseTime("9:00"), Events: []ArrivalDeparture{{}, {}}}
+		assert.Equal(t, []Trip{}, tour.Trips())
+	})
+}
+
+func TestTimetable_Trips(t *testing.T) {
+	manager, _ := LoadScenario(filepath.Join("..", "testdata", "wuerzburg"))
+	timetable, _ := manager.Timetable("ihDOhYX9PK")
+	trips := timetable.Trips()
+	assert.Equal(t, 51, len(trips))
+	for index := 1; index < len(trips); index++ {
+		assert.False(t, trips[index].Start.Before(trips[index-1].Start))
+	}
+	assert.Equal(t, Trip{
+		TourIndex: 0,
+		Start:     MustParseTime("5:55"),
+		Events:    timetable.Tours[0].Events,
+	}, trips[0])
+	assert.Equal(t, 1, trips[13].TourIndex)
+	assert.Equal(t, "8:30", trips[13].Start.String())
+}
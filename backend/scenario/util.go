@@ -1,10 +1,13 @@
 package scenario
 
 import (
+	"math"
 	"regexp"
 	"strconv"
 )
 
+const earthRadius = 6371000.0
+
 var numberRegex = regexp.MustCompile("^(\\w+) (\\d+)")
 
 func sortLines(lines []Line) func(i, j int) bool {
@@ -61,3 +64,13 @@ func mustBeNumber(text string) int {
 	number, _ := strconv.Atoi(text)
 	return number
 }
+
+// HaversineDistance returns the great-circle distance between both coordinates in meters.
+func HaversineDistance(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
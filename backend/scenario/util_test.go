@@ -1,6 +1,7 @@
 package scenario
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"sort"
 	"testing"
@@ -21,3 +22,8 @@ func TestSortLines(t *testing.T) {
 	assert.Equal(t, "Linie 10: Hubland", lines[2].Name)
 	assert.Equal(t, "Sonderwagen U", lines[3].Name)
 }
+
+func ExampleHaversineDistance() {
+	fmt.Printf("%.0f", HaversineDistance(49.79745, 9.93503, 49.80182, 9.92265))
+	// Output: 1013
+}